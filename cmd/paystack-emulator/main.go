@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+)
+
+// The emulator reads the same PAYSTACK_* variables as the server, so pointing
+// PAYSTACK_BASE_URL at it is the only change needed to run offline.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Printf("warning: .env not loaded: %v", err)
+	}
+
+	port := getEnv("EMULATOR_PORT", "8090")
+	delay, err := time.ParseDuration(getEnv("EMULATOR_TRANSFER_DELAY", "2s"))
+	if err != nil {
+		log.Fatalf("EMULATOR_TRANSFER_DELAY: %v", err)
+	}
+	emu := paystackemu.New(paystackemu.Config{
+		SecretKey:       getEnv("PAYSTACK_SECRET_KEY", "sk_test_emulator"),
+		WebhookSecret:   os.Getenv("PAYSTACK_WEBHOOK_SECRET"),
+		WebhookURL:      getEnv("EMULATOR_WEBHOOK_URL", "http://localhost:8080/wallet/paystack/webhook"),
+		PublicURL:       getEnv("EMULATOR_PUBLIC_URL", "http://localhost:"+port),
+		TransferOutcome: getEnv("EMULATOR_TRANSFER_OUTCOME", paystackemu.TransferOutcomeSuccess),
+		TransferDelay:   delay,
+	})
+	log.Printf("paystack emulator listening on :%s", port)
+	if err := http.ListenAndServe(":"+port, emu.Handler()); err != nil {
+		log.Fatalf("emulator failed: %v", err)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
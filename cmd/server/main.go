@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
 
@@ -9,6 +13,7 @@ import (
 	"github.com/CyberwizD/Wallet-Service/internal/database"
 	"github.com/CyberwizD/Wallet-Service/internal/models"
 	"github.com/CyberwizD/Wallet-Service/internal/server"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
 )
 
 func main() {
@@ -20,11 +25,34 @@ func main() {
 	cfg := config.Load()
 
 	db := database.Connect(cfg.DBURL)
-	if err := db.AutoMigrate(&models.User{}, &models.Wallet{}, &models.APIKey{}, &models.Transaction{}); err != nil {
+	// Wallets were once unique per user; they are now unique per user and currency.
+	if db.Migrator().HasIndex(&models.Wallet{}, "idx_wallets_user_id") {
+		if err := db.Migrator().DropIndex(&models.Wallet{}, "idx_wallets_user_id"); err != nil {
+			log.Fatalf("migration failed: %v", err)
+		}
+	}
+	if err := db.AutoMigrate(
+		&models.User{}, &models.Wallet{}, &models.APIKey{}, &models.Transaction{},
+		&models.LedgerAccount{}, &models.JournalEntry{}, &models.LedgerLine{},
+		&models.ReconciliationRun{}, &models.SettlementRun{}, &models.IdempotencyKey{}, &models.Hold{},
+		&models.StandingOrder{}, &models.StandingOrderRun{},
+		&models.TransferBatch{}, &models.TransferBatchItem{},
+		&models.BankRecipient{}, &models.WebhookEvent{},
+		&models.SavedCard{}, &models.AutoTopUp{}, &models.Session{},
+	); err != nil {
 		log.Fatalf("migration failed: %v", err)
 	}
+	if err := services.NewLedgerService(db).BackfillOpeningBalances(); err != nil {
+		log.Fatalf("ledger backfill failed: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	svc := server.NewServices(cfg, db)
+	server.StartJobs(ctx, cfg, svc)
 
-	r := server.SetupRouter(cfg, db)
+	r := server.SetupRouter(cfg, db, svc)
 	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("server failed: %v", err)
 	}
@@ -27,12 +27,14 @@ func NewGoogleOAuth(clientID, clientSecret, redirectURL string) *oauth2.Config {
 	}
 }
 
-// ExchangeCode exchanges an auth code for a token.
-func ExchangeCode(ctx context.Context, cfg *oauth2.Config, code string) (*oauth2.Token, error) {
-	return cfg.Exchange(ctx, code)
+// ExchangeCode exchanges an auth code for a token, proving the PKCE verifier
+// the code was requested with.
+func ExchangeCode(ctx context.Context, cfg *oauth2.Config, code, verifier string) (*oauth2.Token, error) {
+	return cfg.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 }
 
-// FetchGoogleUser pulls the profile for the given token.
+// FetchGoogleUser pulls the profile for the given token. Sign-in relies on the
+// ID token; this only fills in details it lacks.
 func FetchGoogleUser(ctx context.Context, token *oauth2.Token) (*GoogleUser, error) {
 	client := oauth2.NewClient(ctx, oauth2.StaticTokenSource(token))
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
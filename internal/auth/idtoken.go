@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GoogleCertsURL serves the keys Google signs ID tokens with.
+const GoogleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+// googleIssuers are the iss values Google puts on ID tokens.
+var googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// Keys are re-fetched after the max-age Google sends, or this long without one,
+// and at most once per jwksRefreshInterval when a token names an unknown key.
+const (
+	defaultJWKSMaxAge   = time.Hour
+	jwksRefreshInterval = time.Minute
+)
+
+// ErrEmailNotVerified means Google has not verified the account's email address.
+var ErrEmailNotVerified = errors.New("google account email is not verified")
+
+// GoogleIDClaims are the ID token claims used to sign a user in.
+type GoogleIDClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// GoogleIDTokenVerifier checks Google ID tokens against Google's published keys.
+// It is safe for concurrent use.
+type GoogleIDTokenVerifier struct {
+	clientID string
+	certsURL string
+	client   *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+// NewGoogleIDTokenVerifier verifies tokens issued to clientID with keys from
+// certsURL (GoogleCertsURL when empty).
+func NewGoogleIDTokenVerifier(clientID, certsURL string) *GoogleIDTokenVerifier {
+	if certsURL == "" {
+		certsURL = GoogleCertsURL
+	}
+	return &GoogleIDTokenVerifier{clientID: clientID, certsURL: certsURL, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Verify checks the token's RS256 signature, audience, issuer, expiry and nonce,
+// and that Google has verified the email address.
+func (v *GoogleIDTokenVerifier) Verify(ctx context.Context, rawToken, nonce string) (*GoogleIDClaims, error) {
+	var claims GoogleIDClaims
+	_, err := jwt.ParseWithClaims(rawToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithAudience(v.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+	if !slices.Contains(googleIssuers, claims.Issuer) {
+		return nil, fmt.Errorf("invalid id token: unexpected issuer %q", claims.Issuer)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	return &claims, nil
+}
+
+// key returns the signing key kid, refreshing the key set when it is stale or
+// does not contain kid.
+func (v *GoogleIDTokenVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := time.Now()
+	key, ok := v.keys[kid]
+	stale := now.After(v.expiresAt)
+	if ok && !stale {
+		return key, nil
+	}
+	if stale || now.Sub(v.fetchedAt) >= jwksRefreshInterval {
+		if err := v.refresh(ctx, now); err != nil {
+			if ok {
+				return key, nil // keep using a known key while Google is unreachable
+			}
+			return nil, err
+		}
+		key, ok = v.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// refresh downloads the key set. The caller holds v.mu.
+func (v *GoogleIDTokenVerifier) refresh(ctx context.Context, now time.Time) error {
+	v.fetchedAt = now
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.certsURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching google keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("google keys returned status %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decoding google keys: %w", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return errors.New("google keys: no usable RSA keys")
+	}
+	v.keys = keys
+	v.expiresAt = now.Add(maxAge(resp.Header.Get("Cache-Control")))
+	return nil
+}
+
+// maxAge reads max-age from a Cache-Control header.
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return defaultJWKSMaxAge
+}
@@ -11,14 +11,17 @@ import (
 type Claims struct {
 	UserID string `json:"uid"`
 	Email  string `json:"email"`
+	// SessionID is the session the token was issued for; the token is refused once it is revoked.
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken issues a JWT for the provided user.
-func GenerateToken(userID, email, secret string, ttl time.Duration) (string, error) {
+// GenerateToken issues an access token for the provided user and session.
+func GenerateToken(userID, email, sessionID, secret string, ttl time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
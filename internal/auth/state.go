@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// OAuthStateTTL bounds how long a sign-in may take between redirect and callback.
+const OAuthStateTTL = 10 * time.Minute
+
+// ErrInvalidOAuthState means the state cookie is missing, tampered with, expired
+// or does not match the state Google returned.
+var ErrInvalidOAuthState = errors.New("invalid or expired oauth state")
+
+// OAuthState is what a sign-in carries from its redirect to the callback, in a
+// signed cookie: the state and nonce sent to Google and the PKCE verifier.
+type OAuthState struct {
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	Verifier  string    `json:"verifier"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// NewOAuthState generates a fresh state and nonce around a PKCE verifier.
+func NewOAuthState(verifier string, now time.Time) OAuthState {
+	return OAuthState{
+		State:     rand.Text(),
+		Nonce:     rand.Text(),
+		Verifier:  verifier,
+		ExpiresAt: now.Add(OAuthStateTTL),
+	}
+}
+
+// Sign encodes the state as a cookie value authenticated with secret.
+func (s OAuthState) Sign(secret string) (string, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(body)
+	return payload + "." + stateMAC(payload, secret), nil
+}
+
+// ParseOAuthState verifies a cookie value made by Sign and checks it has not
+// expired and was issued for the state Google returned.
+func ParseOAuthState(value, secret, state string, now time.Time) (*OAuthState, error) {
+	payload, mac, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(stateMAC(payload, secret))) {
+		return nil, ErrInvalidOAuthState
+	}
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+	var s OAuthState
+	if err := json.Unmarshal(body, &s); err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+	if now.After(s.ExpiresAt) || state == "" || !hmac.Equal([]byte(s.State), []byte(state)) {
+		return nil, ErrInvalidOAuthState
+	}
+	return &s, nil
+}
+
+// stateMAC signs with a key derived from secret, so state cookies and JWTs
+// sharing JWT_SECRET cannot be swapped for one another.
+func stateMAC(payload, secret string) string {
+	key := hmac.New(sha256.New, []byte(secret))
+	key.Write([]byte("oauth-state"))
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
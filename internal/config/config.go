@@ -2,36 +2,130 @@ package config
 
 import (
 	"log"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds the application configuration loaded from environment variables.
 type Config struct {
-	Port                  string
-	DBURL                 string
-	JWTSecret             string
-	GoogleClientID        string
-	GoogleClientSecret    string
-	GoogleRedirectURL     string
+	Port               string
+	DBURL              string
+	JWTSecret          string
+	GoogleClientID     string
+	GoogleClientSecret string
+	GoogleRedirectURL  string
+	// AuthRedirectURL receives the session tokens in its URL fragment after Google sign-in;
+	// empty returns it as JSON from the callback.
+	AuthRedirectURL string
+	// AccessTokenTTL is the lifetime of access JWTs; RefreshTokenTTL is how long a
+	// session survives without its refresh token being used.
+	AccessTokenTTL        time.Duration
+	RefreshTokenTTL       time.Duration
 	PaystackSecret        string
 	PaystackBaseURL       string
 	PaystackWebhookSecret string
+	// PaystackVirtualAccountBank is the bank slug dedicated virtual accounts are opened at.
+	PaystackVirtualAccountBank string
+	// PaystackWebhookPreviousSecrets stay valid for signatures until PaystackWebhookRotationEnds
+	// (or indefinitely when it is zero), so a secret can be rotated without dropping deliveries.
+	PaystackWebhookPreviousSecrets []string
+	PaystackWebhookRotationEnds    time.Time
+	// PaystackWebhookAllowedIPs restricts the webhook route to these IPs/CIDRs; empty allows any source.
+	PaystackWebhookAllowedIPs []string
+	// Flutterwave is an optional second deposit gateway, enabled by FlutterwaveSecret.
+	FlutterwaveSecret            string
+	FlutterwaveBaseURL           string
+	FlutterwaveWebhookHash       string
+	FlutterwaveRedirectURL       string
+	FlutterwaveWebhookAllowedIPs []string
+	// PaymentGateways orders the deposit gateways by preference; later ones take
+	// over when earlier ones fail. Paystack is always included.
+	PaymentGateways []string
+	// TrustedProxies may set X-Forwarded-For; with none, the client IP is the TCP peer.
+	TrustedProxies []string
+	// AdminEmails lists JWT users allowed to call /admin endpoints.
+	AdminEmails []string
+	// ReconciliationInterval controls the balance reconciliation job; zero disables it.
+	ReconciliationInterval time.Duration
+	// DepositExpiryTTL is how long a deposit may stay pending before the expiry job
+	// re-checks it and, if unpaid, marks it expired.
+	DepositExpiryTTL time.Duration
+	// DepositExpiryInterval controls the deposit expiry job; zero disables it.
+	DepositExpiryInterval time.Duration
+	// WithdrawalVerifyAfter is how long a withdrawal may stay pending without a
+	// transfer webhook before the verification job asks Paystack about it.
+	WithdrawalVerifyAfter time.Duration
+	// WithdrawalVerifyInterval controls the withdrawal verification job; zero disables it.
+	WithdrawalVerifyInterval time.Duration
+	// RefundVerifyAfter is how long a refund may stay pending without a refund
+	// webhook before the verification job asks Paystack about it.
+	RefundVerifyAfter time.Duration
+	// RefundVerifyInterval controls the refund verification job; zero disables it.
+	RefundVerifyInterval time.Duration
+	// AutoTopUpInterval controls how often wallets are checked against their auto top-up threshold; zero disables it.
+	AutoTopUpInterval time.Duration
+	// SettlementInterval controls the Paystack settlement reconciliation job; zero disables it.
+	SettlementInterval time.Duration
+	// SettlementAutoFix lets the scheduled settlement run credit missing credits.
+	SettlementAutoFix bool
+	// PublicURL is the service's externally reachable base URL. When set, checkout
+	// returns customers to its deposit callback page, which verifies the payment.
+	PublicURL string
+	// DepositCallbackAllowedURLs lists the URL prefixes, including app deep links,
+	// a deposit's callback_url may start with; empty rejects every callback_url.
+	DepositCallbackAllowedURLs []string
+	// DepositChannels restricts checkout to these payment channels; empty offers all.
+	DepositChannels []string
 }
 
 // Load returns a Config populated from environment variables with reasonable defaults.
 func Load() Config {
 	cfg := Config{
-		Port:                  getEnv("PORT", "8080"),
-		DBURL:                 getEnv("DATABASE_URL", ""),
-		JWTSecret:             getEnv("JWT_SECRET", ""),
-		GoogleClientID:        getEnv("GOOGLE_CLIENT_ID", ""),
-		GoogleClientSecret:    getEnv("GOOGLE_CLIENT_SECRET", ""),
-		GoogleRedirectURL:     getEnv("GOOGLE_REDIRECT_URL", ""),
-		PaystackSecret:        getEnv("PAYSTACK_SECRET_KEY", ""),
-		PaystackBaseURL:       getEnv("PAYSTACK_BASE_URL", "https://api.paystack.co"),
-		PaystackWebhookSecret: getEnv("PAYSTACK_WEBHOOK_SECRET", ""),
+		Port:                   getEnv("PORT", "8080"),
+		DBURL:                  getEnv("DATABASE_URL", ""),
+		JWTSecret:              getEnv("JWT_SECRET", ""),
+		GoogleClientID:         getEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret:     getEnv("GOOGLE_CLIENT_SECRET", ""),
+		GoogleRedirectURL:      getEnv("GOOGLE_REDIRECT_URL", ""),
+		AuthRedirectURL:        getEnv("AUTH_REDIRECT_URL", ""),
+		AccessTokenTTL:         getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:        getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PaystackSecret:         getEnv("PAYSTACK_SECRET_KEY", ""),
+		PaystackBaseURL:        getEnv("PAYSTACK_BASE_URL", "https://api.paystack.co"),
+		PaystackWebhookSecret:  getEnv("PAYSTACK_WEBHOOK_SECRET", ""),
+		AdminEmails:            getList("ADMIN_EMAILS"),
+		ReconciliationInterval: getDuration("RECONCILIATION_INTERVAL", 24*time.Hour),
+		DepositExpiryTTL:       getDuration("DEPOSIT_EXPIRY_TTL", 24*time.Hour),
+		DepositExpiryInterval:  getDuration("DEPOSIT_EXPIRY_INTERVAL", 15*time.Minute),
+		AutoTopUpInterval:      getDuration("AUTO_TOP_UP_INTERVAL", time.Minute),
+		SettlementInterval:     getDuration("SETTLEMENT_RECONCILIATION_INTERVAL", 0),
+		SettlementAutoFix:      getBool("SETTLEMENT_RECONCILIATION_AUTO_FIX"),
+
+		WithdrawalVerifyAfter:    getDuration("WITHDRAWAL_VERIFY_AFTER", 30*time.Minute),
+		WithdrawalVerifyInterval: getDuration("WITHDRAWAL_VERIFY_INTERVAL", 15*time.Minute),
+		RefundVerifyAfter:        getDuration("REFUND_VERIFY_AFTER", time.Hour),
+		RefundVerifyInterval:     getDuration("REFUND_VERIFY_INTERVAL", 15*time.Minute),
+
+		PaystackWebhookPreviousSecrets: getList("PAYSTACK_WEBHOOK_PREVIOUS_SECRETS"),
+		PaystackWebhookRotationEnds:    getTime("PAYSTACK_WEBHOOK_ROTATION_ENDS"),
+		PaystackWebhookAllowedIPs:      getList("PAYSTACK_WEBHOOK_ALLOWED_IPS"),
+		PaystackVirtualAccountBank:     getEnv("PAYSTACK_DVA_BANK", "wema-bank"),
+		TrustedProxies:                 getList("TRUSTED_PROXIES"),
+
+		FlutterwaveSecret:            getEnv("FLUTTERWAVE_SECRET_KEY", ""),
+		FlutterwaveBaseURL:           getEnv("FLUTTERWAVE_BASE_URL", "https://api.flutterwave.com/v3"),
+		FlutterwaveWebhookHash:       getEnv("FLUTTERWAVE_WEBHOOK_HASH", ""),
+		FlutterwaveRedirectURL:       getEnv("FLUTTERWAVE_REDIRECT_URL", ""),
+		FlutterwaveWebhookAllowedIPs: getList("FLUTTERWAVE_WEBHOOK_ALLOWED_IPS"),
+		PaymentGateways:              getList("PAYMENT_GATEWAYS"),
+
+		PublicURL:                  strings.TrimSuffix(getEnv("PUBLIC_URL", ""), "/"),
+		DepositCallbackAllowedURLs: getList("DEPOSIT_CALLBACK_ALLOWED_URLS"),
+		DepositChannels:            getList("DEPOSIT_CHANNELS"),
 	}
 
 	if cfg.DBURL == "" {
@@ -43,12 +137,101 @@ func Load() Config {
 	if cfg.PaystackSecret == "" {
 		log.Fatal("PAYSTACK_SECRET_KEY is required")
 	}
+	for _, entry := range cfg.PaystackWebhookAllowedIPs {
+		if !validIPOrCIDR(entry) {
+			log.Fatalf("PAYSTACK_WEBHOOK_ALLOWED_IPS: %q is not an IP address or CIDR", entry)
+		}
+	}
+	for _, entry := range cfg.FlutterwaveWebhookAllowedIPs {
+		if !validIPOrCIDR(entry) {
+			log.Fatalf("FLUTTERWAVE_WEBHOOK_ALLOWED_IPS: %q is not an IP address or CIDR", entry)
+		}
+	}
+	cfg.PaymentGateways = paymentGateways(cfg)
+	if cfg.PublicURL != "" {
+		if u, err := url.Parse(cfg.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			log.Fatalf("PUBLIC_URL: %q is not an http(s) URL", cfg.PublicURL)
+		}
+	}
+	if cfg.AuthRedirectURL != "" {
+		if u, err := url.Parse(cfg.AuthRedirectURL); err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+			log.Fatalf("AUTH_REDIRECT_URL: %q must be an absolute URL without a fragment", cfg.AuthRedirectURL)
+		}
+	}
+	for _, entry := range cfg.DepositCallbackAllowedURLs {
+		if u, err := url.Parse(entry); err != nil || u.Scheme == "" || u.Host == "" {
+			log.Fatalf("DEPOSIT_CALLBACK_ALLOWED_URLS: %q must be an absolute URL such as https://app.example.com/paid or myapp://payments", entry)
+		}
+	}
+	for _, channel := range cfg.DepositChannels {
+		if !depositChannels[channel] {
+			log.Fatalf("DEPOSIT_CHANNELS: unknown channel %q", channel)
+		}
+	}
+	if cfg.AccessTokenTTL <= 0 || cfg.RefreshTokenTTL <= cfg.AccessTokenTTL {
+		log.Fatal("ACCESS_TOKEN_TTL must be positive and shorter than REFRESH_TOKEN_TTL")
+	}
+	if cfg.DepositExpiryTTL <= 0 {
+		log.Fatal("DEPOSIT_EXPIRY_TTL must be positive")
+	}
+	if cfg.WithdrawalVerifyAfter <= 0 {
+		log.Fatal("WITHDRAWAL_VERIFY_AFTER must be positive")
+	}
+	if cfg.RefundVerifyAfter <= 0 {
+		log.Fatal("REFUND_VERIFY_AFTER must be positive")
+	}
+	if len(cfg.PaystackWebhookPreviousSecrets) > 0 && cfg.PaystackWebhookRotationEnds.IsZero() {
+		log.Printf("warning: PAYSTACK_WEBHOOK_PREVIOUS_SECRETS accepted with no PAYSTACK_WEBHOOK_ROTATION_ENDS; remove them once rotation is done")
+	}
 	if cfg.GoogleClientID == "" || cfg.GoogleClientSecret == "" || cfg.GoogleRedirectURL == "" {
 		log.Fatal("GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET, and GOOGLE_REDIRECT_URL are required")
 	}
 	return cfg
 }
 
+// depositChannels are the checkout channels DEPOSIT_CHANNELS may name.
+var depositChannels = map[string]bool{
+	"card": true, "bank": true, "ussd": true, "qr": true, "mobile_money": true,
+	"bank_transfer": true, "eft": true, "apple_pay": true,
+}
+
+// paymentGateways validates PAYMENT_GATEWAYS. It defaults to Paystack followed by
+// Flutterwave when configured, and appends Paystack as the last resort if omitted.
+func paymentGateways(cfg Config) []string {
+	names := cfg.PaymentGateways
+	if len(names) == 0 {
+		names = []string{"paystack"}
+		if cfg.FlutterwaveSecret != "" {
+			names = append(names, "flutterwave")
+		}
+	}
+	var res []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		switch name {
+		case "paystack":
+		case "flutterwave":
+			if cfg.FlutterwaveSecret == "" {
+				log.Fatal("PAYMENT_GATEWAYS: flutterwave requires FLUTTERWAVE_SECRET_KEY")
+			}
+			if cfg.FlutterwaveWebhookHash == "" {
+				log.Printf("warning: FLUTTERWAVE_WEBHOOK_HASH is not set; Flutterwave webhooks will be rejected")
+			}
+		default:
+			log.Fatalf("PAYMENT_GATEWAYS: unknown gateway %q", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+	if !seen["paystack"] {
+		res = append(res, "paystack")
+	}
+	return res
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -56,6 +239,59 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getList(key string) []string {
+	var res []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if v := strings.TrimSpace(item); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("%s must be a duration (e.g. 30m, 24h): %v", key, err)
+	}
+	return d
+}
+
+func getBool(key string) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("%s must be true or false: %v", key, err)
+	}
+	return b
+}
+
+func getTime(key string) time.Time {
+	value := os.Getenv(key)
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Fatalf("%s must be an RFC3339 timestamp (e.g. 2025-01-31T00:00:00Z): %v", key, err)
+	}
+	return t
+}
+
+func validIPOrCIDR(entry string) bool {
+	if _, _, err := net.ParseCIDR(entry); err == nil {
+		return true
+	}
+	return net.ParseIP(entry) != nil
+}
+
 // ParseExpiry converts the custom expiry strings (1H,1D,1M,1Y) into a duration.
 func ParseExpiry(exp string) (time.Duration, error) {
 	switch exp {
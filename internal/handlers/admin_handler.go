@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler exposes operator-only endpoints.
+type AdminHandler struct {
+	reconciliation *services.ReconciliationService
+	settlements    *services.SettlementService
+	walletService  *services.WalletService
+	webhooks       *services.WebhookService
+}
+
+// NewAdminHandler constructs an AdminHandler.
+func NewAdminHandler(reconciliation *services.ReconciliationService, settlements *services.SettlementService, walletService *services.WalletService, webhooks *services.WebhookService) *AdminHandler {
+	return &AdminHandler{reconciliation: reconciliation, settlements: settlements, walletService: walletService, webhooks: webhooks}
+}
+
+// RunReconciliation recomputes wallet balances immediately and returns the drift report.
+func (h *AdminHandler) RunReconciliation(c *gin.Context) {
+	report, err := h.reconciliation.Run(services.ReconciliationTriggerManual)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ReconciliationRuns lists recent reconciliation runs.
+func (h *AdminHandler) ReconciliationRuns(c *gin.Context) {
+	runs, err := h.reconciliation.Runs(50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(runs))
+	for _, r := range runs {
+		resp = append(resp, gin.H{
+			"id":              r.ID,
+			"trigger":         r.Trigger,
+			"wallets_checked": r.WalletsChecked,
+			"mismatches":      r.MismatchCount,
+			"started_at":      r.StartedAt,
+			"finished_at":     r.FinishedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ReconciliationReport returns the stored report for a run.
+func (h *AdminHandler) ReconciliationReport(c *gin.Context) {
+	report, err := h.reconciliation.Report(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+type reverseTransferRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	Force  bool   `json:"force"`
+}
+
+// ReverseTransfer undoes a completed transfer with compensating entries on both wallets.
+func (h *AdminHandler) ReverseTransfer(c *gin.Context) {
+	var req reverseTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	reversalID, err := h.walletService.ReverseTransfer(services.ReversalRequest{
+		TransferID: c.Param("id"),
+		Operator:   middleware.GetUser(c).Email,
+		Reason:     req.Reason,
+		Force:      req.Force,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrRecipientInsufficientFunds) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "reversal_id": reversalID, "transfer_id": c.Param("id")})
+}
+
+type refundDepositRequest struct {
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// RefundDeposit returns all or part of a successful deposit to the payer through
+// Paystack. The refund is pending until Paystack reports it processed or failed.
+func (h *AdminHandler) RefundDeposit(c *gin.Context) {
+	var req refundDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	refund, err := h.walletService.RefundDeposit(c.Request.Context(), services.RefundRequest{
+		DepositReference: c.Param("reference"),
+		Amount:           req.Amount,
+		Operator:         middleware.GetUser(c).Email,
+		Reason:           req.Reason,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrRefundExceedsDeposit) {
+			status = http.StatusConflict
+		}
+		respondError(c, err, status)
+		return
+	}
+	c.JSON(http.StatusAccepted, refundResponse(*refund))
+}
+
+// DepositRefunds lists the refunds of a deposit.
+func (h *AdminHandler) DepositRefunds(c *gin.Context) {
+	refunds, err := h.walletService.Refunds(c.Param("reference"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(refunds))
+	for _, r := range refunds {
+		resp = append(resp, refundResponse(r))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func refundResponse(r models.Transaction) gin.H {
+	return gin.H{
+		"reference":          r.Reference,
+		"deposit_reference":  r.OriginalReference,
+		"status":             r.Status,
+		"amount":             r.Amount,
+		"currency":           r.Currency,
+		"provider_refund_id": r.ProviderReference,
+		"reason":             r.Narration,
+		"created_at":         r.CreatedAt,
+		"updated_at":         r.UpdatedAt,
+	}
+}
+
+// WebhookEvents lists stored webhooks, newest first, filtered by status, event_type or reference.
+func (h *AdminHandler) WebhookEvents(c *gin.Context) {
+	filter := services.WebhookFilter{
+		Status:    models.WebhookEventStatus(c.Query("status")),
+		EventType: c.Query("event_type"),
+		Reference: c.Query("reference"),
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.Limit = limit
+	}
+	events, err := h.webhooks.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(events))
+	for _, e := range events {
+		resp = append(resp, webhookEventResponse(e))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// WebhookEvent returns one stored webhook including its headers and raw body.
+func (h *AdminHandler) WebhookEvent(c *gin.Context) {
+	event, err := h.webhooks.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	resp := webhookEventResponse(*event)
+	resp["headers"] = json.RawMessage(event.Headers)
+	resp["body"] = string(event.Body)
+	c.JSON(http.StatusOK, resp)
+}
+
+// ReplayWebhook reprocesses a stored webhook now and returns its new state.
+func (h *AdminHandler) ReplayWebhook(c *gin.Context) {
+	event, err := h.webhooks.Replay(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, services.ErrWebhookNotReplayable) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhookEventResponse(*event))
+}
+
+func webhookEventResponse(e models.WebhookEvent) gin.H {
+	return gin.H{
+		"id":              e.ID,
+		"provider":        e.Provider,
+		"event_id":        e.EventID,
+		"event_type":      e.EventType,
+		"reference":       e.Reference,
+		"signature_valid": e.SignatureValid,
+		"status":          e.Status,
+		"attempts":        e.Attempts,
+		"last_error":      e.LastError,
+		"next_attempt_at": e.NextAttemptAt,
+		"processed_at":    e.ProcessedAt,
+		"created_at":      e.CreatedAt,
+	}
+}
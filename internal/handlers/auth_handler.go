@@ -1,8 +1,11 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/CyberwizD/Wallet-Service/internal/auth"
@@ -13,59 +16,182 @@ import (
 	"golang.org/x/oauth2"
 )
 
+const (
+	// oauthStateCookie carries the signed auth.OAuthState between sign-in redirect and callback.
+	oauthStateCookie = "oauth_state"
+	// refreshTokenCookie hands the refresh token to a browser frontend without
+	// exposing it to scripts; /auth/refresh and /auth/logout read it when the body has none.
+	refreshTokenCookie = "refresh_token"
+)
+
 // AuthHandler manages Google auth endpoints.
 type AuthHandler struct {
 	cfg         config.Config
 	userService *services.UserService
+	sessions    *services.SessionService
 	oauthConfig *oauth2.Config
+	idTokens    *auth.GoogleIDTokenVerifier
 }
 
 // NewAuthHandler constructs an AuthHandler.
-func NewAuthHandler(cfg config.Config, userService *services.UserService) *AuthHandler {
+func NewAuthHandler(cfg config.Config, userService *services.UserService, sessions *services.SessionService) *AuthHandler {
 	return &AuthHandler{
 		cfg:         cfg,
 		userService: userService,
+		sessions:    sessions,
 		oauthConfig: auth.NewGoogleOAuth(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL),
+		idTokens:    auth.NewGoogleIDTokenVerifier(cfg.GoogleClientID, ""),
 	}
 }
 
-// StartGoogleAuth redirects to Google's OAuth consent screen.
+// SetGoogleEndpoints replaces Google's authorization, token and signing key
+// endpoints, e.g. with a test double.
+func (h *AuthHandler) SetGoogleEndpoints(endpoint oauth2.Endpoint, certsURL string) {
+	h.oauthConfig.Endpoint = endpoint
+	h.idTokens = auth.NewGoogleIDTokenVerifier(h.cfg.GoogleClientID, certsURL)
+}
+
+// StartGoogleAuth redirects to Google's OAuth consent screen with a fresh state,
+// nonce and PKCE challenge, remembered in a short-lived signed cookie.
 func (h *AuthHandler) StartGoogleAuth(c *gin.Context) {
-	url := h.oauthConfig.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
+	verifier := oauth2.GenerateVerifier()
+	state := auth.NewOAuthState(verifier, time.Now())
+	value, err := state.Sign(h.cfg.JWTSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot start sign-in"})
+		return
+	}
+	h.setStateCookie(c, value, int(auth.OAuthStateTTL.Seconds()))
+	url := h.oauthConfig.AuthCodeURL(state.State,
+		oauth2.AccessTypeOffline,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", state.Nonce),
+	)
 	c.Redirect(http.StatusFound, url)
 }
 
-// GoogleCallback exchanges the code, upserts the user, and returns a JWT.
+// GoogleCallback checks the state against the sign-in cookie, exchanges the code
+// with its PKCE verifier, verifies the ID token, upserts the user and opens a
+// session. With AUTH_REDIRECT_URL set the access token, or the error, is passed to
+// it in the URL fragment and the refresh token is set as an HttpOnly cookie;
+// otherwise the tokens are returned as JSON.
 func (h *AuthHandler) GoogleCallback(c *gin.Context) {
+	cookie, _ := c.Cookie(oauthStateCookie)
+	h.setStateCookie(c, "", -1) // each state is good for one callback
+	if reason := c.Query("error"); reason != "" {
+		h.authFailed(c, http.StatusBadRequest, "google sign-in failed", reason)
+		return
+	}
+	state, err := auth.ParseOAuthState(cookie, h.cfg.JWTSecret, c.Query("state"), time.Now())
+	if err != nil {
+		h.authFailed(c, http.StatusBadRequest, "invalid oauth state", "restart sign-in from /auth/google")
+		return
+	}
 	code := c.Query("code")
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing code"})
+		h.authFailed(c, http.StatusBadRequest, "missing code", "")
 		return
 	}
-	token, err := auth.ExchangeCode(context.Background(), h.oauthConfig, code)
+	ctx := c.Request.Context()
+	token, err := auth.ExchangeCode(ctx, h.oauthConfig, code, state.Verifier)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot exchange code", "details": err.Error()})
+		h.authFailed(c, http.StatusBadRequest, "cannot exchange code", err.Error())
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		h.authFailed(c, http.StatusUnauthorized, "google returned no id token", "")
+		return
+	}
+	claims, err := h.idTokens.Verify(ctx, rawIDToken, state.Nonce)
+	if errors.Is(err, auth.ErrEmailNotVerified) {
+		h.authFailed(c, http.StatusForbidden, err.Error(), "")
 		return
 	}
-	userInfo, err := auth.FetchGoogleUser(context.Background(), token)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot fetch google user", "details": err.Error()})
+		h.authFailed(c, http.StatusUnauthorized, "invalid id token", err.Error())
 		return
 	}
-	user, err := h.userService.UpsertGoogleUser(userInfo.Email, userInfo.Name)
+	name := claims.Name
+	if name == "" {
+		if userInfo, err := auth.FetchGoogleUser(ctx, token); err == nil {
+			name = userInfo.Name
+		}
+	}
+	user, err := h.userService.UpsertGoogleUser(claims.Email, name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "unable to persist user", "details": err.Error()})
+		h.authFailed(c, http.StatusInternalServerError, "unable to persist user", err.Error())
 		return
 	}
-	jwtToken, err := auth.GenerateToken(user.ID, user.Email, h.cfg.JWTSecret, 24*time.Hour)
+	tokens, err := h.sessions.Start(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot generate jwt", "details": err.Error()})
+		h.authFailed(c, http.StatusInternalServerError, "cannot start session", err.Error())
+		return
+	}
+	if h.cfg.AuthRedirectURL != "" {
+		h.setRefreshCookie(c, tokens)
+		h.redirectWithFragment(c, url.Values{
+			"token":        {tokens.AccessToken},
+			"expires_in_s": {strconv.FormatInt(secondsUntil(tokens.AccessExpiresAt), 10)},
+			"session_id":   {tokens.SessionID},
+		})
+		return
+	}
+	resp := tokensResponse(tokens)
+	resp["user"] = gin.H{"email": user.Email, "name": user.Name, "id": user.ID}
+	resp["wallet"] = gin.H{"number": user.Wallet.Number, "balance": user.Wallet.Balance, "currency": user.Wallet.Currency}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// authFailed reports a failed sign-in as JSON, or to the frontend when AUTH_REDIRECT_URL is set.
+func (h *AuthHandler) authFailed(c *gin.Context, status int, message, details string) {
+	if h.cfg.AuthRedirectURL != "" {
+		h.redirectWithFragment(c, url.Values{"error": {message}})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"token":        jwtToken,
-		"user":         gin.H{"email": user.Email, "name": user.Name, "id": user.ID},
-		"wallet":       gin.H{"number": user.Wallet.Number, "balance": user.Wallet.Balance},
-		"expires_in_s": int64((24 * time.Hour).Seconds()),
+	body := gin.H{"error": message}
+	if details != "" {
+		body["details"] = details
+	}
+	c.JSON(status, body)
+}
+
+// redirectWithFragment sends the browser to AUTH_REDIRECT_URL with values in the
+// fragment, which browsers do not send to servers or in Referer headers.
+func (h *AuthHandler) redirectWithFragment(c *gin.Context, values url.Values) {
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, h.cfg.AuthRedirectURL+"#"+values.Encode())
+}
+
+func (h *AuthHandler) setStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     "/auth/google",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.cfg.GoogleRedirectURL, "https://"),
+		// Lax still sends the cookie on Google's top-level redirect back to us.
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// setRefreshCookie stores the session's refresh token in an HttpOnly cookie
+// scoped to the /auth endpoints that take it; nil tokens clear it.
+func (h *AuthHandler) setRefreshCookie(c *gin.Context, tokens *services.SessionTokens) {
+	value, maxAge := "", -1
+	if tokens != nil {
+		value, maxAge = tokens.RefreshToken, int(time.Until(tokens.RefreshExpiresAt).Seconds())
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    value,
+		Path:     "/auth",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.cfg.GoogleRedirectURL, "https://"),
+		// Only the frontend's own requests may refresh or end the session.
+		SameSite: http.SameSiteStrictMode,
 	})
 }
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AutoTopUpHandler exposes the auto top-up rule of a wallet.
+type AutoTopUpHandler struct {
+	service *services.AutoTopUpService
+}
+
+// NewAutoTopUpHandler constructs an AutoTopUpHandler.
+func NewAutoTopUpHandler(service *services.AutoTopUpService) *AutoTopUpHandler {
+	return &AutoTopUpHandler{service: service}
+}
+
+type setAutoTopUpRequest struct {
+	CardID    string `json:"card_id" binding:"required"`
+	Threshold int64  `json:"threshold" binding:"required"`
+	Amount    int64  `json:"amount" binding:"required"`
+	Currency  string `json:"currency"`
+}
+
+// Set creates or replaces the auto top-up rule of the caller's wallet.
+func (h *AutoTopUpHandler) Set(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req setAutoTopUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	rule, err := h.service.Set(user, services.AutoTopUpRequest{
+		CardID:    req.CardID,
+		Threshold: req.Threshold,
+		Amount:    req.Amount,
+		Currency:  req.Currency,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, autoTopUpResponse(*rule))
+}
+
+// Get returns the auto top-up rule of the caller's wallet; ?currency= selects a non-default wallet.
+func (h *AutoTopUpHandler) Get(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	rule, err := h.service.Get(user.ID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, autoTopUpResponse(*rule))
+}
+
+// Delete removes the auto top-up rule of the caller's wallet.
+func (h *AutoTopUpHandler) Delete(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	if err := h.service.Delete(user.ID, c.Query("currency")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrAutoTopUpNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func autoTopUpResponse(rule models.AutoTopUp) gin.H {
+	return gin.H{
+		"id":              rule.ID,
+		"card_id":         rule.CardID,
+		"threshold":       rule.Threshold,
+		"amount":          rule.Amount,
+		"status":          rule.Status,
+		"failures":        rule.Failures,
+		"last_error":      rule.LastError,
+		"last_attempt_at": rule.LastAttemptAt,
+	}
+}
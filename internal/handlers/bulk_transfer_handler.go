@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBulkCSVBytes bounds a bulk transfer request; a full batch of CSV rows with
+// maximum-length narrations is about 300 KiB.
+const maxBulkCSVBytes = 1 << 20
+
+// BulkTransferHandler exposes bulk transfer endpoints.
+type BulkTransferHandler struct {
+	service *services.BulkTransferService
+}
+
+// NewBulkTransferHandler constructs a BulkTransferHandler.
+func NewBulkTransferHandler(service *services.BulkTransferService) *BulkTransferHandler {
+	return &BulkTransferHandler{service: service}
+}
+
+type bulkTransferItem struct {
+	WalletNumber string `json:"wallet_number"`
+	Amount       int64  `json:"amount"`
+	Narration    string `json:"narration"`
+}
+
+type bulkTransferRequest struct {
+	Mode     string             `json:"mode"`
+	Currency string             `json:"currency"`
+	Items    []bulkTransferItem `json:"items" binding:"required"`
+}
+
+// Create accepts a JSON list or a CSV upload (multipart field "file", or a text/csv body)
+// with columns wallet_number,amount[,narration]. For CSV the mode and currency come
+// from the "mode" and "currency" parameters.
+func (h *BulkTransferHandler) Create(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var (
+		mode, currency string
+		items          []services.BulkTransferItem
+		tooLarge       *http.MaxBytesError
+		err            error
+	)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkCSVBytes)
+	switch contentType := c.ContentType(); {
+	case contentType == "multipart/form-data":
+		mode, currency = c.PostForm("mode"), c.PostForm("currency")
+		file, ferr := c.FormFile("file")
+		if errors.As(ferr, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CSV file too large"})
+			return
+		}
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing CSV file", "details": ferr.Error()})
+			return
+		}
+		f, ferr := file.Open()
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read CSV file"})
+			return
+		}
+		defer f.Close()
+		items, err = parseBulkCSV(f)
+	case contentType == "text/csv":
+		mode, currency = c.Query("mode"), c.Query("currency")
+		items, err = parseBulkCSV(c.Request.Body)
+	default:
+		var req bulkTransferRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+			return
+		}
+		mode, currency = req.Mode, req.Currency
+		for _, item := range req.Items {
+			items = append(items, services.BulkTransferItem(item))
+		}
+	}
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CSV file too large"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	batch, err := h.service.Create(user, models.BatchMode(mode), currency, items)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, batchResponse(batch))
+}
+
+// Status returns a batch and its per-item results.
+func (h *BulkTransferHandler) Status(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	batch, err := h.service.Get(user.ID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, batchResponse(batch))
+}
+
+// parseBulkCSV reads wallet_number,amount[,narration] rows; a wallet_number,amount header row is skipped.
+// It stops at the first row past MaxBulkTransferItems.
+func parseBulkCSV(r io.Reader) ([]services.BulkTransferItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var items []services.BulkTransferItem
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected wallet_number,amount[,narration]", line)
+		}
+		if line == 1 && isBulkCSVHeader(record) {
+			continue
+		}
+		amount, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: amount must be an integer in kobo", line)
+		}
+		item := services.BulkTransferItem{WalletNumber: strings.TrimSpace(record[0]), Amount: amount}
+		if len(record) > 2 {
+			item.Narration = strings.TrimSpace(record[2])
+		}
+		if items = append(items, item); len(items) > services.MaxBulkTransferItems {
+			return nil, fmt.Errorf("a batch can contain at most %d items", services.MaxBulkTransferItems)
+		}
+	}
+	return items, nil
+}
+
+// isBulkCSVHeader reports whether record is the wallet_number,amount[,narration] header row.
+func isBulkCSVHeader(record []string) bool {
+	first := strings.TrimPrefix(strings.TrimSpace(record[0]), "\ufeff")
+	return strings.EqualFold(first, "wallet_number") && strings.EqualFold(strings.TrimSpace(record[1]), "amount")
+}
+
+func batchResponse(batch *models.TransferBatch) gin.H {
+	items := make([]gin.H, 0, len(batch.Items))
+	for _, item := range batch.Items {
+		items = append(items, gin.H{
+			"position":      item.Position,
+			"wallet_number": item.WalletNumber,
+			"amount":        item.Amount,
+			"narration":     item.Narration,
+			"status":        item.Status,
+			"transfer_id":   item.TransferID,
+			"error":         item.Error,
+		})
+	}
+	return gin.H{
+		"batch_id":      batch.ID,
+		"currency":      batch.Currency,
+		"mode":          batch.Mode,
+		"status":        batch.Status,
+		"item_count":    batch.ItemCount,
+		"success_count": batch.SuccessCount,
+		"failed_count":  batch.FailedCount,
+		"total_amount":  batch.TotalAmount,
+		"items":         items,
+		"created_at":    batch.CreatedAt,
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cards lists the caller's saved cards.
+func (h *WalletHandler) Cards(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	cards, err := h.walletService.Cards(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(cards))
+	for _, card := range cards {
+		resp = append(resp, cardResponse(card))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RemoveCard deactivates and deletes a saved card.
+func (h *WalletHandler) RemoveCard(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	if err := h.walletService.RemoveCard(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrCardNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "removed", "id": c.Param("id")})
+}
+
+type chargeCardRequest struct {
+	Amount   int64  `json:"amount" binding:"required"`
+	Currency string `json:"currency"`
+}
+
+// ChargeCard tops up the caller's wallet from a saved card without a checkout redirect.
+func (h *WalletHandler) ChargeCard(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req chargeCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	tx, err := h.walletService.ChargeCard(c.Request.Context(), user, c.Param("id"), req.Amount, req.Currency)
+	if err != nil {
+		if errors.Is(err, services.ErrCardNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+	status := http.StatusOK
+	if tx.Status == models.TransactionPending {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, gin.H{
+		"reference": tx.Reference,
+		"status":    tx.Status,
+		"amount":    tx.Amount,
+		"currency":  tx.Currency,
+	})
+}
+
+func cardResponse(card models.SavedCard) gin.H {
+	return gin.H{
+		"id":         card.ID,
+		"brand":      card.Brand,
+		"last4":      card.Last4,
+		"bank":       card.Bank,
+		"exp_month":  card.ExpMonth,
+		"exp_year":   card.ExpYear,
+		"created_at": card.CreatedAt,
+	}
+}
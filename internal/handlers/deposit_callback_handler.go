@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var depositCallbackTemplate = template.Must(template.New("deposit-callback").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Deposit {{.Status}}</title>
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>body{font-family:sans-serif;max-width:28rem;margin:3rem auto;padding:0 1rem}</style>
+</head>
+<body>
+  <h2>{{.Heading}}</h2>
+  {{if .Reference}}<p><strong>{{.Currency}} {{.Major}}</strong></p>
+  <p>Reference: <code>{{.Reference}}</code></p>{{end}}
+  <p>{{.Message}}</p>
+</body>
+</html>`))
+
+type depositCallbackView struct {
+	Status    string
+	Heading   string
+	Message   string
+	Reference string
+	Currency  string
+	Major     string
+}
+
+// DepositCallback is where checkout returns the customer. It verifies the deposit
+// with its gateway, then redirects to the callback_url given when the deposit was
+// started, adding reference and status, or renders the outcome when there is none.
+// The reference is read from Paystack's reference or trxref, or Flutterwave's tx_ref.
+func (h *WalletHandler) DepositCallback(c *gin.Context) {
+	ref := c.Query("reference")
+	if ref == "" {
+		ref = c.Query("trxref")
+	}
+	if ref == "" {
+		ref = c.Query("tx_ref")
+	}
+	tx, err := h.walletService.DepositStatus(ref)
+	if ref == "" || err != nil || tx.Type != models.TransactionTypeDeposit {
+		renderDepositCallback(c, http.StatusNotFound, depositCallbackView{
+			Status:  "not found",
+			Heading: "Deposit not found",
+			Message: "We could not find this payment. Check your wallet for its status.",
+		})
+		return
+	}
+	if tx.Status == models.TransactionPending || tx.Status == models.TransactionExpired {
+		// A gateway that cannot be reached leaves the deposit pending; its webhook settles it later.
+		if refreshed, err := h.walletService.RefreshDeposit(c.Request.Context(), ref); err != nil {
+			log.Printf("deposit %s: callback verification: %v", ref, err)
+		} else {
+			tx = refreshed
+		}
+	}
+	if tx.CallbackURL != "" {
+		target, err := services.DepositReturnURL(tx.CallbackURL, tx.Reference, string(tx.Status))
+		if err == nil {
+			c.Redirect(http.StatusFound, target)
+			return
+		}
+		log.Printf("deposit %s: invalid callback URL: %v", ref, err)
+	}
+
+	view := depositCallbackView{
+		Status:    string(tx.Status),
+		Reference: tx.Reference,
+		Currency:  tx.Currency,
+		Major:     formatMajor(tx.Amount),
+	}
+	switch tx.Status {
+	case models.TransactionSuccess:
+		view.Heading, view.Message = "Payment received", "Your wallet has been credited."
+	case models.TransactionPending:
+		view.Heading, view.Message = "Payment processing", "We are confirming your payment; your wallet is credited as soon as it completes."
+	case models.TransactionFlagged:
+		view.Heading, view.Message = "Payment under review", "The payment did not match this deposit and is being reviewed."
+	default:
+		view.Heading, view.Message = "Payment not completed", "This deposit was not completed, so nothing was added to your wallet."
+	}
+	renderDepositCallback(c, http.StatusOK, view)
+}
+
+func renderDepositCallback(c *gin.Context, status int, view depositCallbackView) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := depositCallbackTemplate.Execute(c.Writer, view); err != nil {
+		log.Printf("deposit callback page: %v", err)
+	}
+}
+
+// formatMajor shows an amount in the currency's smallest unit in major units.
+func formatMajor(amount int64) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
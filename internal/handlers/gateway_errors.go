@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gatewayErrorStatuses maps payment provider failures to the status returned to
+// our caller. A provider refusing our credentials is our fault, not the caller's.
+var gatewayErrorStatuses = map[services.GatewayErrorKind]int{
+	services.GatewayValidation:  http.StatusBadRequest,
+	services.GatewayAuth:        http.StatusBadGateway,
+	services.GatewayRateLimited: http.StatusTooManyRequests,
+	services.GatewayUnavailable: http.StatusServiceUnavailable,
+}
+
+// respondError writes err with the status for its provider failure kind, or
+// fallback when err did not come from a payment provider.
+func respondError(c *gin.Context, err error, fallback int) {
+	var gerr *services.GatewayError
+	if !errors.As(err, &gerr) {
+		c.JSON(fallback, gin.H{"error": err.Error()})
+		return
+	}
+	status, ok := gatewayErrorStatuses[gerr.Kind]
+	if !ok {
+		status = http.StatusBadGateway
+	}
+	if gerr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(gerr.RetryAfter.Seconds())))
+	}
+	c.JSON(status, gin.H{"error": err.Error(), "provider": gerr.Provider, "provider_error": gerr.Kind})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type placeHoldRequest struct {
+	Amount           int64  `json:"amount" binding:"required"`
+	ExpiresInSeconds int64  `json:"expires_in_seconds"`
+	Description      string `json:"description"`
+	Currency         string `json:"currency"`
+}
+
+// PlaceHold reserves funds on the caller's wallet.
+func (h *WalletHandler) PlaceHold(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req placeHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	hold, err := h.walletService.PlaceHold(user, req.Currency, req.Amount, time.Duration(req.ExpiresInSeconds)*time.Second, req.Description)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, holdResponse(*hold))
+}
+
+// Holds lists the caller's holds.
+func (h *WalletHandler) Holds(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	holds, err := h.walletService.Holds(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(holds))
+	for _, hold := range holds {
+		resp = append(resp, holdResponse(hold))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type captureHoldRequest struct {
+	WalletNumber    string `json:"wallet_number" binding:"required"`
+	Amount          int64  `json:"amount"`
+	Narration       string `json:"narration"`
+	ClientReference string `json:"client_reference"`
+}
+
+// CaptureHold settles a hold, fully or partly, into a transfer.
+func (h *WalletHandler) CaptureHold(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req captureHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	transferID, err := h.walletService.CaptureHold(user, c.Param("id"), req.WalletNumber, req.Amount, services.TransferOptions{
+		Narration:       req.Narration,
+		ClientReference: req.ClientReference,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "hold_id": c.Param("id"), "transfer_id": transferID})
+}
+
+// ReleaseHold frees a hold without moving money.
+func (h *WalletHandler) ReleaseHold(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	if err := h.walletService.ReleaseHold(user, c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "hold_id": c.Param("id")})
+}
+
+func holdResponse(hold models.Hold) gin.H {
+	return gin.H{
+		"id":              hold.ID,
+		"reference":       hold.Reference,
+		"amount":          hold.Amount,
+		"currency":        hold.Currency,
+		"captured_amount": hold.CapturedAmount,
+		"status":          hold.Status,
+		"description":     hold.Description,
+		"transfer_id":     hold.TransferID,
+		"expires_at":      hold.ExpiresAt,
+		"created_at":      hold.CreatedAt,
+	}
+}
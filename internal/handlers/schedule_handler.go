@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleHandler exposes scheduled and recurring transfer endpoints.
+type ScheduleHandler struct {
+	service *services.ScheduleService
+}
+
+// NewScheduleHandler constructs a ScheduleHandler.
+func NewScheduleHandler(service *services.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{service: service}
+}
+
+type createScheduleRequest struct {
+	WalletNumber string     `json:"wallet_number" binding:"required"`
+	Amount       int64      `json:"amount" binding:"required"`
+	Narration    string     `json:"narration"`
+	Frequency    string     `json:"frequency" binding:"required"`
+	StartAt      time.Time  `json:"start_at" binding:"required"`
+	EndAt        *time.Time `json:"end_at"`
+	MaxRuns      int        `json:"max_runs"`
+	Currency     string     `json:"currency"`
+}
+
+// Create schedules a one-off or recurring transfer from the caller's wallet.
+func (h *ScheduleHandler) Create(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req createScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	order, err := h.service.Create(user, services.ScheduleRequest{
+		DestWalletNumber: req.WalletNumber,
+		Amount:           req.Amount,
+		Narration:        req.Narration,
+		Frequency:        models.ScheduleFrequency(req.Frequency),
+		StartAt:          req.StartAt,
+		EndAt:            req.EndAt,
+		MaxRuns:          req.MaxRuns,
+		Currency:         req.Currency,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, standingOrderResponse(*order))
+}
+
+// List returns the caller's scheduled transfers.
+func (h *ScheduleHandler) List(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	orders, err := h.service.List(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(orders))
+	for _, o := range orders {
+		resp = append(resp, standingOrderResponse(o))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Cancel stops a scheduled transfer.
+func (h *ScheduleHandler) Cancel(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	if err := h.service.Cancel(user.ID, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "cancelled", "id": c.Param("id")})
+}
+
+// Runs returns the execution history of a scheduled transfer.
+func (h *ScheduleHandler) Runs(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	runs, err := h.service.Runs(user.ID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(runs))
+	for _, r := range runs {
+		resp = append(resp, gin.H{
+			"scheduled_for": r.ScheduledFor,
+			"status":        r.Status,
+			"transfer_id":   r.TransferID,
+			"error":         r.Error,
+			"executed_at":   r.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func standingOrderResponse(o models.StandingOrder) gin.H {
+	return gin.H{
+		"id":            o.ID,
+		"wallet_number": o.DestWalletNumber,
+		"amount":        o.Amount,
+		"currency":      o.Currency,
+		"narration":     o.Narration,
+		"frequency":     o.Frequency,
+		"start_at":      o.StartAt,
+		"next_run_at":   o.NextRunAt,
+		"end_at":        o.EndAt,
+		"max_runs":      o.MaxRuns,
+		"run_count":     o.RunCount,
+		"skipped_runs":  o.SkippedRuns,
+		"status":        o.Status,
+		"created_at":    o.CreatedAt,
+	}
+}
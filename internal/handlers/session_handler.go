@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh trades a refresh token for a new access token and refresh token. A
+// token taken from the refresh cookie is rotated in the cookie and left out of
+// the response.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	refreshToken, fromCookie := refreshTokenFrom(c, req.RefreshToken)
+	if refreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+	tokens, err := h.sessions.Refresh(refreshToken, c.Request.UserAgent(), c.ClientIP())
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		if fromCookie {
+			h.setRefreshCookie(c, nil)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrRefreshTokenRotated) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := tokensResponse(tokens)
+	if fromCookie {
+		h.setRefreshCookie(c, tokens)
+		delete(resp, "refresh_token")
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
+
+// Logout revokes the refresh token's session, or every session of its user with all.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req logoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	refreshToken, fromCookie := refreshTokenFrom(c, req.RefreshToken)
+	if refreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+	if fromCookie {
+		h.setRefreshCookie(c, nil)
+	}
+	revoked, err := h.sessions.Logout(refreshToken, req.All)
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "logged_out", "revoked": revoked})
+}
+
+// Sessions lists the caller's active sessions, marking the one making the request.
+func (h *AuthHandler) Sessions(c *gin.Context) {
+	user := sessionUser(c)
+	if user == "" {
+		return
+	}
+	sessions, err := h.sessions.Sessions(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	current := middleware.GetSessionID(c)
+	resp := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, gin.H{
+			"id":           s.ID,
+			"user_agent":   s.UserAgent,
+			"ip":           s.IP,
+			"created_at":   s.CreatedAt,
+			"last_used_at": s.LastUsedAt,
+			"expires_at":   s.ExpiresAt,
+			"current":      s.ID == current,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeSession ends one of the caller's sessions.
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	user := sessionUser(c)
+	if user == "" {
+		return
+	}
+	if err := h.sessions.Revoke(user, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked", "id": c.Param("id")})
+}
+
+// sessionUser returns the ID of a JWT-authenticated caller, or replies with an
+// error and returns "" for API keys, which have no sessions.
+func sessionUser(c *gin.Context) string {
+	if middleware.GetAPIKey(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot manage sessions"})
+		return ""
+	}
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user authentication required"})
+		return ""
+	}
+	return user.ID
+}
+
+// refreshTokenFrom returns the refresh token sent in the body, or else the one in
+// the refresh cookie, and whether it came from the cookie.
+func refreshTokenFrom(c *gin.Context, body string) (string, bool) {
+	if body != "" {
+		return body, false
+	}
+	cookie, _ := c.Cookie(refreshTokenCookie)
+	return cookie, cookie != ""
+}
+
+func tokensResponse(tokens *services.SessionTokens) gin.H {
+	return gin.H{
+		"token":              tokens.AccessToken,
+		"expires_in_s":       secondsUntil(tokens.AccessExpiresAt),
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"session_id":         tokens.SessionID,
+	}
+}
+
+func secondsUntil(t time.Time) int64 {
+	return int64(time.Until(t).Round(time.Second).Seconds())
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSettlementCSVBytes bounds a settlement export upload, about 50,000 charges.
+const maxSettlementCSVBytes = 10 << 20
+
+type reconcileSettlementsRequest struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+	Fix  bool   `json:"fix"`
+}
+
+// ReconcileSettlements matches Paystack charges for a period to deposits and returns
+// the discrepancy report. A JSON body pulls the charges from the Paystack API; a CSV
+// upload (multipart field "file", or a text/csv body) uses a Paystack dashboard
+// export instead, with from, to and fix given as parameters.
+func (h *AdminHandler) ReconcileSettlements(c *gin.Context) {
+	var (
+		req      reconcileSettlementsRequest
+		charges  []services.PaystackCharge
+		fromCSV  = true
+		tooLarge *http.MaxBytesError
+		err      error
+	)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSettlementCSVBytes)
+	switch contentType := c.ContentType(); {
+	case contentType == "multipart/form-data":
+		req = reconcileSettlementsRequest{From: c.PostForm("from"), To: c.PostForm("to"), Fix: c.PostForm("fix") == "true"}
+		file, ferr := c.FormFile("file")
+		if errors.As(ferr, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CSV file too large"})
+			return
+		}
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing CSV file", "details": ferr.Error()})
+			return
+		}
+		f, ferr := file.Open()
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read CSV file"})
+			return
+		}
+		defer f.Close()
+		charges, err = parseSettlementCSV(f)
+	case contentType == "text/csv":
+		req = reconcileSettlementsRequest{From: c.Query("from"), To: c.Query("to"), Fix: c.Query("fix") == "true"}
+		charges, err = parseSettlementCSV(c.Request.Body)
+	default:
+		fromCSV = false
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+			return
+		}
+	}
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CSV file too large"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	period := services.SettlementRequest{Fix: req.Fix, Trigger: services.ReconciliationTriggerManual}
+	if period.From, err = parsePeriodBound("from", req.From); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if period.To, err = parsePeriodBound("to", req.To); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var report *services.SettlementReport
+	if fromCSV {
+		report, err = h.settlements.RunFromExport(c.Request.Context(), period, charges)
+	} else {
+		report, err = h.settlements.RunFromPaystack(c.Request.Context(), period)
+	}
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// SettlementRuns lists recent settlement runs.
+func (h *AdminHandler) SettlementRuns(c *gin.Context) {
+	runs, err := h.settlements.Runs(50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(runs))
+	for _, r := range runs {
+		resp = append(resp, gin.H{
+			"id":              r.ID,
+			"source":          r.Source,
+			"trigger":         r.Trigger,
+			"from":            r.PeriodStart,
+			"to":              r.PeriodEnd,
+			"charges_checked": r.ChargesChecked,
+			"discrepancies":   r.DiscrepancyCount,
+			"fixed":           r.FixedCount,
+			"started_at":      r.StartedAt,
+			"finished_at":     r.FinishedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SettlementReport returns the stored report for a settlement run.
+func (h *AdminHandler) SettlementReport(c *gin.Context) {
+	report, err := h.settlements.Report(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// parsePeriodBound accepts an RFC3339 timestamp or a date, meaning midnight UTC.
+func parsePeriodBound(name, value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC3339 timestamp or a YYYY-MM-DD date", name)
+}
+
+// settlementCSVColumns maps the header names of a Paystack transactions export,
+// lower-cased, to the fields we read.
+var settlementCSVColumns = map[string]string{
+	"reference":             "reference",
+	"transaction reference": "reference",
+	"amount":                "amount",
+	"amount paid":           "amount",
+	"currency":              "currency",
+	"status":                "status",
+	"email":                 "email",
+	"customer email":        "email",
+	"channel":               "channel",
+	"paid at":               "paid_at",
+	"paid_at":               "paid_at",
+	"transaction date":      "paid_at",
+	"settlement":            "settlement",
+	"settlement id":         "settlement",
+	"settlement reference":  "settlement",
+}
+
+// parseSettlementCSV reads a Paystack transactions export. The header row names the
+// columns; reference, amount (in major units, as exported) and status are required.
+func parseSettlementCSV(r io.Reader) ([]services.PaystackCharge, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := settlementCSVColumns[name]; ok {
+			if _, dup := columns[field]; !dup {
+				columns[field] = i
+			}
+		}
+	}
+	for _, field := range []string{"reference", "amount", "status"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("CSV header must include a %s column", field)
+		}
+	}
+	var charges []services.PaystackCharge
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		amount, err := services.ParsePaystackAmount(get("amount"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		charge := services.PaystackCharge{
+			Reference:    get("reference"),
+			Status:       strings.ToLower(get("status")),
+			Amount:       amount,
+			Currency:     strings.ToUpper(get("currency")),
+			Email:        get("email"),
+			Channel:      get("channel"),
+			SettlementID: get("settlement"),
+		}
+		if charge.Currency == "" {
+			charge.Currency = models.DefaultCurrency
+		}
+		if paidAt := get("paid_at"); paidAt != "" {
+			for _, layout := range []string{time.RFC3339, time.DateTime} {
+				if t, err := time.Parse(layout, paidAt); err == nil {
+					charge.PaidAt = &t
+					break
+				}
+			}
+		}
+		charges = append(charges, charge)
+	}
+	return charges, nil
+}
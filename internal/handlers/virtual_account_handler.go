@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AssignVirtualAccount gives the caller's wallet (?currency= selects a non-default
+// one) a Paystack dedicated virtual account, or returns the one it already has.
+func (h *WalletHandler) AssignVirtualAccount(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	wallet, created, err := h.walletService.AssignVirtualAccount(c.Request.Context(), user, c.Query("currency"))
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, virtualAccountResponse(wallet))
+}
+
+// VirtualAccount returns the dedicated virtual account of the caller's wallet.
+func (h *WalletHandler) VirtualAccount(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	wallet, err := h.walletService.WalletFor(user.ID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if wallet.VirtualAccountNumber == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "wallet has no virtual account"})
+		return
+	}
+	c.JSON(http.StatusOK, virtualAccountResponse(wallet))
+}
+
+func virtualAccountResponse(wallet *models.Wallet) gin.H {
+	return gin.H{
+		"account_number": wallet.VirtualAccountNumber,
+		"account_name":   wallet.VirtualAccountName,
+		"bank_name":      wallet.VirtualAccountBank,
+		"currency":       wallet.Currency,
+		"wallet_number":  wallet.Number,
+	}
+}
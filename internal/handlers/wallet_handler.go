@@ -1,11 +1,14 @@
 package handlers
 
 import (
-	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
 	"github.com/CyberwizD/Wallet-Service/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -14,19 +17,22 @@ import (
 // WalletHandler exposes wallet endpoints.
 type WalletHandler struct {
 	walletService *services.WalletService
-	paystack      *services.PaystackService
 }
 
 // NewWalletHandler constructs a WalletHandler.
-func NewWalletHandler(walletService *services.WalletService, paystack *services.PaystackService) *WalletHandler {
-	return &WalletHandler{walletService: walletService, paystack: paystack}
+func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
+	return &WalletHandler{walletService: walletService}
 }
 
 type depositRequest struct {
-	Amount int64 `json:"amount" binding:"required"`
+	Amount      int64             `json:"amount" binding:"required"`
+	Currency    string            `json:"currency"`
+	CallbackURL string            `json:"callback_url"`
+	Channels    []string          `json:"channels"`
+	Metadata    map[string]string `json:"metadata"`
 }
 
-// Deposit starts a Paystack deposit flow.
+// Deposit starts a deposit checkout with the first available payment gateway.
 func (h *WalletHandler) Deposit(c *gin.Context) {
 	user := middleware.GetUser(c)
 	if user == nil {
@@ -38,83 +44,132 @@ func (h *WalletHandler) Deposit(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
 		return
 	}
-	ref, authURL, err := h.walletService.InitiateDeposit(user, req.Amount)
+	tx, authURL, err := h.walletService.InitiateDeposit(c.Request.Context(), user, req.Amount, req.Currency, services.DepositOptions{
+		CallbackURL: req.CallbackURL,
+		Channels:    req.Channels,
+		Metadata:    req.Metadata,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"reference": ref, "authorization_url": authURL})
+	c.JSON(http.StatusOK, gin.H{"reference": tx.Reference, "authorization_url": authURL, "provider": tx.Provider})
 }
 
-// PaystackWebhook handles Paystack transaction events (must remain idempotent).
-func (h *WalletHandler) PaystackWebhook(c *gin.Context) {
-	signature := c.GetHeader("x-paystack-signature")
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read body"})
+// DepositStatus returns the status of a deposit reference. With ?verify=true a
+// pending or expired deposit is re-verified with its gateway and settled if it has completed.
+func (h *WalletHandler) DepositStatus(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
-	if !h.paystack.VerifySignature(body, signature) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+	ref := c.Param("reference")
+	// Another user's reference is reported as unknown and never sent to the gateway.
+	tx, err := h.walletService.DepositStatusFor(user.ID, ref)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reference not found"})
 		return
 	}
-	var event paystackWebhookEvent
-	if err := json.Unmarshal(body, &event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
-		return
+	if c.Query("verify") == "true" && tx.Type == models.TransactionTypeDeposit &&
+		(tx.Status == models.TransactionPending || tx.Status == models.TransactionExpired) {
+		tx, err = h.walletService.RefreshDeposit(c.Request.Context(), ref)
+		if err != nil {
+			respondError(c, err, http.StatusBadGateway)
+			return
+		}
 	}
-	if event.Data.Reference == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing reference"})
+	c.JSON(http.StatusOK, gin.H{
+		"reference": tx.Reference,
+		"status":    tx.Status,
+		"amount":    tx.Amount,
+		"currency":  tx.Currency,
+		"provider":  tx.Provider,
+	})
+}
+
+// Balance returns the caller's wallet balance; ?currency= selects a non-default wallet.
+func (h *WalletHandler) Balance(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
-	if err := h.walletService.ApplyDepositWebhook(event.Data.Reference, event.Data.Status, body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	wallet, err := h.walletService.Balance(user.ID, c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": true})
+	c.JSON(http.StatusOK, walletResponse(wallet))
 }
 
-type paystackWebhookEvent struct {
-	Event string `json:"event"`
-	Data  struct {
-		Status    string `json:"status"`
-		Reference string `json:"reference"`
-	} `json:"data"`
+type openWalletRequest struct {
+	Currency string `json:"currency" binding:"required"`
 }
 
-// DepositStatus returns the status of a deposit reference without crediting wallets.
-func (h *WalletHandler) DepositStatus(c *gin.Context) {
-	ref := c.Param("reference")
-	tx, err := h.walletService.DepositStatus(ref)
+// OpenWallet creates a wallet in another currency for a JWT-authenticated user.
+func (h *WalletHandler) OpenWallet(c *gin.Context) {
+	if middleware.GetAPIKey(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot open wallets"})
+		return
+	}
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user authentication required"})
+		return
+	}
+	var req openWalletRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	wallet, err := h.walletService.OpenWallet(user, req.Currency)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "reference not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"reference": tx.Reference,
-		"status":    tx.Status,
-		"amount":    tx.Amount,
-	})
+	c.JSON(http.StatusCreated, walletResponse(wallet))
 }
 
-// Balance returns the caller's wallet balance.
-func (h *WalletHandler) Balance(c *gin.Context) {
+// Wallets lists the caller's wallets, one per currency.
+func (h *WalletHandler) Wallets(c *gin.Context) {
 	user := middleware.GetUser(c)
 	if user == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
-	bal, err := h.walletService.Balance(user.ID)
+	wallets, err := h.walletService.Wallets(user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"balance": bal, "wallet_number": user.Wallet.Number})
+	resp := make([]gin.H, 0, len(wallets))
+	for i := range wallets {
+		resp = append(resp, walletResponse(&wallets[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func walletResponse(wallet *models.Wallet) gin.H {
+	resp := gin.H{
+		"currency":          wallet.Currency,
+		"balance":           wallet.Balance,
+		"available_balance": wallet.Available(),
+		"held_balance":      wallet.HeldBalance,
+		"wallet_number":     wallet.Number,
+	}
+	if wallet.VirtualAccountNumber != "" {
+		resp["virtual_account"] = virtualAccountResponse(wallet)
+	}
+	return resp
 }
 
 type transferRequest struct {
-	WalletNumber string `json:"wallet_number" binding:"required"`
-	Amount       int64  `json:"amount" binding:"required"`
+	WalletNumber    string `json:"wallet_number" binding:"required"`
+	Amount          int64  `json:"amount" binding:"required"`
+	Narration       string `json:"narration"`
+	ClientReference string `json:"client_reference"`
+	Currency        string `json:"currency"`
 }
 
 // Transfer moves funds to another wallet.
@@ -129,34 +184,134 @@ func (h *WalletHandler) Transfer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
 		return
 	}
-	if err := h.walletService.Transfer(user, req.WalletNumber, req.Amount); err != nil {
+	transferID, err := h.walletService.Transfer(user, req.WalletNumber, req.Amount, services.TransferOptions{
+		Narration:       req.Narration,
+		ClientReference: req.ClientReference,
+		Currency:        req.Currency,
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Transfer completed"})
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Transfer completed", "transfer_id": transferID})
+}
+
+// TransferDetail shows both legs of a transfer the caller sent or received.
+func (h *WalletHandler) TransferDetail(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	legs, err := h.walletService.TransferLegs(user.ID, c.Param("id"))
+	if err != nil || len(legs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transfer not found"})
+		return
+	}
+	first := legs[0]
+	resp := gin.H{
+		"transfer_id":      first.TransferID,
+		"amount":           first.Amount,
+		"currency":         first.Currency,
+		"narration":        first.Narration,
+		"client_reference": first.ClientReference,
+		"created_at":       first.CreatedAt,
+	}
+	legList := make([]gin.H, 0, len(legs))
+	for _, leg := range legs {
+		if leg.Direction == models.EntryDebit {
+			resp["to_wallet"] = leg.CounterpartyWallet
+		} else {
+			resp["from_wallet"] = leg.CounterpartyWallet
+		}
+		legList = append(legList, gin.H{
+			"reference":  leg.Reference,
+			"direction":  leg.Direction,
+			"amount":     leg.Amount,
+			"status":     leg.Status,
+			"created_at": leg.CreatedAt,
+		})
+	}
+	resp["legs"] = legList
+	c.JSON(http.StatusOK, resp)
 }
 
-// Transactions lists wallet activity.
+// Transactions lists wallet activity a page at a time with optional filters.
 func (h *WalletHandler) Transactions(c *gin.Context) {
 	user := middleware.GetUser(c)
 	if user == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
-	txs, err := h.walletService.Transactions(user.ID)
+	filter, err := parseTransactionFilter(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := h.walletService.Transactions(user.ID, filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	resp := make([]gin.H, 0, len(txs))
-	for _, t := range txs {
+	resp := make([]gin.H, 0, len(page.Items))
+	for _, t := range page.Items {
 		resp = append(resp, gin.H{
-			"type":       t.Type,
-			"amount":     t.Amount,
-			"status":     t.Status,
-			"reference":  t.Reference,
-			"created_at": t.CreatedAt,
+			"type":                t.Type,
+			"amount":              t.Amount,
+			"currency":            t.Currency,
+			"status":              t.Status,
+			"reference":           t.Reference,
+			"direction":           t.Direction,
+			"counterparty_wallet": t.CounterpartyWallet,
+			"transfer_id":         t.TransferID,
+			"narration":           t.Narration,
+			"created_at":          t.CreatedAt,
 		})
 	}
-	c.JSON(http.StatusOK, resp)
+	var next interface{}
+	if page.NextCursor != "" {
+		next = page.NextCursor
+	}
+	c.JSON(http.StatusOK, gin.H{"data": resp, "next_cursor": next})
+}
+
+func parseTransactionFilter(c *gin.Context) (services.TransactionFilter, error) {
+	filter := services.TransactionFilter{
+		Type:            models.TransactionType(c.Query("type")),
+		Status:          models.TransactionStatus(c.Query("status")),
+		Counterparty:    c.Query("counterparty"),
+		ReferencePrefix: c.Query("reference_prefix"),
+		Currency:        c.Query("currency"),
+		Cursor:          c.Query("cursor"),
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(name); v != "" {
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+			}
+			*dst = &ts
+		}
+	}
+	for name, dst := range map[string]**int64{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		if v := c.Query(name); v != "" {
+			amount, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an integer amount in kobo", name)
+			}
+			*dst = &amount
+		}
+	}
+	return filter, nil
 }
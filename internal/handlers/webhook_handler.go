@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/CyberwizD/Wallet-Service/internal/metrics"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBodyBytes bounds a webhook delivery; provider events are a few kilobytes.
+const maxWebhookBodyBytes = 1 << 20
+
+// WebhookHandler receives provider webhooks into the inbox.
+type WebhookHandler struct {
+	inbox *services.WebhookService
+}
+
+// NewWebhookHandler constructs a WebhookHandler.
+func NewWebhookHandler(inbox *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{inbox: inbox}
+}
+
+// Receive returns the webhook endpoint for gateway. It stores each event and
+// acknowledges it; a background worker applies it. A 500 is returned only when the
+// event could not be stored, so the provider retries.
+func (h *WebhookHandler) Receive(gateway services.PaymentGateway) gin.HandlerFunc {
+	provider := gateway.Name()
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			metrics.WebhooksRejected.Add(provider+":too_large", 1)
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "body too large"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read body"})
+			return
+		}
+		metrics.WebhooksReceived.Add(provider, 1)
+		valid := gateway.VerifyWebhook(c.Request.Header, body)
+		event, duplicate, err := h.inbox.Record(provider, c.Request.Header, body, valid)
+		if err != nil {
+			log.Printf("%s webhook not stored: %v", provider, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot store event"})
+			return
+		}
+		if !valid {
+			metrics.WebhooksRejected.Add(provider+":invalid_signature", 1)
+			log.Printf("webhook rejected: provider=%s reason=invalid_signature ip=%s event=%s", provider, c.ClientIP(), event.ID)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": true, "event_id": event.ID, "duplicate": duplicate})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type addRecipientRequest struct {
+	BankCode      string `json:"bank_code" binding:"required"`
+	AccountNumber string `json:"account_number" binding:"required"`
+	Currency      string `json:"currency"`
+}
+
+// AddRecipient saves a bank account as a Paystack transfer recipient.
+func (h *WalletHandler) AddRecipient(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req addRecipientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	recipient, err := h.walletService.AddRecipient(c.Request.Context(), user, req.BankCode, req.AccountNumber, req.Currency)
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+	c.JSON(http.StatusCreated, recipientResponse(*recipient))
+}
+
+// Recipients lists the caller's saved bank accounts.
+func (h *WalletHandler) Recipients(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	recipients, err := h.walletService.Recipients(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := make([]gin.H, 0, len(recipients))
+	for _, r := range recipients {
+		resp = append(resp, recipientResponse(r))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type withdrawRequest struct {
+	RecipientID string `json:"recipient_id" binding:"required"`
+	Amount      int64  `json:"amount" binding:"required"`
+	Reason      string `json:"reason"`
+}
+
+// Withdraw pays out from the caller's wallet to a saved bank account.
+func (h *WalletHandler) Withdraw(c *gin.Context) {
+	user := middleware.GetUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req withdrawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	record, err := h.walletService.InitiateWithdrawal(c.Request.Context(), user, req.RecipientID, req.Amount, req.Reason)
+	if errors.Is(err, services.ErrTransferOTPRequired) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"reference": record.Reference,
+		"status":    record.Status,
+		"amount":    record.Amount,
+		"currency":  record.Currency,
+	})
+}
+
+func recipientResponse(r models.BankRecipient) gin.H {
+	return gin.H{
+		"id":             r.ID,
+		"bank_code":      r.BankCode,
+		"account_number": r.AccountNumber,
+		"account_name":   r.AccountName,
+		"currency":       r.Currency,
+		"recipient_code": r.RecipientCode,
+		"created_at":     r.CreatedAt,
+	}
+}
@@ -0,0 +1,13 @@
+// Package metrics holds process-wide counters published through expvar.
+package metrics
+
+import "expvar"
+
+var (
+	// WebhooksReceived counts inbound webhook deliveries by provider.
+	WebhooksReceived = expvar.NewMap("webhooks_received")
+	// WebhooksRejected counts refused webhook deliveries by "provider:reason".
+	WebhooksRejected = expvar.NewMap("webhooks_rejected")
+	// GatewayErrors counts failed payment provider calls by "provider:kind".
+	GatewayErrors = expvar.NewMap("gateway_errors")
+)
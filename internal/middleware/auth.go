@@ -17,11 +17,13 @@ import (
 type contextKey string
 
 const (
-	contextUserKey   contextKey = "currentUser"
-	contextAPIKeyKey contextKey = "currentAPIKey"
+	contextUserKey    contextKey = "currentUser"
+	contextAPIKeyKey  contextKey = "currentAPIKey"
+	contextSessionKey contextKey = "currentSession"
 )
 
 // AuthMiddleware populates the request context with either a JWT user or an API key principal.
+// A JWT is only accepted while the session named in its sid claim is active.
 func AuthMiddleware(db *gorm.DB, jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if tryJWT(c, db, jwtSecret) {
@@ -37,14 +39,15 @@ func AuthMiddleware(db *gorm.DB, jwtSecret string) gin.HandlerFunc {
 }
 
 // RequirePermission asserts the current principal has the given permission.
+// API keys must carry it; JWT users hold every permission.
 func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if u := GetUser(c); u != nil {
-			c.Next()
-			return
-		}
 		apiKey := GetAPIKey(c)
 		if apiKey == nil {
+			if GetUser(c) != nil {
+				c.Next()
+				return
+			}
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
 			return
 		}
@@ -70,6 +73,11 @@ func GetUser(c *gin.Context) *models.User {
 	return nil
 }
 
+// GetSessionID returns the session of a JWT-authenticated request, or "".
+func GetSessionID(c *gin.Context) string {
+	return c.GetString(string(contextSessionKey))
+}
+
 // GetAPIKey returns the API key principal if present.
 func GetAPIKey(c *gin.Context) *models.APIKey {
 	if val, exists := c.Get(string(contextAPIKeyKey)); exists {
@@ -90,14 +98,19 @@ func tryJWT(c *gin.Context, db *gorm.DB, jwtSecret string) bool {
 		return false
 	}
 	claims, err := auth.ParseToken(parts[1], jwtSecret)
-	if err != nil {
+	if err != nil || claims.SessionID == "" {
+		return false
+	}
+	var session models.Session
+	if err := db.First(&session, "id = ? AND user_id = ?", claims.SessionID, claims.UserID).Error; err != nil || !session.Active(time.Now()) {
 		return false
 	}
 	var user models.User
-	if err := db.Preload("Wallet").First(&user, "id = ?", claims.UserID).Error; err != nil {
+	if err := db.Preload("Wallet", "currency = ?", models.DefaultCurrency).First(&user, "id = ?", claims.UserID).Error; err != nil {
 		return false
 	}
 	c.Set(string(contextUserKey), &user)
+	c.Set(string(contextSessionKey), session.ID)
 	return true
 }
 
@@ -115,7 +128,7 @@ func tryAPIKey(c *gin.Context, db *gorm.DB) bool {
 		return false
 	}
 	var user models.User
-	if err := db.Preload("Wallet").First(&user, "id = ?", record.UserID).Error; err != nil {
+	if err := db.Preload("Wallet", "currency = ?", models.DefaultCurrency).First(&user, "id = ?", record.UserID).Error; err != nil {
 		return false
 	}
 	now := time.Now()
@@ -125,3 +138,23 @@ func tryAPIKey(c *gin.Context, db *gorm.DB) bool {
 	c.Set(string(contextAPIKeyKey), &record)
 	return true
 }
+
+// RequireAdmin allows only JWT-authenticated users whose email is on the admin list.
+func RequireAdmin(adminEmails []string) gin.HandlerFunc {
+	admins := make(map[string]struct{}, len(adminEmails))
+	for _, e := range adminEmails {
+		admins[strings.ToLower(strings.TrimSpace(e))] = struct{}{}
+	}
+	return func(c *gin.Context) {
+		user := GetUser(c)
+		if user == nil || GetAPIKey(c) != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access requires a user token"})
+			return
+		}
+		if _, ok := admins[strings.ToLower(user.Email)]; !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		c.Next()
+	}
+}
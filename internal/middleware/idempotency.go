@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyHeader is the request header carrying the client's idempotency key.
+const IdempotencyHeader = "Idempotency-Key"
+
+// Idempotency replays the stored response when a request is retried with the same
+// Idempotency-Key, and rejects the key if it is reused for a different request.
+// Requests without the header pass through untouched. Must run after AuthMiddleware.
+func Idempotency(store *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+		principal := idempotencyPrincipal(c)
+		if principal == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "cannot read body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		endpoint := c.Request.Method + " " + c.FullPath()
+		sum := sha256.Sum256(body)
+		stored, err := store.Begin(principal, key, endpoint, hex.EncodeToString(sum[:]))
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, services.ErrIdempotencyKeyInFlight):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stored != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, "application/json; charset=utf-8", stored.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		defer func() {
+			// A panicking handler has no response to store; free the key for a retry.
+			if r := recover(); r != nil {
+				releaseIdempotencyKey(store, principal, key)
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		// Retryable outcomes are not stored, so the same key can be sent again.
+		if recorder.Status() >= http.StatusInternalServerError || recorder.Status() == http.StatusTooManyRequests {
+			releaseIdempotencyKey(store, principal, key)
+			return
+		}
+		if err := store.Complete(principal, key, recorder.Status(), recorder.body.Bytes()); err != nil {
+			log.Printf("idempotency key %s of %s: storing response: %v", key, principal, err)
+		}
+	}
+}
+
+func releaseIdempotencyKey(store *services.IdempotencyService, principal, key string) {
+	if err := store.Release(principal, key); err != nil {
+		log.Printf("idempotency key %s of %s: releasing: %v", key, principal, err)
+	}
+}
+
+// idempotencyPrincipal scopes keys to the calling API key, or the user for JWT callers.
+func idempotencyPrincipal(c *gin.Context) string {
+	if key := GetAPIKey(c); key != nil {
+		return "apikey:" + key.ID
+	}
+	if user := GetUser(c); user != nil {
+		return "user:" + user.ID
+	}
+	return ""
+}
+
+// responseRecorder copies the response body while it is written to the client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
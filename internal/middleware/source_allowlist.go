@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/CyberwizD/Wallet-Service/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookSourceAllowlist refuses webhook deliveries whose client IP is not in
+// entries (IPs or CIDRs). An empty list allows every source. Refusals are logged
+// and counted under metrics.WebhooksRejected.
+func WebhookSourceAllowlist(provider string, entries []string) gin.HandlerFunc {
+	nets := parseAllowlist(entries)
+	return func(c *gin.Context) {
+		if len(nets) == 0 {
+			c.Next()
+			return
+		}
+		ip := net.ParseIP(c.ClientIP())
+		for _, n := range nets {
+			if ip != nil && n.Contains(ip) {
+				c.Next()
+				return
+			}
+		}
+		metrics.WebhooksRejected.Add(provider+":source_not_allowed", 1)
+		log.Printf("webhook rejected: provider=%s reason=source_not_allowed ip=%s", provider, c.ClientIP())
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "source not allowed"})
+	}
+}
+
+// parseAllowlist turns IPs and CIDRs into networks; a bare IP matches only itself.
+// Entries are validated when config is loaded, so unparsable ones are skipped.
+func parseAllowlist(entries []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, n)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			continue
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// BankRecipient is a user's bank account registered with Paystack as a transfer recipient.
+type BankRecipient struct {
+	ID            string `gorm:"type:uuid;primaryKey"`
+	UserID        string `gorm:"type:uuid;index"`
+	Currency      string `gorm:"size:3;not null"`
+	BankCode      string `gorm:"not null"`
+	AccountNumber string `gorm:"not null"`
+	AccountName   string
+	RecipientCode string `gorm:"uniqueIndex"` // Paystack RCP_ code
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,47 @@
+package models
+
+import "strings"
+
+// DefaultCurrency is the currency of the wallet every user is created with.
+const DefaultCurrency = "NGN"
+
+// SupportedCurrencies lists the ISO 4217 codes Paystack can collect in.
+var SupportedCurrencies = []string{"NGN", "GHS", "KES", "USD", "ZAR"}
+
+// NormalizeCurrency upper-cases a currency code, returns DefaultCurrency for an
+// empty code, and reports whether the result is supported.
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency, true
+	}
+	for _, c := range SupportedCurrencies {
+		if c == code {
+			return code, true
+		}
+	}
+	return code, false
+}
+
+// ClearingAccountCode is the Paystack clearing ledger account for a currency.
+// The default currency keeps the original SystemAccountPaystackClearing code.
+func ClearingAccountCode(currency string) string {
+	if currency == "" || currency == DefaultCurrency {
+		return SystemAccountPaystackClearing
+	}
+	return SystemAccountPaystackClearing + ":" + strings.ToLower(currency)
+}
+
+// GatewayClearingAccountCode is the clearing ledger account for funds a payment
+// provider collected in a currency. Paystack, and deposits recorded before providers
+// were tracked, use ClearingAccountCode.
+func GatewayClearingAccountCode(provider, currency string) string {
+	if provider == "" || provider == "paystack" {
+		return ClearingAccountCode(currency)
+	}
+	code := "system:" + provider + "_clearing"
+	if currency == "" || currency == DefaultCurrency {
+		return code
+	}
+	return code + ":" + strings.ToLower(currency)
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// HoldStatus captures the lifecycle of a fund hold.
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldReleased HoldStatus = "released"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold reserves part of a wallet's balance until it is captured, released or expires.
+type Hold struct {
+	ID             string     `gorm:"type:uuid;primaryKey"`
+	Reference      string     `gorm:"uniqueIndex"`
+	WalletID       string     `gorm:"index"`
+	Currency       string     `gorm:"size:3;not null;default:NGN"`
+	Amount         int64      `gorm:"not null"`
+	CapturedAmount int64      `gorm:"not null;default:0"`
+	Status         HoldStatus `gorm:"index"`
+	Description    string
+	TransferID     string    // transfer created on capture
+	WithdrawalID   string    `gorm:"index"` // pending withdrawal or refund reference; such holds never expire and are settled by Paystack
+	ExpiresAt      time.Time `gorm:"index"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
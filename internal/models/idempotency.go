@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the outcome of a request made with an Idempotency-Key header
+// so retries can be answered with the original response.
+type IdempotencyKey struct {
+	ID           string `gorm:"type:uuid;primaryKey"`
+	Principal    string `gorm:"uniqueIndex:idx_idempotency_principal_key;size:128"`
+	Key          string `gorm:"uniqueIndex:idx_idempotency_principal_key;size:255"`
+	Endpoint     string
+	Fingerprint  string `gorm:"size:64"`
+	StatusCode   int    // zero while the original request is still running
+	ResponseBody []byte
+	CreatedAt    time.Time `gorm:"index"`
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// EntryDirection marks which side of the ledger a line posts to.
+type EntryDirection string
+
+const (
+	EntryDebit  EntryDirection = "debit"
+	EntryCredit EntryDirection = "credit"
+)
+
+// LedgerAccountKind separates user wallet accounts from platform-owned accounts.
+type LedgerAccountKind string
+
+const (
+	LedgerAccountWallet LedgerAccountKind = "wallet"
+	LedgerAccountSystem LedgerAccountKind = "system"
+)
+
+// System ledger account codes.
+const (
+	SystemAccountPaystackClearing = "system:paystack_clearing"
+	// SystemAccountFees collects platform fees; no operation charges one yet.
+	SystemAccountFees = "system:fees"
+	// SystemAccountOpeningBalances funds wallet balances that predate the ledger.
+	SystemAccountOpeningBalances = "system:opening_balances"
+)
+
+// LedgerAccount is a double-entry account. Balance is credits minus debits and
+// is kept in step with the account's lines.
+type LedgerAccount struct {
+	ID        string            `gorm:"type:uuid;primaryKey"`
+	Code      string            `gorm:"uniqueIndex;size:64"`
+	Kind      LedgerAccountKind `gorm:"index"`
+	WalletID  *string           `gorm:"type:uuid;uniqueIndex"`
+	Balance   int64             `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// JournalEntry groups balanced ledger lines for a single business event.
+type JournalEntry struct {
+	ID          string `gorm:"type:uuid;primaryKey"`
+	Reference   string `gorm:"uniqueIndex"`
+	Description string
+	Lines       []LedgerLine
+	CreatedAt   time.Time
+}
+
+// LedgerLine posts an amount to one side of an account.
+type LedgerLine struct {
+	ID             string         `gorm:"type:uuid;primaryKey"`
+	JournalEntryID string         `gorm:"type:uuid;index"`
+	AccountID      string         `gorm:"type:uuid;index"`
+	Direction      EntryDirection `gorm:"size:8"`
+	Amount         int64          `gorm:"not null"`
+	CreatedAt      time.Time
+}
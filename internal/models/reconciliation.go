@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ReconciliationRun records one pass of the wallet balance reconciliation job.
+type ReconciliationRun struct {
+	ID             string `gorm:"type:uuid;primaryKey"`
+	Trigger        string `gorm:"index"` // schedule or manual
+	WalletsChecked int
+	MismatchCount  int
+	Report         []byte    `gorm:"type:jsonb"`
+	StartedAt      time.Time `gorm:"index"`
+	FinishedAt     time.Time
+}
+
+// SettlementRun records one comparison of Paystack's charges against our deposits.
+type SettlementRun struct {
+	ID               string `gorm:"type:uuid;primaryKey"`
+	Source           string `gorm:"size:16;index"` // api or csv
+	Trigger          string `gorm:"index"`         // schedule or manual
+	PeriodStart      time.Time
+	PeriodEnd        time.Time
+	ChargesChecked   int
+	DiscrepancyCount int
+	FixedCount       int
+	Report           []byte    `gorm:"type:jsonb"`
+	StartedAt        time.Time `gorm:"index"`
+	FinishedAt       time.Time
+}
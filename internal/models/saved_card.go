@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// SavedCard is a reusable Paystack card authorization captured from a successful
+// card deposit. AuthorizationCode charges the card again without the customer and
+// is never returned by the API.
+type SavedCard struct {
+	ID                string `gorm:"type:uuid;primaryKey"`
+	UserID            string `gorm:"type:uuid;uniqueIndex:idx_saved_cards_user_signature,priority:1"`
+	Signature         string `gorm:"size:64;uniqueIndex:idx_saved_cards_user_signature,priority:2"` // Paystack's card fingerprint
+	AuthorizationCode string `gorm:"size:64;not null"`
+	Email             string // customer the authorization is bound to
+	Brand             string
+	Last4             string `gorm:"size:4"`
+	Bank              string
+	ExpMonth          string `gorm:"size:2"`
+	ExpYear           string `gorm:"size:4"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// AutoTopUpStatus captures whether an auto top-up rule still charges.
+type AutoTopUpStatus string
+
+const (
+	AutoTopUpActive AutoTopUpStatus = "active"
+	// AutoTopUpPaused rules stopped after repeated failed charges; saving the rule again resumes it.
+	AutoTopUpPaused AutoTopUpStatus = "paused"
+)
+
+// AutoTopUp charges a saved card for Amount whenever the wallet's available
+// balance falls below Threshold. A wallet has at most one rule.
+type AutoTopUp struct {
+	ID            string          `gorm:"type:uuid;primaryKey"`
+	UserID        string          `gorm:"type:uuid;index"`
+	WalletID      string          `gorm:"uniqueIndex"`
+	CardID        string          `gorm:"type:uuid;index"`
+	Threshold     int64           `gorm:"not null"`
+	Amount        int64           `gorm:"not null"`
+	Status        AutoTopUpStatus `gorm:"index"`
+	LastReference string          // deposit of the latest charge, until its outcome is counted
+	LastAttemptAt *time.Time
+	Failures      int // consecutive failed charges
+	LastError     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
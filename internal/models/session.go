@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Session is a signed-in device. Access tokens name it in their sid claim and
+// stop working once it is revoked; its refresh token is rotated on every use.
+type Session struct {
+	ID     string `gorm:"type:uuid;primaryKey"`
+	UserID string `gorm:"type:uuid;index"`
+	User   User   `gorm:"constraint:OnDelete:CASCADE;"`
+	// RefreshTokenHash is the SHA-256 of the current refresh token; PreviousTokenHash
+	// is the one it replaced, kept to detect a rotated token being used again.
+	RefreshTokenHash  string    `gorm:"uniqueIndex;size:64"`
+	PreviousTokenHash string    `gorm:"index;size:64"`
+	UserAgent         string    `gorm:"size:255"`
+	IP                string    `gorm:"size:64"`
+	ExpiresAt         time.Time `gorm:"index"` // when the refresh token lapses unless used
+	LastUsedAt        time.Time
+	RevokedAt         *time.Time `gorm:"index"`
+	RevokeReason      string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// Active reports whether the session can still authenticate requests at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
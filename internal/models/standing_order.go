@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// ScheduleFrequency controls how often a standing order repeats.
+type ScheduleFrequency string
+
+const (
+	FrequencyOnce    ScheduleFrequency = "once"
+	FrequencyDaily   ScheduleFrequency = "daily"
+	FrequencyWeekly  ScheduleFrequency = "weekly"
+	FrequencyMonthly ScheduleFrequency = "monthly"
+)
+
+// StandingOrderStatus captures the lifecycle of a scheduled transfer.
+type StandingOrderStatus string
+
+const (
+	StandingOrderActive    StandingOrderStatus = "active"
+	StandingOrderCompleted StandingOrderStatus = "completed"
+	StandingOrderCancelled StandingOrderStatus = "cancelled"
+	StandingOrderFailed    StandingOrderStatus = "failed" // one-off transfer that could not run
+)
+
+// StandingOrder is a one-off future transfer or a recurring transfer schedule.
+type StandingOrder struct {
+	ID               string `gorm:"type:uuid;primaryKey"`
+	UserID           string `gorm:"type:uuid;index"`
+	WalletID         string `gorm:"index"` // source wallet; runs debit it whatever the user's default is
+	Currency         string `gorm:"size:3;not null;default:NGN"`
+	DestWalletNumber string
+	Amount           int64 `gorm:"not null"`
+	Narration        string
+	Frequency        ScheduleFrequency
+	StartAt          time.Time
+	NextRunAt        time.Time `gorm:"index"`
+	EndAt            *time.Time
+	MaxRuns          int // zero means no limit
+	RunCount         int
+	SkippedRuns      int                 // occurrences missed while the executor was down and never paid
+	Status           StandingOrderStatus `gorm:"index"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// StandingOrderRun records one execution attempt of a standing order.
+type StandingOrderRun struct {
+	ID              string `gorm:"type:uuid;primaryKey"`
+	StandingOrderID string `gorm:"type:uuid;index"`
+	ScheduledFor    time.Time
+	Status          TransactionStatus
+	TransferID      string
+	Error           string
+	CreatedAt       time.Time
+}
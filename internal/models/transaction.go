@@ -10,6 +10,11 @@ type TransactionType string
 const (
 	TransactionTypeDeposit  TransactionType = "deposit"
 	TransactionTypeTransfer TransactionType = "transfer"
+	TransactionTypeReversal TransactionType = "reversal"
+	// TransactionTypeWithdrawal is a payout from a wallet to a bank account.
+	TransactionTypeWithdrawal TransactionType = "withdrawal"
+	// TransactionTypeRefund returns part or all of a deposit to the card or account that paid it.
+	TransactionTypeRefund TransactionType = "refund"
 )
 
 // TransactionStatus captures lifecycle states for transactions.
@@ -19,6 +24,14 @@ const (
 	TransactionPending TransactionStatus = "pending"
 	TransactionSuccess TransactionStatus = "success"
 	TransactionFailed  TransactionStatus = "failed"
+	// TransactionReversed marks a completed transfer leg undone by a reversal.
+	TransactionReversed TransactionStatus = "reversed"
+	// TransactionFlagged marks a deposit Paystack confirmed with a different amount,
+	// currency or customer than we recorded; it is not credited and needs review.
+	TransactionFlagged TransactionStatus = "flagged"
+	// TransactionExpired marks a deposit never paid within the deposit TTL. A payment
+	// confirmed after expiry still credits it.
+	TransactionExpired TransactionStatus = "expired"
 )
 
 // Transaction represents any balance-impacting operation.
@@ -28,10 +41,20 @@ type Transaction struct {
 	Type               TransactionType   `gorm:"index"`
 	Status             TransactionStatus `gorm:"index"`
 	Amount             int64
-	WalletID           string `gorm:"index"`
-	CounterpartyWallet string // recipient for transfers
+	Currency           string         `gorm:"size:3;not null;default:NGN"`
+	WalletID           string         `gorm:"index;index:idx_transactions_wallet_created,priority:1"`
+	CounterpartyWallet string         // recipient for transfers
+	Direction          EntryDirection `gorm:"size:8"`        // debit or credit against WalletID
+	Provider           string         `gorm:"size:32;index"` // payment gateway that handled a deposit
 	Description        string
-	RawPayload         []byte `gorm:"type:jsonb"`
-	CreatedAt          time.Time
+	TransferID         string `gorm:"index"` // shared by both legs of a transfer
+	Narration          string
+	ClientReference    string    `gorm:"index"`
+	OriginalReference  string    `gorm:"index"`         // leg this row compensates, for reversals and refunds
+	ProviderReference  string    `gorm:"size:64;index"` // provider's own id, e.g. a Paystack refund id
+	CallbackURL        string    `gorm:"size:512"`      // where the customer returns after a deposit checkout
+	RawPayload         []byte    `gorm:"type:jsonb"`
+	JournalEntryID     string    `gorm:"index"` // ledger entry that moved the balance
+	CreatedAt          time.Time `gorm:"index:idx_transactions_wallet_created,priority:2"`
 	UpdatedAt          time.Time
 }
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// BatchMode selects how a bulk transfer treats individual failures.
+type BatchMode string
+
+const (
+	// BatchAllOrNothing rolls back every item if any item fails.
+	BatchAllOrNothing BatchMode = "all_or_nothing"
+	// BatchBestEffort commits each item independently.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// BatchStatus captures the outcome of a bulk transfer.
+type BatchStatus string
+
+const (
+	BatchProcessing         BatchStatus = "processing"
+	BatchCompleted          BatchStatus = "completed"
+	BatchPartiallyCompleted BatchStatus = "partially_completed"
+	BatchFailed             BatchStatus = "failed"
+)
+
+// BatchItemStatus captures the outcome of one bulk transfer item.
+type BatchItemStatus string
+
+const (
+	BatchItemPending    BatchItemStatus = "pending"
+	BatchItemSuccess    BatchItemStatus = "success"
+	BatchItemFailed     BatchItemStatus = "failed"
+	BatchItemRolledBack BatchItemStatus = "rolled_back" // succeeded, then undone by an all-or-nothing failure
+)
+
+// TransferBatch is one bulk transfer request from a wallet to many recipients.
+type TransferBatch struct {
+	ID           string `gorm:"type:uuid;primaryKey"`
+	UserID       string `gorm:"type:uuid;index"`
+	WalletID     string `gorm:"index"`
+	Currency     string `gorm:"size:3;not null;default:NGN"`
+	Mode         BatchMode
+	Status       BatchStatus `gorm:"index"`
+	ItemCount    int
+	SuccessCount int
+	FailedCount  int
+	TotalAmount  int64
+	Items        []TransferBatchItem `gorm:"foreignKey:BatchID"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// TransferBatchItem is a single recipient line of a bulk transfer.
+type TransferBatchItem struct {
+	ID           string `gorm:"type:uuid;primaryKey"`
+	BatchID      string `gorm:"type:uuid;index"`
+	Position     int
+	WalletNumber string
+	Amount       int64
+	Narration    string
+	Status       BatchItemStatus
+	TransferID   string
+	Error        string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -11,6 +11,6 @@ type User struct {
 	Name      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Wallet    Wallet
+	Wallet    Wallet // the DefaultCurrency wallet; other currencies are looked up separately
 	APIKeys   []APIKey
 }
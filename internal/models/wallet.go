@@ -2,12 +2,26 @@ package models
 
 import "time"
 
-// Wallet stores the balance for a user in kobo (smallest currency unit).
+// Wallet stores a user's balance in one currency, in its smallest unit (kobo,
+// pesewas, cents). A user has at most one wallet per currency.
+// Balance is the ledger balance; HeldBalance is the part reserved by active holds.
 type Wallet struct {
-	ID        string `gorm:"type:uuid;primaryKey"`
-	UserID    string `gorm:"type:uuid;uniqueIndex"`
-	Number    string `gorm:"uniqueIndex;size:32"`
-	Balance   int64  `gorm:"not null"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID          string `gorm:"type:uuid;primaryKey"`
+	UserID      string `gorm:"type:uuid;uniqueIndex:idx_wallets_user_currency,priority:1"`
+	Currency    string `gorm:"size:3;not null;default:NGN;uniqueIndex:idx_wallets_user_currency,priority:2"`
+	Number      string `gorm:"uniqueIndex;size:32"`
+	Balance     int64  `gorm:"not null"`
+	HeldBalance int64  `gorm:"not null;default:0"`
+	// Paystack dedicated virtual account: bank transfers into it credit this wallet.
+	VirtualAccountNumber string `gorm:"size:32;index"`
+	VirtualAccountName   string
+	VirtualAccountBank   string
+	PaystackCustomerCode string `gorm:"size:64"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+// Available is the balance that can be spent or held.
+func (w Wallet) Available() int64 {
+	return w.Balance - w.HeldBalance
 }
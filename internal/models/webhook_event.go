@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// WebhookEventStatus tracks an inbound webhook through the inbox.
+type WebhookEventStatus string
+
+const (
+	// WebhookPending events are waiting for their first or next processing attempt.
+	WebhookPending   WebhookEventStatus = "pending"
+	WebhookProcessed WebhookEventStatus = "processed"
+	// WebhookFailed events used up their retries; they can be replayed by an admin.
+	WebhookFailed WebhookEventStatus = "failed"
+	// WebhookRejected events had an invalid signature and are kept only for auditing.
+	WebhookRejected WebhookEventStatus = "rejected"
+)
+
+// WebhookEvent is an inbound provider webhook exactly as received, plus its processing outcome.
+type WebhookEvent struct {
+	ID             string             `gorm:"type:uuid;primaryKey"`
+	Provider       string             `gorm:"size:32;uniqueIndex:idx_webhook_events_provider_event,priority:1"`
+	EventID        string             `gorm:"uniqueIndex:idx_webhook_events_provider_event,priority:2"` // provider's dedup key
+	EventType      string             `gorm:"index"`
+	Reference      string             `gorm:"index"`
+	Headers        []byte             `gorm:"type:jsonb"`
+	Body           []byte             // raw bytes; may not be valid JSON when the signature is bad
+	SignatureValid bool               `gorm:"not null"`
+	Status         WebhookEventStatus `gorm:"index"`
+	Attempts       int                `gorm:"not null;default:0"`
+	LastError      string
+	NextAttemptAt  time.Time `gorm:"index"`
+	ProcessedAt    *time.Time
+	CreatedAt      time.Time `gorm:"index"`
+	UpdatedAt      time.Time
+}
@@ -0,0 +1,125 @@
+package paystackemu
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+var checkoutTemplate = template.Must(template.New("checkout").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Paystack emulator checkout</title>
+<style>body{font-family:sans-serif;max-width:28rem;margin:3rem auto}button{padding:.6rem 1.2rem;margin-right:.5rem}</style>
+</head>
+<body>
+  <h2>Paystack emulator</h2>
+  <p><strong>{{.Currency}} {{.Major}}</strong> from {{.Email}}</p>
+  <p>Reference: <code>{{.Reference}}</code></p>
+  {{if eq .Status "ongoing"}}
+  <form method="post" action="/checkout/{{.AccessCode}}/approve" style="display:inline"><button>Approve payment</button></form>
+  <form method="post" action="/checkout/{{.AccessCode}}/decline" style="display:inline"><button>Decline payment</button></form>
+  {{else}}
+  <p>Payment {{.Status}}.{{if .Error}} {{.Error}}{{end}}</p>
+  {{end}}
+</body>
+</html>`))
+
+type checkoutView struct {
+	Reference  string
+	AccessCode string
+	Currency   string
+	Major      string
+	Email      string
+	Status     string
+	Error      string
+	// Return is where the customer is sent once the checkout is decided, if anywhere.
+	Return string
+}
+
+func (e *Emulator) checkoutView(code string) (checkoutView, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	tx, ok := e.transactions[e.checkouts[code]]
+	if !ok {
+		return checkoutView{}, false
+	}
+	view := checkoutView{
+		Reference:  tx.Reference,
+		AccessCode: tx.AccessCode,
+		Currency:   tx.Currency,
+		Major:      formatMajor(tx.Amount),
+		Email:      tx.Email,
+		Status:     tx.Status,
+	}
+	// Like Paystack, a paid checkout returns to callback_url with the reference
+	// appended, and an abandoned one to the cancel_action metadata as given.
+	switch {
+	case tx.Status == "success" && tx.CallbackURL != "":
+		view.Return = withReference(tx.CallbackURL, tx.Reference)
+	case tx.Status == "failed":
+		view.Return = tx.Metadata["cancel_action"]
+	}
+	return view, true
+}
+
+func (e *Emulator) checkoutPage(w http.ResponseWriter, r *http.Request) {
+	view, ok := e.checkoutView(r.PathValue("code"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = checkoutTemplate.Execute(w, view)
+}
+
+// checkoutDecision approves or declines a checkout and shows the outcome. A failed
+// webhook delivery is reported on the page; the payment itself stays approved.
+func (e *Emulator) checkoutDecision(w http.ResponseWriter, r *http.Request) {
+	view, ok := e.checkoutView(r.PathValue("code"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var err error
+	switch r.PathValue("decision") {
+	case "approve":
+		err = e.Approve(view.Reference)
+	case "decline":
+		err = e.Decline(view.Reference)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	view, _ = e.checkoutView(view.AccessCode)
+	if view.Return != "" {
+		if err != nil {
+			log.Printf("paystack emulator: checkout %s: %v", view.Reference, err)
+		}
+		http.Redirect(w, r, view.Return, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		view.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = checkoutTemplate.Execute(w, view)
+}
+
+// withReference adds Paystack's trxref and reference parameters to a callback URL.
+func withReference(callbackURL, reference string) string {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return callbackURL
+	}
+	query := u.Query()
+	query.Set("trxref", reference)
+	query.Set("reference", reference)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func formatMajor(amount int64) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
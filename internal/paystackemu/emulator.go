@@ -0,0 +1,1016 @@
+// Package paystackemu is an in-memory stand-in for the parts of the Paystack API
+// this service uses, so deposits, refunds and withdrawals can be exercised offline. It
+// serves a fake checkout page to approve or decline payments, simulates bank
+// transfers into dedicated virtual accounts and settlements of collected charges,
+// and delivers webhooks signed the way Paystack signs them.
+package paystackemu
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+)
+
+// Transfer outcomes applied automatically after Config.TransferDelay. Refunds
+// follow the same outcome, success meaning processed.
+const (
+	TransferOutcomeSuccess = "success"
+	TransferOutcomeFailed  = "failed"
+	// TransferOutcomeManual leaves transfers pending until settled through the control endpoint.
+	TransferOutcomeManual = "manual"
+)
+
+// Config configures an Emulator.
+type Config struct {
+	// SecretKey is the bearer token API calls must present.
+	SecretKey string
+	// WebhookSecret signs webhooks; it defaults to SecretKey, as on Paystack.
+	WebhookSecret string
+	// WebhookURL receives webhooks; empty disables delivery.
+	WebhookURL string
+	// PublicURL is the emulator's externally reachable base URL, used in checkout links.
+	PublicURL string
+	// TransferOutcome settles new transfers and refunds; defaults to TransferOutcomeSuccess.
+	TransferOutcome string
+	TransferDelay   time.Duration
+}
+
+type transaction struct {
+	ID         int64
+	Reference  string
+	AccessCode string
+	Amount     int64
+	Currency   string
+	Email      string
+	Status     string
+	PaidAt     *time.Time
+	Channel    string
+	// ReceiverAccount is the virtual account a bank transfer was paid into.
+	ReceiverAccount string
+	// AuthorizationCode is the reusable card authorization of a successful card charge.
+	AuthorizationCode string
+	// SettlementID is the settlement that paid the charge out, once settled.
+	SettlementID int64
+	CreatedAt    time.Time
+	// CallbackURL and Metadata are as given to /transaction/initialize.
+	CallbackURL string
+	Metadata    map[string]string
+}
+
+type settlement struct {
+	ID          int64
+	Currency    string
+	TotalAmount int64
+	SettledAt   time.Time
+	References  []string
+}
+
+// authorization is the emulated card behind every approved checkout of one customer.
+type authorization struct {
+	Code      string
+	Signature string
+	Email     string
+	Active    bool
+}
+
+type dedicatedAccount struct {
+	AccountNumber string
+	AccountName   string
+	CustomerCode  string
+	Email         string
+}
+
+type recipient struct {
+	Code          string
+	Name          string
+	AccountNumber string
+	BankCode      string
+	Currency      string
+}
+
+type refund struct {
+	ID          int64
+	Transaction string // reference of the refunded charge
+	Amount      int64
+	Currency    string
+	Note        string
+	Status      string
+}
+
+type transfer struct {
+	ID        int64
+	Code      string
+	Reference string
+	Amount    int64
+	Currency  string
+	Recipient string
+	Reason    string
+	Status    string
+}
+
+// Emulator holds the emulated Paystack state. It is safe for concurrent use.
+type Emulator struct {
+	cfg    Config
+	client *http.Client
+
+	mu           sync.Mutex
+	nextID       int64
+	transactions map[string]*transaction      // by reference
+	checkouts    map[string]string            // access code -> reference
+	recipients   map[string]*recipient        // by recipient code
+	transfers    map[string]*transfer         // by reference
+	refunds      map[string]*refund           // by id
+	customers    map[string]string            // email -> customer code
+	accounts     map[string]*dedicatedAccount // by account number
+	cards        map[string]*authorization    // by authorization code
+	settlements  []*settlement                // oldest first
+}
+
+// New constructs an Emulator.
+func New(cfg Config) *Emulator {
+	if cfg.WebhookSecret == "" {
+		cfg.WebhookSecret = cfg.SecretKey
+	}
+	if cfg.TransferOutcome == "" {
+		cfg.TransferOutcome = TransferOutcomeSuccess
+	}
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+	return &Emulator{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		// Seeded from the clock so a restarted emulator never reuses the event ids
+		// the webhook inbox deduplicates on.
+		nextID:       time.Now().UnixMilli(),
+		transactions: map[string]*transaction{},
+		checkouts:    map[string]string{},
+		recipients:   map[string]*recipient{},
+		transfers:    map[string]*transfer{},
+		refunds:      map[string]*refund{},
+		customers:    map[string]string{},
+		accounts:     map[string]*dedicatedAccount{},
+		cards:        map[string]*authorization{},
+	}
+}
+
+// Handler serves the emulated API, the checkout pages and the transfer controls.
+func (e *Emulator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /transaction/initialize", e.authorized(e.initialize))
+	mux.HandleFunc("GET /transaction/verify/{reference}", e.authorized(e.verify))
+	mux.HandleFunc("GET /transaction", e.authorized(e.listTransactions))
+	mux.HandleFunc("GET /settlement", e.authorized(e.listSettlements))
+	mux.HandleFunc("GET /settlement/{id}/transactions", e.authorized(e.settlementTransactions))
+	mux.HandleFunc("GET /bank/resolve", e.authorized(e.resolveAccount))
+	mux.HandleFunc("POST /transferrecipient", e.authorized(e.createRecipient))
+	mux.HandleFunc("POST /transfer", e.authorized(e.createTransfer))
+	mux.HandleFunc("GET /transfer/verify/{reference}", e.authorized(e.verifyTransfer))
+	mux.HandleFunc("POST /refund", e.authorized(e.createRefund))
+	mux.HandleFunc("GET /refund", e.authorized(e.listRefunds))
+	mux.HandleFunc("POST /transaction/charge_authorization", e.authorized(e.chargeAuthorization))
+	mux.HandleFunc("POST /customer/deactivate_authorization", e.authorized(e.deactivateAuthorization))
+	mux.HandleFunc("POST /customer", e.authorized(e.createCustomer))
+	mux.HandleFunc("POST /dedicated_account", e.authorized(e.createDedicatedAccount))
+	mux.HandleFunc("GET /checkout/{code}", e.checkoutPage)
+	mux.HandleFunc("POST /checkout/{code}/{decision}", e.checkoutDecision)
+	mux.HandleFunc("POST /emulator/transfers/{reference}/{event}", e.transferControl)
+	mux.HandleFunc("POST /emulator/refunds/{id}/{event}", e.refundControl)
+	mux.HandleFunc("POST /emulator/settlements", e.settlementControl)
+	mux.HandleFunc("POST /emulator/virtual-accounts/{account}/credit", e.virtualAccountControl)
+	return mux
+}
+
+func (e *Emulator) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+e.cfg.SecretKey {
+			writeJSON(w, http.StatusUnauthorized, false, "Invalid key", nil)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (e *Emulator) initialize(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount      int64             `json:"amount"`
+		Currency    string            `json:"currency"`
+		Email       string            `json:"email"`
+		Reference   string            `json:"reference"`
+		CallbackURL string            `json:"callback_url"`
+		Channels    []string          `json:"channels"`
+		Metadata    map[string]string `json:"metadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 || req.Email == "" {
+		writeJSON(w, http.StatusBadRequest, false, "amount and email are required", nil)
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = "NGN"
+	}
+	if req.Reference == "" {
+		req.Reference = util.MustUUID()
+	}
+	e.mu.Lock()
+	if _, exists := e.transactions[req.Reference]; exists {
+		e.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, false, "Duplicate Transaction Reference", nil)
+		return
+	}
+	e.nextID++
+	tx := &transaction{
+		ID:          e.nextID,
+		Reference:   req.Reference,
+		AccessCode:  strings.ReplaceAll(util.MustUUID(), "-", "")[:15],
+		Amount:      req.Amount,
+		Currency:    strings.ToUpper(req.Currency),
+		Email:       req.Email,
+		Status:      "ongoing",
+		Channel:     "card",
+		CreatedAt:   time.Now(),
+		CallbackURL: req.CallbackURL,
+		Metadata:    req.Metadata,
+	}
+	// The customer pays with the first channel offered when card is not among them.
+	if len(req.Channels) > 0 && !slices.Contains(req.Channels, "card") {
+		tx.Channel = req.Channels[0]
+	}
+	e.transactions[tx.Reference] = tx
+	e.checkouts[tx.AccessCode] = tx.Reference
+	e.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, true, "Authorization URL created", map[string]interface{}{
+		"authorization_url": e.cfg.PublicURL + "/checkout/" + tx.AccessCode,
+		"access_code":       tx.AccessCode,
+		"reference":         tx.Reference,
+	})
+}
+
+func (e *Emulator) verify(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	tx, ok := e.transactions[r.PathValue("reference")]
+	var data map[string]interface{}
+	if ok {
+		data = e.chargeData(tx)
+	}
+	e.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, false, "Transaction reference not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, "Verification successful", data)
+}
+
+// listTransactions lists charges created between the from and to parameters, newest first.
+func (e *Emulator) listTransactions(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := listingPeriod(w, r)
+	if !ok {
+		return
+	}
+	e.mu.Lock()
+	var txs []*transaction
+	for _, tx := range e.transactions {
+		if !tx.CreatedAt.Before(from) && (to.IsZero() || !tx.CreatedAt.After(to)) {
+			txs = append(txs, tx)
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool { return txs[i].ID > txs[j].ID })
+	rows := make([]interface{}, 0, len(txs))
+	for _, tx := range txs {
+		rows = append(rows, e.chargeData(tx))
+	}
+	e.mu.Unlock()
+	writePage(w, r, rows)
+}
+
+// listSettlements lists settlements paid out between the from and to parameters.
+func (e *Emulator) listSettlements(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := listingPeriod(w, r)
+	if !ok {
+		return
+	}
+	e.mu.Lock()
+	rows := []interface{}{}
+	for _, st := range e.settlements {
+		if !st.SettledAt.Before(from) && (to.IsZero() || !st.SettledAt.After(to)) {
+			rows = append(rows, map[string]interface{}{
+				"id":              st.ID,
+				"status":          "success",
+				"currency":        st.Currency,
+				"total_amount":    st.TotalAmount,
+				"total_fees":      0,
+				"settlement_date": st.SettledAt,
+			})
+		}
+	}
+	e.mu.Unlock()
+	writePage(w, r, rows)
+}
+
+func (e *Emulator) settlementTransactions(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	var found *settlement
+	for _, st := range e.settlements {
+		if fmt.Sprint(st.ID) == r.PathValue("id") {
+			found = st
+		}
+	}
+	rows := []interface{}{}
+	if found != nil {
+		for _, ref := range found.References {
+			rows = append(rows, e.chargeData(e.transactions[ref]))
+		}
+	}
+	e.mu.Unlock()
+	if found == nil {
+		writeJSON(w, http.StatusNotFound, false, "Settlement not found", nil)
+		return
+	}
+	writePage(w, r, rows)
+}
+
+func (e *Emulator) settlementControl(w http.ResponseWriter, r *http.Request) {
+	id, err := e.Settle()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, false, err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, "Settlement created", map[string]interface{}{"id": id})
+}
+
+// Settle pays out every successful charge not yet settled as one NGN settlement
+// and returns its id.
+func (e *Emulator) Settle() (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	st := &settlement{ID: e.nextID, Currency: "NGN", SettledAt: time.Now()}
+	for _, tx := range e.transactions {
+		if tx.Status == "success" && tx.SettlementID == 0 && tx.Currency == st.Currency {
+			tx.SettlementID = st.ID
+			st.TotalAmount += tx.Amount
+			st.References = append(st.References, tx.Reference)
+		}
+	}
+	if len(st.References) == 0 {
+		return 0, fmt.Errorf("no unsettled charges")
+	}
+	sort.Strings(st.References)
+	e.settlements = append(e.settlements, st)
+	return st.ID, nil
+}
+
+// listingPeriod parses the optional from and to parameters of a listing.
+func listingPeriod(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	var bounds [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, false, "Invalid "+name+" date", nil)
+				return time.Time{}, time.Time{}, false
+			}
+			bounds[i] = t
+		}
+	}
+	return bounds[0], bounds[1], true
+}
+
+func (e *Emulator) resolveAccount(w http.ResponseWriter, r *http.Request) {
+	number := r.URL.Query().Get("account_number")
+	if len(number) != 10 || r.URL.Query().Get("bank_code") == "" {
+		writeJSON(w, http.StatusUnprocessableEntity, false, "Could not resolve account name. Check parameters or try again.", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, "Account number resolved", map[string]interface{}{
+		"account_number": number,
+		"account_name":   "EMULATED ACCOUNT " + number[6:],
+	})
+}
+
+func (e *Emulator) createRecipient(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type          string `json:"type"`
+		Name          string `json:"name"`
+		AccountNumber string `json:"account_number"`
+		BankCode      string `json:"bank_code"`
+		Currency      string `json:"currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AccountNumber == "" || req.BankCode == "" {
+		writeJSON(w, http.StatusBadRequest, false, "account_number and bank_code are required", nil)
+		return
+	}
+	rcp := &recipient{
+		Code:          "RCP_" + strings.ReplaceAll(util.MustUUID(), "-", "")[:12],
+		Name:          req.Name,
+		AccountNumber: req.AccountNumber,
+		BankCode:      req.BankCode,
+		Currency:      req.Currency,
+	}
+	e.mu.Lock()
+	e.recipients[rcp.Code] = rcp
+	e.mu.Unlock()
+	writeJSON(w, http.StatusCreated, true, "Transfer recipient created successfully", map[string]interface{}{
+		"recipient_code": rcp.Code,
+		"name":           rcp.Name,
+		"currency":       rcp.Currency,
+	})
+}
+
+// chargeAuthorization charges a card saved from an earlier checkout. Charges on an
+// active authorization for its own customer always succeed.
+func (e *Emulator) chargeAuthorization(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AuthorizationCode string `json:"authorization_code"`
+		Email             string `json:"email"`
+		Amount            int64  `json:"amount"`
+		Currency          string `json:"currency"`
+		Reference         string `json:"reference"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 || req.Email == "" {
+		writeJSON(w, http.StatusBadRequest, false, "authorization_code, amount and email are required", nil)
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = "NGN"
+	}
+	if req.Reference == "" {
+		req.Reference = util.MustUUID()
+	}
+	e.mu.Lock()
+	card, ok := e.cards[req.AuthorizationCode]
+	if !ok || !card.Active || card.Email != req.Email {
+		e.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, false, "Invalid authorization code", nil)
+		return
+	}
+	if _, exists := e.transactions[req.Reference]; exists {
+		e.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, false, "Duplicate Transaction Reference", nil)
+		return
+	}
+	e.nextID++
+	now := time.Now()
+	tx := &transaction{
+		ID:                e.nextID,
+		Reference:         req.Reference,
+		Amount:            req.Amount,
+		Currency:          strings.ToUpper(req.Currency),
+		Email:             req.Email,
+		Status:            "success",
+		PaidAt:            &now,
+		Channel:           "card",
+		AuthorizationCode: card.Code,
+		CreatedAt:         now,
+	}
+	e.transactions[tx.Reference] = tx
+	data := e.chargeData(tx)
+	e.mu.Unlock()
+
+	if err := e.sendWebhook("charge.success", data); err != nil {
+		log.Printf("paystack emulator: %v", err)
+	}
+	writeJSON(w, http.StatusOK, true, "Charge attempted", data)
+}
+
+func (e *Emulator) deactivateAuthorization(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AuthorizationCode string `json:"authorization_code"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	e.mu.Lock()
+	card, ok := e.cards[req.AuthorizationCode]
+	if ok {
+		card.Active = false
+	}
+	e.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, false, "Authorization code not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, "Authorization has been deactivated", nil)
+}
+
+func (e *Emulator) createCustomer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		writeJSON(w, http.StatusBadRequest, false, "email is required", nil)
+		return
+	}
+	e.mu.Lock()
+	code, ok := e.customers[req.Email]
+	if !ok {
+		code = "CUS_" + strings.ReplaceAll(util.MustUUID(), "-", "")[:12]
+		e.customers[req.Email] = code
+	}
+	e.mu.Unlock()
+	writeJSON(w, http.StatusOK, true, "Customer created", map[string]interface{}{
+		"customer_code": code,
+		"email":         req.Email,
+	})
+}
+
+// createDedicatedAccount assigns a customer a virtual account, returning the
+// existing one on repeat calls.
+func (e *Emulator) createDedicatedAccount(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Customer      string `json:"customer"`
+		PreferredBank string `json:"preferred_bank"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Customer == "" {
+		writeJSON(w, http.StatusBadRequest, false, "customer is required", nil)
+		return
+	}
+	e.mu.Lock()
+	email := ""
+	for addr, code := range e.customers {
+		if code == req.Customer {
+			email = addr
+		}
+	}
+	if email == "" {
+		e.mu.Unlock()
+		writeJSON(w, http.StatusNotFound, false, "Customer not found", nil)
+		return
+	}
+	var acct *dedicatedAccount
+	for _, a := range e.accounts {
+		if a.CustomerCode == req.Customer {
+			acct = a
+		}
+	}
+	if acct == nil {
+		e.nextID++
+		acct = &dedicatedAccount{
+			AccountNumber: fmt.Sprintf("99%08d", e.nextID%100_000_000),
+			AccountName:   "EMULATED/" + strings.ToUpper(email),
+			CustomerCode:  req.Customer,
+			Email:         email,
+		}
+		e.accounts[acct.AccountNumber] = acct
+	}
+	e.mu.Unlock()
+	writeJSON(w, http.StatusOK, true, "NUBAN successfully created", map[string]interface{}{
+		"account_number": acct.AccountNumber,
+		"account_name":   acct.AccountName,
+		"currency":       "NGN",
+		"assigned":       true,
+		"bank":           map[string]interface{}{"name": "Emulator Bank", "slug": "emulator-bank"},
+		"customer":       map[string]interface{}{"customer_code": acct.CustomerCode, "email": acct.Email},
+	})
+}
+
+func (e *Emulator) virtualAccountControl(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount int64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+		writeJSON(w, http.StatusBadRequest, false, "amount is required", nil)
+		return
+	}
+	reference, err := e.CreditVirtualAccount(r.PathValue("account"), req.Amount)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, false, err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, "Transfer received", map[string]interface{}{"reference": reference})
+}
+
+// CreditVirtualAccount simulates a bank transfer of amount kobo into a dedicated
+// virtual account and delivers the resulting charge.success. It returns the
+// Paystack-generated reference.
+func (e *Emulator) CreditVirtualAccount(accountNumber string, amount int64) (string, error) {
+	e.mu.Lock()
+	acct, ok := e.accounts[accountNumber]
+	if !ok {
+		e.mu.Unlock()
+		return "", fmt.Errorf("virtual account %s not found", accountNumber)
+	}
+	e.nextID++
+	now := time.Now()
+	tx := &transaction{
+		ID:              e.nextID,
+		Reference:       fmt.Sprintf("T%d", e.nextID),
+		Amount:          amount,
+		Currency:        "NGN",
+		Email:           acct.Email,
+		Status:          "success",
+		PaidAt:          &now,
+		Channel:         "dedicated_nuban",
+		ReceiverAccount: acct.AccountNumber,
+		CreatedAt:       now,
+	}
+	e.transactions[tx.Reference] = tx
+	data := e.chargeData(tx)
+	e.mu.Unlock()
+	return tx.Reference, e.sendWebhook("charge.success", data)
+}
+
+func (e *Emulator) createTransfer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount    int64  `json:"amount"`
+		Currency  string `json:"currency"`
+		Recipient string `json:"recipient"`
+		Reference string `json:"reference"`
+		Reason    string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 || req.Reference == "" {
+		writeJSON(w, http.StatusBadRequest, false, "amount and reference are required", nil)
+		return
+	}
+	e.mu.Lock()
+	if _, ok := e.recipients[req.Recipient]; !ok {
+		e.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, false, "Invalid transfer recipient", nil)
+		return
+	}
+	if _, exists := e.transfers[req.Reference]; exists {
+		e.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, false, "Duplicate Transfer Reference", nil)
+		return
+	}
+	e.nextID++
+	tr := &transfer{
+		ID:        e.nextID,
+		Code:      "TRF_" + strings.ReplaceAll(util.MustUUID(), "-", "")[:12],
+		Reference: req.Reference,
+		Amount:    req.Amount,
+		Currency:  req.Currency,
+		Recipient: req.Recipient,
+		Reason:    req.Reason,
+		Status:    "pending",
+	}
+	e.transfers[tr.Reference] = tr
+	e.mu.Unlock()
+
+	if e.cfg.TransferOutcome != TransferOutcomeManual {
+		go func() {
+			time.Sleep(e.cfg.TransferDelay)
+			if err := e.SettleTransfer(tr.Reference, "transfer."+e.cfg.TransferOutcome); err != nil {
+				log.Printf("paystack emulator: settling transfer %s: %v", tr.Reference, err)
+			}
+		}()
+	}
+	writeJSON(w, http.StatusOK, true, "Transfer has been queued", map[string]interface{}{
+		"transfer_code": tr.Code,
+		"reference":     tr.Reference,
+		"status":        tr.Status,
+	})
+}
+
+func (e *Emulator) transferControl(w http.ResponseWriter, r *http.Request) {
+	if err := e.SettleTransfer(r.PathValue("reference"), "transfer."+r.PathValue("event")); err != nil {
+		writeJSON(w, http.StatusBadRequest, false, err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, "Transfer updated", nil)
+}
+
+// SettleTransfer moves a transfer to the state of event (transfer.success,
+// transfer.failed or transfer.reversed) and delivers that webhook.
+func (e *Emulator) SettleTransfer(reference, event string) error {
+	status := map[string]string{
+		"transfer.success":  "success",
+		"transfer.failed":   "failed",
+		"transfer.reversed": "reversed",
+	}[event]
+	if status == "" {
+		return fmt.Errorf("unknown transfer event %s", event)
+	}
+	e.mu.Lock()
+	tr, ok := e.transfers[reference]
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("transfer %s not found", reference)
+	}
+	if tr.Status != "pending" && !(status == "reversed" && tr.Status == "success") {
+		e.mu.Unlock()
+		return fmt.Errorf("transfer %s is already %s", reference, tr.Status)
+	}
+	tr.Status = status
+	data := transferData(tr)
+	e.mu.Unlock()
+	return e.sendWebhook(event, data)
+}
+
+func (e *Emulator) verifyTransfer(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	tr, ok := e.transfers[r.PathValue("reference")]
+	var data map[string]interface{}
+	if ok {
+		data = transferData(tr)
+	}
+	e.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, false, "Transfer not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, "Transfer retrieved", data)
+}
+
+func transferData(tr *transfer) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            tr.ID,
+		"transfer_code": tr.Code,
+		"reference":     tr.Reference,
+		"amount":        tr.Amount,
+		"currency":      tr.Currency,
+		"reason":        tr.Reason,
+		"status":        tr.Status,
+		"recipient":     map[string]interface{}{"recipient_code": tr.Recipient},
+	}
+}
+
+func (e *Emulator) createRefund(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Transaction  string `json:"transaction"`
+		Amount       int64  `json:"amount"`
+		Currency     string `json:"currency"`
+		MerchantNote string `json:"merchant_note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Transaction == "" {
+		writeJSON(w, http.StatusBadRequest, false, "transaction is required", nil)
+		return
+	}
+	e.mu.Lock()
+	tx, ok := e.transactions[req.Transaction]
+	if !ok || tx.Status != "success" {
+		e.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, false, "Transaction has not been paid", nil)
+		return
+	}
+	var refunded int64
+	for _, rf := range e.refunds {
+		if rf.Transaction == tx.Reference && rf.Status != "failed" {
+			refunded += rf.Amount
+		}
+	}
+	if req.Amount == 0 {
+		req.Amount = tx.Amount - refunded
+	}
+	if req.Amount <= 0 || refunded+req.Amount > tx.Amount {
+		e.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, false, "Refund amount cannot be greater than transaction amount", nil)
+		return
+	}
+	e.nextID++
+	rf := &refund{
+		ID:          e.nextID,
+		Transaction: tx.Reference,
+		Amount:      req.Amount,
+		Currency:    tx.Currency,
+		Note:        req.MerchantNote,
+		Status:      "pending",
+	}
+	id := fmt.Sprint(rf.ID)
+	e.refunds[id] = rf
+	e.mu.Unlock()
+
+	if e.cfg.TransferOutcome != TransferOutcomeManual {
+		event := "refund.processed"
+		if e.cfg.TransferOutcome == TransferOutcomeFailed {
+			event = "refund.failed"
+		}
+		go func() {
+			time.Sleep(e.cfg.TransferDelay)
+			if err := e.SettleRefund(id, event); err != nil {
+				log.Printf("paystack emulator: settling refund %s: %v", id, err)
+			}
+		}()
+	}
+	writeJSON(w, http.StatusOK, true, "Refund has been queued for processing", map[string]interface{}{
+		"id":            rf.ID,
+		"transaction":   map[string]interface{}{"reference": rf.Transaction},
+		"amount":        rf.Amount,
+		"currency":      rf.Currency,
+		"merchant_note": rf.Note,
+		"status":        rf.Status,
+	})
+}
+
+// listRefunds lists the refunds of the charge named by the transaction parameter.
+func (e *Emulator) listRefunds(w http.ResponseWriter, r *http.Request) {
+	reference := r.URL.Query().Get("transaction")
+	e.mu.Lock()
+	var refunds []*refund
+	for _, rf := range e.refunds {
+		if reference == "" || rf.Transaction == reference {
+			refunds = append(refunds, rf)
+		}
+	}
+	sort.Slice(refunds, func(i, j int) bool { return refunds[i].ID > refunds[j].ID })
+	rows := make([]interface{}, 0, len(refunds))
+	for _, rf := range refunds {
+		rows = append(rows, map[string]interface{}{
+			"id":                    rf.ID,
+			"transaction_reference": rf.Transaction,
+			"amount":                rf.Amount,
+			"currency":              rf.Currency,
+			"merchant_note":         rf.Note,
+			"status":                rf.Status,
+		})
+	}
+	e.mu.Unlock()
+	writePage(w, r, rows)
+}
+
+func (e *Emulator) refundControl(w http.ResponseWriter, r *http.Request) {
+	if err := e.SettleRefund(r.PathValue("id"), "refund."+r.PathValue("event")); err != nil {
+		writeJSON(w, http.StatusBadRequest, false, err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, true, "Refund updated", nil)
+}
+
+// SettleRefund moves a pending refund to the state of event (refund.processed or
+// refund.failed) and delivers that webhook.
+func (e *Emulator) SettleRefund(id, event string) error {
+	status := map[string]string{
+		"refund.processed": "processed",
+		"refund.failed":    "failed",
+	}[event]
+	if status == "" {
+		return fmt.Errorf("unknown refund event %s", event)
+	}
+	e.mu.Lock()
+	rf, ok := e.refunds[id]
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("refund %s not found", id)
+	}
+	if rf.Status != "pending" {
+		e.mu.Unlock()
+		return fmt.Errorf("refund %s is already %s", id, rf.Status)
+	}
+	rf.Status = status
+	data := map[string]interface{}{
+		"id":                    rf.ID,
+		"status":                rf.Status,
+		"transaction_reference": rf.Transaction,
+		"amount":                rf.Amount,
+		"currency":              rf.Currency,
+		"customer":              map[string]interface{}{"email": e.transactions[rf.Transaction].Email},
+	}
+	e.mu.Unlock()
+	return e.sendWebhook(event, data)
+}
+
+// Approve completes a checkout as a successful payment and delivers charge.success.
+func (e *Emulator) Approve(reference string) error {
+	e.mu.Lock()
+	tx, ok := e.transactions[reference]
+	if !ok || tx.Status != "ongoing" {
+		e.mu.Unlock()
+		return fmt.Errorf("no open checkout for %s", reference)
+	}
+	now := time.Now()
+	tx.Status, tx.PaidAt = "success", &now
+	if tx.Channel == "card" {
+		tx.AuthorizationCode = e.cardFor(tx.Email).Code
+	}
+	data := e.chargeData(tx)
+	e.mu.Unlock()
+	return e.sendWebhook("charge.success", data)
+}
+
+// cardFor returns the customer's active emulated card, issuing one if needed.
+// The caller holds e.mu.
+func (e *Emulator) cardFor(email string) *authorization {
+	for _, card := range e.cards {
+		if card.Email == email && card.Active {
+			return card
+		}
+	}
+	card := &authorization{
+		Code:      "AUTH_" + strings.ReplaceAll(util.MustUUID(), "-", "")[:10],
+		Signature: "SIG_" + strings.ReplaceAll(util.MustUUID(), "-", "")[:16],
+		Email:     email,
+		Active:    true,
+	}
+	e.cards[card.Code] = card
+	return card
+}
+
+// Decline fails a checkout. Like Paystack, no webhook is sent for a failed charge;
+// the failure is visible through /transaction/verify.
+func (e *Emulator) Decline(reference string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	tx, ok := e.transactions[reference]
+	if !ok || tx.Status != "ongoing" {
+		return fmt.Errorf("no open checkout for %s", reference)
+	}
+	tx.Status = "failed"
+	return nil
+}
+
+// sendWebhook posts a signed event to the configured webhook URL.
+func (e *Emulator) sendWebhook(event string, data map[string]interface{}) error {
+	if e.cfg.WebhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{"event": event, "data": data})
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha512.New, []byte(e.cfg.WebhookSecret))
+	mac.Write(body)
+	req, err := http.NewRequest(http.MethodPost, e.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-paystack-signature", hex.EncodeToString(mac.Sum(nil)))
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", event, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: status %d", event, resp.StatusCode)
+	}
+	return nil
+}
+
+// chargeData renders a transaction as Paystack does. The caller holds e.mu.
+func (e *Emulator) chargeData(tx *transaction) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":        tx.ID,
+		"status":    tx.Status,
+		"reference": tx.Reference,
+		"amount":    tx.Amount,
+		"currency":  tx.Currency,
+		"paid_at":   tx.PaidAt,
+		"channel":   tx.Channel,
+		"customer":  map[string]interface{}{"email": tx.Email},
+	}
+	if tx.Metadata != nil {
+		data["metadata"] = tx.Metadata
+	}
+	if tx.AuthorizationCode != "" {
+		data["authorization"] = map[string]interface{}{
+			"authorization_code": tx.AuthorizationCode,
+			"signature":          e.cards[tx.AuthorizationCode].Signature,
+			"channel":            "card",
+			"card_type":          "visa ",
+			"last4":              "4081",
+			"bank":               "TEST BANK",
+			"exp_month":          "12",
+			"exp_year":           "2030",
+			"reusable":           true,
+		}
+	}
+	if tx.ReceiverAccount != "" {
+		data["authorization"] = map[string]interface{}{
+			"channel":                      tx.Channel,
+			"receiver_bank_account_number": tx.ReceiverAccount,
+			"receiver_bank":                "Emulator Bank",
+		}
+	}
+	return data
+}
+
+// writePage writes one page of rows, selected by the page and perPage parameters, with Paystack's meta block.
+func writePage(w http.ResponseWriter, r *http.Request, rows []interface{}) {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("perPage"))
+	if err != nil || perPage <= 0 {
+		perPage = 50
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	start := min((page-1)*perPage, len(rows))
+	end := min(start+perPage, len(rows))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  true,
+		"message": "Retrieved",
+		"data":    rows[start:end],
+		"meta": map[string]interface{}{
+			"total":     len(rows),
+			"page":      page,
+			"perPage":   perPage,
+			"pageCount": (len(rows) + perPage - 1) / perPage,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, status bool, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "message": message, "data": data})
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/config"
+	"github.com/CyberwizD/Wallet-Service/internal/jobs"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+)
+
+// StartJobs launches background workers; they stop when ctx is cancelled.
+func StartJobs(ctx context.Context, cfg config.Config, svc *Services) {
+	jobs.Every(ctx, "reconciliation", cfg.ReconciliationInterval, func(context.Context) error {
+		_, err := svc.Reconciliation.Run(services.ReconciliationTriggerSchedule)
+		return err
+	})
+	jobs.Every(ctx, "settlement-reconciliation", cfg.SettlementInterval, func(ctx context.Context) error {
+		now := time.Now()
+		_, err := svc.Settlements.RunFromPaystack(ctx, services.SettlementRequest{
+			From:    now.Add(-services.SettlementScheduleWindow),
+			To:      now,
+			Fix:     cfg.SettlementAutoFix,
+			Trigger: services.ReconciliationTriggerSchedule,
+		})
+		return err
+	})
+	jobs.Every(ctx, "hold-expiry", time.Minute, func(context.Context) error {
+		return svc.Wallets.ExpireHolds()
+	})
+	jobs.Every(ctx, "deposit-expiry", cfg.DepositExpiryInterval, func(ctx context.Context) error {
+		n, err := svc.Wallets.ExpireDeposits(ctx, time.Now(), cfg.DepositExpiryTTL)
+		if n > 0 {
+			log.Printf("deposit expiry: %d deposits expired", n)
+		}
+		return err
+	})
+	jobs.Every(ctx, "withdrawal-verify", cfg.WithdrawalVerifyInterval, func(ctx context.Context) error {
+		n, err := svc.Wallets.VerifyWithdrawals(ctx, time.Now(), cfg.WithdrawalVerifyAfter)
+		if n > 0 {
+			log.Printf("withdrawal verify: %d withdrawals settled", n)
+		}
+		return err
+	})
+	jobs.Every(ctx, "refund-verify", cfg.RefundVerifyInterval, func(ctx context.Context) error {
+		n, err := svc.Wallets.VerifyRefunds(ctx, time.Now(), cfg.RefundVerifyAfter)
+		if n > 0 {
+			log.Printf("refund verify: %d refunds settled", n)
+		}
+		return err
+	})
+	jobs.Every(ctx, "auto-top-up", cfg.AutoTopUpInterval, func(ctx context.Context) error {
+		return svc.AutoTopUps.RunDue(ctx, time.Now())
+	})
+	jobs.Every(ctx, "scheduled-transfers", time.Minute, func(context.Context) error {
+		return svc.Schedules.RunDue(time.Now())
+	})
+	jobs.Every(ctx, "webhook-inbox", 5*time.Second, func(ctx context.Context) error {
+		return svc.Webhooks.ProcessDue(ctx, time.Now())
+	})
+	jobs.Every(ctx, "rejected-webhook-purge", time.Hour, func(context.Context) error {
+		return svc.Webhooks.PurgeRejected(time.Now())
+	})
+	jobs.Every(ctx, "idempotency-purge", time.Hour, func(context.Context) error {
+		return svc.Idempotency.PurgeExpired()
+	})
+	jobs.Every(ctx, "session-purge", time.Hour, func(context.Context) error {
+		return svc.Sessions.PurgeExpired()
+	})
+}
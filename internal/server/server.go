@@ -1,6 +1,9 @@
 package server
 
 import (
+	"expvar"
+	"log"
+
 	"github.com/CyberwizD/Wallet-Service/internal/config"
 	"github.com/CyberwizD/Wallet-Service/internal/handlers"
 	"github.com/CyberwizD/Wallet-Service/internal/middleware"
@@ -11,17 +14,20 @@ import (
 )
 
 // SetupRouter wires dependencies and routes.
-func SetupRouter(cfg config.Config, db *gorm.DB) *gin.Engine {
-	paystack := services.NewPaystackService(cfg.PaystackSecret, cfg.PaystackBaseURL)
-	userService := services.NewUserService(db)
-	walletService := services.NewWalletService(db, paystack)
-	keyService := services.NewAPIKeyService(db)
-
-	authHandler := handlers.NewAuthHandler(cfg, userService)
-	keyHandler := handlers.NewKeyHandler(keyService)
-	walletHandler := handlers.NewWalletHandler(walletService, paystack)
+func SetupRouter(cfg config.Config, db *gorm.DB, svc *Services) *gin.Engine {
+	authHandler := handlers.NewAuthHandler(cfg, svc.Users, svc.Sessions)
+	keyHandler := handlers.NewKeyHandler(svc.Keys)
+	walletHandler := handlers.NewWalletHandler(svc.Wallets)
+	webhookHandler := handlers.NewWebhookHandler(svc.Webhooks)
+	scheduleHandler := handlers.NewScheduleHandler(svc.Schedules)
+	bulkHandler := handlers.NewBulkTransferHandler(svc.BulkTransfers)
+	autoTopUpHandler := handlers.NewAutoTopUpHandler(svc.AutoTopUps)
+	adminHandler := handlers.NewAdminHandler(svc.Reconciliation, svc.Settlements, svc.Wallets, svc.Webhooks)
 
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
+	}
 
 	// Lightweight Swagger UI backed by docs/swagger.yaml
 	r.StaticFile("/swagger.yaml", "docs/swagger.yaml")
@@ -55,20 +61,78 @@ func SetupRouter(cfg config.Config, db *gorm.DB) *gin.Engine {
 
 	r.GET("/auth/google", authHandler.StartGoogleAuth)
 	r.GET("/auth/google/callback", authHandler.GoogleCallback)
+	r.POST("/auth/refresh", authHandler.Refresh)
+	r.POST("/auth/logout", authHandler.Logout)
+	// Checkout redirects the customer's browser here, without credentials.
+	r.GET(services.DepositCallbackPath, walletHandler.DepositCallback)
 
 	protected := r.Group("/")
 	protected.Use(middleware.AuthMiddleware(db, cfg.JWTSecret))
+	idempotent := middleware.Idempotency(svc.Idempotency)
 	{
+		protected.GET("/auth/sessions", authHandler.Sessions)
+		protected.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
 		protected.POST("/keys/create", keyHandler.CreateKey)
 		protected.POST("/keys/rollover", keyHandler.RolloverKey)
 
-		protected.POST("/wallet/deposit", middleware.RequirePermission("deposit"), walletHandler.Deposit)
+		protected.POST("/wallets", walletHandler.OpenWallet)
+		protected.GET("/wallets", middleware.RequirePermission("read"), walletHandler.Wallets)
+		protected.POST("/wallet/deposit", middleware.RequirePermission("deposit"), idempotent, walletHandler.Deposit)
+		protected.POST("/wallet/virtual-account", middleware.RequirePermission("deposit"), walletHandler.AssignVirtualAccount)
+		protected.GET("/wallet/virtual-account", middleware.RequirePermission("read"), walletHandler.VirtualAccount)
+		protected.GET("/wallet/cards", middleware.RequirePermission("read"), walletHandler.Cards)
+		protected.DELETE("/wallet/cards/:id", middleware.RequirePermission("deposit"), walletHandler.RemoveCard)
+		protected.POST("/wallet/cards/:id/charge", middleware.RequirePermission("deposit"), idempotent, walletHandler.ChargeCard)
+		protected.PUT("/wallet/auto-top-up", middleware.RequirePermission("deposit"), autoTopUpHandler.Set)
+		protected.GET("/wallet/auto-top-up", middleware.RequirePermission("read"), autoTopUpHandler.Get)
+		protected.DELETE("/wallet/auto-top-up", middleware.RequirePermission("deposit"), autoTopUpHandler.Delete)
 		protected.GET("/wallet/deposit/:reference/status", middleware.RequirePermission("read"), walletHandler.DepositStatus)
 		protected.GET("/wallet/balance", middleware.RequirePermission("read"), walletHandler.Balance)
-		protected.POST("/wallet/transfer", middleware.RequirePermission("transfer"), walletHandler.Transfer)
+		protected.POST("/wallet/transfer", middleware.RequirePermission("transfer"), idempotent, walletHandler.Transfer)
+		protected.GET("/wallet/transfers/:id", middleware.RequirePermission("read"), walletHandler.TransferDetail)
+		protected.POST("/wallet/bulk-transfers", middleware.RequirePermission("transfer"), idempotent, bulkHandler.Create)
+		protected.GET("/wallet/bulk-transfers/:id", middleware.RequirePermission("read"), bulkHandler.Status)
+		protected.POST("/wallet/recipients", middleware.RequirePermission("withdraw"), walletHandler.AddRecipient)
+		protected.GET("/wallet/recipients", middleware.RequirePermission("read"), walletHandler.Recipients)
+		protected.POST("/wallet/withdraw", middleware.RequirePermission("withdraw"), idempotent, walletHandler.Withdraw)
+		protected.POST("/wallet/holds", middleware.RequirePermission("transfer"), walletHandler.PlaceHold)
+		protected.GET("/wallet/holds", middleware.RequirePermission("read"), walletHandler.Holds)
+		protected.POST("/wallet/holds/:id/capture", middleware.RequirePermission("transfer"), walletHandler.CaptureHold)
+		protected.POST("/wallet/holds/:id/release", middleware.RequirePermission("transfer"), walletHandler.ReleaseHold)
+		protected.POST("/wallet/scheduled-transfers", middleware.RequirePermission("transfer"), scheduleHandler.Create)
+		protected.GET("/wallet/scheduled-transfers", middleware.RequirePermission("read"), scheduleHandler.List)
+		protected.POST("/wallet/scheduled-transfers/:id/cancel", middleware.RequirePermission("transfer"), scheduleHandler.Cancel)
+		protected.GET("/wallet/scheduled-transfers/:id/runs", middleware.RequirePermission("read"), scheduleHandler.Runs)
 		protected.GET("/wallet/transactions", middleware.RequirePermission("read"), walletHandler.Transactions)
 	}
 
-	r.POST("/wallet/paystack/webhook", walletHandler.PaystackWebhook)
+	admin := protected.Group("/admin")
+	admin.Use(middleware.RequireAdmin(cfg.AdminEmails))
+	{
+		admin.POST("/reconciliation/run", adminHandler.RunReconciliation)
+		admin.GET("/reconciliation/runs", adminHandler.ReconciliationRuns)
+		admin.GET("/reconciliation/runs/:id", adminHandler.ReconciliationReport)
+		admin.POST("/settlements/reconcile", adminHandler.ReconcileSettlements)
+		admin.GET("/settlements/runs", adminHandler.SettlementRuns)
+		admin.GET("/settlements/runs/:id", adminHandler.SettlementReport)
+		admin.POST("/transfers/:id/reverse", adminHandler.ReverseTransfer)
+		admin.POST("/deposits/:reference/refunds", idempotent, adminHandler.RefundDeposit)
+		admin.GET("/deposits/:reference/refunds", adminHandler.DepositRefunds)
+		admin.GET("/metrics", gin.WrapH(expvar.Handler()))
+		admin.GET("/webhooks", adminHandler.WebhookEvents)
+		admin.GET("/webhooks/:id", adminHandler.WebhookEvent)
+		admin.POST("/webhooks/:id/replay", adminHandler.ReplayWebhook)
+	}
+
+	webhookSources := map[string][]string{
+		services.ProviderPaystack:    cfg.PaystackWebhookAllowedIPs,
+		services.ProviderFlutterwave: cfg.FlutterwaveWebhookAllowedIPs,
+	}
+	for _, gateway := range svc.Gateways {
+		name := gateway.Name()
+		r.POST("/wallet/"+name+"/webhook",
+			middleware.WebhookSourceAllowlist(name, webhookSources[name]),
+			webhookHandler.Receive(gateway))
+	}
 	return r
 }
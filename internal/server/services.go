@@ -0,0 +1,78 @@
+package server
+
+import (
+	"github.com/CyberwizD/Wallet-Service/internal/config"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// Services bundles the service instances shared by HTTP routes and background jobs.
+type Services struct {
+	Paystack       *services.PaystackService
+	Gateways       []services.PaymentGateway // deposit gateways in order of preference
+	Users          *services.UserService
+	Sessions       *services.SessionService
+	Wallets        *services.WalletService
+	Keys           *services.APIKeyService
+	Reconciliation *services.ReconciliationService
+	Settlements    *services.SettlementService
+	Idempotency    *services.IdempotencyService
+	Schedules      *services.ScheduleService
+	BulkTransfers  *services.BulkTransferService
+	Webhooks       *services.WebhookService
+	AutoTopUps     *services.AutoTopUpService
+}
+
+// NewServices constructs every service from config and the database handle.
+func NewServices(cfg config.Config, db *gorm.DB) *Services {
+	paystack := services.NewPaystackService(cfg.PaystackSecret, cfg.PaystackBaseURL)
+	paystack.SetWebhookSecrets(webhookSecrets(cfg))
+	var gateways []services.PaymentGateway
+	for _, name := range cfg.PaymentGateways {
+		switch name {
+		case services.ProviderPaystack:
+			gateways = append(gateways, paystack)
+		case services.ProviderFlutterwave:
+			gateways = append(gateways, services.NewFlutterwaveService(
+				cfg.FlutterwaveSecret, cfg.FlutterwaveBaseURL, cfg.FlutterwaveWebhookHash, cfg.FlutterwaveRedirectURL))
+		}
+	}
+	sessions := services.NewSessionService(db, cfg.JWTSecret)
+	sessions.SetTokenTTLs(cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	wallets := services.NewWalletService(db, paystack)
+	wallets.SetGateways(gateways...)
+	wallets.SetVirtualAccountBank(cfg.PaystackVirtualAccountBank)
+	wallets.SetDepositCallback(cfg.PublicURL, cfg.DepositCallbackAllowedURLs)
+	wallets.SetDepositChannels(cfg.DepositChannels)
+	return &Services{
+		Paystack:       paystack,
+		Gateways:       gateways,
+		Users:          services.NewUserService(db),
+		Sessions:       sessions,
+		Wallets:        wallets,
+		Keys:           services.NewAPIKeyService(db),
+		Reconciliation: services.NewReconciliationService(db),
+		Settlements:    services.NewSettlementService(db, wallets),
+		Idempotency:    services.NewIdempotencyService(db),
+		Schedules:      services.NewScheduleService(db, wallets),
+		BulkTransfers:  services.NewBulkTransferService(db, wallets),
+		Webhooks:       services.NewWebhookService(db, wallets),
+		AutoTopUps:     services.NewAutoTopUpService(db, wallets),
+	}
+}
+
+// webhookSecrets lists the keys accepted for Paystack webhook signatures: the
+// current webhook secret (the API secret key unless overridden) plus any previous
+// secrets still inside the rotation window.
+func webhookSecrets(cfg config.Config) []services.WebhookSecret {
+	current := cfg.PaystackWebhookSecret
+	if current == "" {
+		current = cfg.PaystackSecret
+	}
+	secrets := []services.WebhookSecret{{Key: current}}
+	for _, previous := range cfg.PaystackWebhookPreviousSecrets {
+		secrets = append(secrets, services.WebhookSecret{Key: previous, NotAfter: cfg.PaystackWebhookRotationEnds})
+	}
+	return secrets
+}
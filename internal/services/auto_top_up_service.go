@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// Auto top-up retry policy.
+const (
+	// MaxAutoTopUpFailures consecutive failed charges pause a rule.
+	MaxAutoTopUpFailures = 3
+	autoTopUpRetryDelay  = time.Hour
+	// autoTopUpCooldown is the minimum time between a successful charge and the next
+	// one, so a wallet that stays below its threshold is not charged on every tick.
+	autoTopUpCooldown  = time.Hour
+	autoTopUpBatchSize = 100
+)
+
+// ErrAutoTopUpNotFound is returned when a wallet has no auto top-up rule.
+var ErrAutoTopUpNotFound = errors.New("auto top-up not found")
+
+// AutoTopUpRequest describes the rule for one wallet.
+type AutoTopUpRequest struct {
+	CardID    string
+	Threshold int64
+	Amount    int64
+	Currency  string // selects the wallet; empty means the default currency
+}
+
+// AutoTopUpService keeps wallets funded by charging a saved card whenever the
+// available balance drops below a threshold.
+type AutoTopUpService struct {
+	db      *gorm.DB
+	wallets *WalletService
+}
+
+// NewAutoTopUpService constructs an AutoTopUpService.
+func NewAutoTopUpService(db *gorm.DB, wallets *WalletService) *AutoTopUpService {
+	return &AutoTopUpService{db: db, wallets: wallets}
+}
+
+// Set creates or replaces the auto top-up rule of the user's wallet. Saving a
+// paused rule resumes it.
+func (s *AutoTopUpService) Set(user *models.User, req AutoTopUpRequest) (*models.AutoTopUp, error) {
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	if req.Threshold <= 0 {
+		return nil, errors.New("threshold must be greater than zero")
+	}
+	if _, err := s.wallets.card(user.ID, req.CardID); err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.WalletFor(user.ID, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var rule models.AutoTopUp
+	err = s.db.First(&rule, "wallet_id = ?", wallet.ID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		rule = models.AutoTopUp{
+			ID:        util.MustUUID(),
+			UserID:    user.ID,
+			WalletID:  wallet.ID,
+			CreatedAt: now,
+		}
+	case err != nil:
+		return nil, err
+	}
+	rule.CardID = req.CardID
+	rule.Threshold = req.Threshold
+	rule.Amount = req.Amount
+	rule.Status = models.AutoTopUpActive
+	rule.Failures = 0
+	rule.LastError = ""
+	rule.UpdatedAt = now
+	if err := s.db.Save(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Get returns the auto top-up rule of the user's wallet in currency.
+func (s *AutoTopUpService) Get(userID, currency string) (*models.AutoTopUp, error) {
+	wallet, err := s.wallets.WalletFor(userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	var rule models.AutoTopUp
+	if err := s.db.First(&rule, "wallet_id = ?", wallet.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutoTopUpNotFound
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Delete removes the auto top-up rule of the user's wallet in currency.
+func (s *AutoTopUpService) Delete(userID, currency string) error {
+	rule, err := s.Get(userID, currency)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(rule).Error
+}
+
+// RunDue charges the card of every active rule whose wallet is below its threshold.
+func (s *AutoTopUpService) RunDue(ctx context.Context, now time.Time) error {
+	var due []models.AutoTopUp
+	if err := s.db.Joins("JOIN wallets ON wallets.id = auto_top_ups.wallet_id").
+		Where("auto_top_ups.status = ? AND wallets.balance - wallets.held_balance < auto_top_ups.threshold", models.AutoTopUpActive).
+		Order("auto_top_ups.updated_at").Limit(autoTopUpBatchSize).Find(&due).Error; err != nil {
+		return err
+	}
+	for _, rule := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.run(ctx, rule, now); err != nil {
+			log.Printf("auto top-up %s: %v", rule.ID, err)
+		}
+	}
+	return nil
+}
+
+// run counts the outcome of the rule's previous charge, then claims and makes the
+// next one. The claim swaps LastReference, so two workers never charge for the same dip.
+func (s *AutoTopUpService) run(ctx context.Context, rule models.AutoTopUp, now time.Time) error {
+	failures, lastError := rule.Failures, rule.LastError
+	if rule.LastReference != "" {
+		last, err := s.wallets.DepositStatus(rule.LastReference)
+		if err != nil {
+			return err
+		}
+		switch last.Status {
+		case models.TransactionPending:
+			return nil // the previous charge is still settling
+		case models.TransactionSuccess:
+			failures, lastError = 0, ""
+		default:
+			failures++
+			lastError = fmt.Sprintf("deposit %s %s", last.Reference, last.Status)
+		}
+	}
+	updates := map[string]interface{}{
+		"failures":       failures,
+		"last_reference": "",
+		"last_error":     lastError,
+		"updated_at":     now,
+	}
+	charge := true
+	switch {
+	case failures >= MaxAutoTopUpFailures:
+		updates["status"] = models.AutoTopUpPaused
+		updates["last_error"] = fmt.Sprintf("paused after %d failed charges", failures)
+		charge = false
+	case failures > 0 && rule.LastAttemptAt != nil && now.Before(rule.LastAttemptAt.Add(autoTopUpRetryDelay)):
+		charge = false
+	case failures == 0 && rule.LastAttemptAt != nil && now.Before(rule.LastAttemptAt.Add(autoTopUpCooldown)):
+		charge = false
+	}
+	reference := fmt.Sprintf("DEP-%s", util.MustUUID())
+	if charge {
+		updates["last_reference"] = reference
+		updates["last_attempt_at"] = now
+	}
+	claim := s.db.Model(&models.AutoTopUp{}).
+		Where("id = ? AND status = ? AND COALESCE(last_reference, '') = ?", rule.ID, models.AutoTopUpActive, rule.LastReference).
+		Updates(updates)
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 || !charge {
+		return nil // another worker took this charge, the rule changed, or it is cooling down
+	}
+
+	var card models.SavedCard
+	var wallet models.Wallet
+	err := s.db.First(&card, "id = ?", rule.CardID).Error
+	if err == nil {
+		err = s.db.First(&wallet, "id = ?", rule.WalletID).Error
+	}
+	if err == nil {
+		_, err = s.wallets.chargeSavedCard(ctx, &card, &wallet, rule.Amount, reference, "auto top-up")
+	}
+	if err != nil {
+		// The failed deposit is counted here, so it no longer needs to be tracked.
+		return s.db.Model(&models.AutoTopUp{}).Where("id = ? AND last_reference = ?", rule.ID, reference).
+			Updates(map[string]interface{}{
+				"failures":       failures + 1,
+				"last_reference": "",
+				"last_error":     err.Error(),
+				"updated_at":     time.Now(),
+			}).Error
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// MaxBulkTransferItems bounds the number of recipients in one batch.
+const MaxBulkTransferItems = 1000
+
+// BulkTransferItem is one requested recipient line.
+type BulkTransferItem struct {
+	WalletNumber string
+	Amount       int64
+	Narration    string
+}
+
+// BulkTransferService sends one wallet's funds to many recipients using the
+// same locking path as WalletService.Transfer.
+type BulkTransferService struct {
+	db      *gorm.DB
+	wallets *WalletService
+}
+
+// NewBulkTransferService constructs a BulkTransferService.
+func NewBulkTransferService(db *gorm.DB, wallets *WalletService) *BulkTransferService {
+	return &BulkTransferService{db: db, wallets: wallets}
+}
+
+// Create records a batch from the user's wallet in currency (the default-currency
+// wallet when empty), executes it in the requested mode, and returns it with per-item results.
+func (s *BulkTransferService) Create(user *models.User, mode models.BatchMode, currency string, items []BulkTransferItem) (*models.TransferBatch, error) {
+	if user == nil || user.Wallet.ID == "" {
+		return nil, errors.New("sender wallet not found")
+	}
+	if mode == "" {
+		mode = models.BatchAllOrNothing
+	}
+	if mode != models.BatchAllOrNothing && mode != models.BatchBestEffort {
+		return nil, errors.New("mode must be all_or_nothing or best_effort")
+	}
+	if len(items) == 0 {
+		return nil, errors.New("at least one item is required")
+	}
+	if len(items) > MaxBulkTransferItems {
+		return nil, fmt.Errorf("a batch can contain at most %d items", MaxBulkTransferItems)
+	}
+	wallet, err := s.wallets.userWallet(user, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	batch := models.TransferBatch{
+		ID:        util.MustUUID(),
+		UserID:    user.ID,
+		WalletID:  wallet.ID,
+		Currency:  wallet.Currency,
+		Mode:      mode,
+		Status:    models.BatchProcessing,
+		ItemCount: len(items),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for i, item := range items {
+		if item.WalletNumber == "" {
+			return nil, fmt.Errorf("item %d: wallet_number is required", i+1)
+		}
+		if item.Amount <= 0 {
+			return nil, fmt.Errorf("item %d: amount must be greater than zero", i+1)
+		}
+		if len(item.Narration) > MaxNarrationLength {
+			return nil, fmt.Errorf("item %d: narration must be at most %d characters", i+1, MaxNarrationLength)
+		}
+		batch.TotalAmount += item.Amount
+		batch.Items = append(batch.Items, models.TransferBatchItem{
+			ID:           util.MustUUID(),
+			BatchID:      batch.ID,
+			Position:     i + 1,
+			WalletNumber: item.WalletNumber,
+			Amount:       item.Amount,
+			Narration:    item.Narration,
+			Status:       models.BatchItemPending,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		})
+	}
+	if err := s.db.Create(&batch).Error; err != nil {
+		return nil, err
+	}
+
+	// Money has moved from here on, so storage errors are logged rather than
+	// returned: each item's status is saved with its transfer and the batch totals
+	// are derived from the items.
+	if mode == models.BatchAllOrNothing {
+		s.runAllOrNothing(&batch)
+	} else {
+		s.runBestEffort(&batch)
+	}
+	summarize(&batch)
+	batch.UpdatedAt = time.Now()
+	if err := s.db.Model(&batch).Updates(map[string]interface{}{
+		"success_count": batch.SuccessCount,
+		"failed_count":  batch.FailedCount,
+		"status":        batch.Status,
+		"updated_at":    batch.UpdatedAt,
+	}).Error; err != nil {
+		log.Printf("transfer batch %s: saving outcome: %v", batch.ID, err)
+	}
+	return &batch, nil
+}
+
+// summarize sets the batch counts and status from its items. The batch stays
+// processing while any item is pending.
+func summarize(batch *models.TransferBatch) {
+	batch.SuccessCount, batch.FailedCount = 0, 0
+	pending := false
+	for _, item := range batch.Items {
+		switch item.Status {
+		case models.BatchItemSuccess:
+			batch.SuccessCount++
+		case models.BatchItemPending:
+			pending = true
+		default:
+			batch.FailedCount++
+		}
+	}
+	switch {
+	case pending:
+		batch.Status = models.BatchProcessing
+	case batch.SuccessCount == batch.ItemCount:
+		batch.Status = models.BatchCompleted
+	case batch.SuccessCount == 0:
+		batch.Status = models.BatchFailed
+	default:
+		batch.Status = models.BatchPartiallyCompleted
+	}
+}
+
+// Get returns a batch with its items if it belongs to the user.
+func (s *BulkTransferService) Get(userID, batchID string) (*models.TransferBatch, error) {
+	var batch models.TransferBatch
+	err := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		First(&batch, "id = ? AND user_id = ?", batchID, userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("batch not found")
+		}
+		return nil, err
+	}
+	summarize(&batch)
+	return &batch, nil
+}
+
+// runAllOrNothing executes every item and saves their statuses in one DB
+// transaction; any failure rolls all of them back.
+func (s *BulkTransferService) runAllOrNothing(batch *models.TransferBatch) {
+	failedAt := -1
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for i := range batch.Items {
+			item := &batch.Items[i]
+			transferID, err := s.wallets.transferTx(tx, batch.WalletID, item.WalletNumber, item.Amount, s.options(batch, item))
+			if err != nil {
+				failedAt = i
+				return err
+			}
+			item.Status, item.TransferID, item.UpdatedAt = models.BatchItemSuccess, transferID, now
+		}
+		for i := range batch.Items {
+			if err := tx.Save(&batch.Items[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+	now := time.Now()
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		item.TransferID, item.UpdatedAt = "", now
+		if i == failedAt || failedAt == -1 {
+			item.Status, item.Error = models.BatchItemFailed, err.Error()
+		} else {
+			item.Status = models.BatchItemRolledBack
+			item.Error = fmt.Sprintf("batch rolled back: item %d failed", failedAt+1)
+		}
+	}
+	// Nothing moved; if this save fails the items stay pending, which reads the same.
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range batch.Items {
+			if err := tx.Save(&batch.Items[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Printf("transfer batch %s: saving rolled back items: %v", batch.ID, err)
+	}
+}
+
+// runBestEffort executes each item in its own DB transaction, saving the item's
+// status with its transfer.
+func (s *BulkTransferService) runBestEffort(batch *models.TransferBatch) {
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			transferID, err := s.wallets.transferTx(tx, batch.WalletID, item.WalletNumber, item.Amount, s.options(batch, item))
+			if err != nil {
+				return err
+			}
+			item.Status, item.TransferID, item.UpdatedAt = models.BatchItemSuccess, transferID, time.Now()
+			return tx.Save(item).Error
+		})
+		if err == nil {
+			continue
+		}
+		item.Status, item.Error, item.TransferID, item.UpdatedAt = models.BatchItemFailed, err.Error(), "", time.Now()
+		if err := s.db.Save(item).Error; err != nil {
+			log.Printf("transfer batch %s: saving item %d: %v", batch.ID, item.Position, err)
+		}
+	}
+}
+
+func (s *BulkTransferService) options(batch *models.TransferBatch, item *models.TransferBatchItem) TransferOptions {
+	return TransferOptions{Narration: item.Narration, ClientReference: batch.ID}
+}
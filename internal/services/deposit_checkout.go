@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DepositCallbackPath is the built-in page checkout returns the customer to when
+// the service's public URL is configured.
+const DepositCallbackPath = "/wallet/deposit/callback"
+
+// PaystackChannels are the payment channels a checkout can be restricted to.
+var PaystackChannels = []string{"card", "bank", "ussd", "qr", "mobile_money", "bank_transfer", "eft", "apple_pay"}
+
+// Limits on the metadata a client attaches to a deposit.
+const (
+	MaxDepositMetadataKeys  = 10
+	maxDepositMetadataKey   = 50
+	maxDepositMetadataValue = 200
+)
+
+// ReservedMetadataKeys are metadata keys the gateway gives a meaning of its own
+// (Paystack's cancel_action redirects a cancelled checkout, custom_fields and
+// custom_filters change the checkout page and the channels it offers). Clients may
+// not set them.
+var ReservedMetadataKeys = []string{"cancel_action", "custom_fields", "custom_filters", "referrer"}
+
+// ErrCallbackURLNotAllowed means a deposit's callback_url is not on the allowlist.
+var ErrCallbackURLNotAllowed = errors.New("callback_url is not allowed")
+
+// CheckoutOptions customise a gateway checkout. Gateways map channel names to
+// their own and ignore the ones they do not support.
+type CheckoutOptions struct {
+	// CallbackURL is where checkout returns the customer; empty keeps the gateway's default.
+	CallbackURL string
+	// Channels restricts the payment methods offered; empty offers them all.
+	Channels []string
+	Metadata map[string]string
+}
+
+// DepositOptions are the client's choices for a deposit checkout.
+type DepositOptions struct {
+	// CallbackURL receives the customer after checkout with reference and status
+	// query parameters. It must match the allowlist; app deep links are accepted.
+	CallbackURL string
+	Channels    []string
+	// Metadata tags the payment at the gateway. wallet_number and user_id are
+	// always set by the service.
+	Metadata map[string]string
+}
+
+// SetDepositCallback configures checkout returns. With publicURL set, customers
+// return to DepositCallbackPath, which verifies the deposit before redirecting
+// to the client's callback_url; without it, the callback_url is handed to the
+// gateway directly. allowed lists the callback URL prefixes clients may use.
+func (s *WalletService) SetDepositCallback(publicURL string, allowed []string) {
+	s.publicURL = strings.TrimSuffix(publicURL, "/")
+	s.callbackAllowlist = allowed
+}
+
+// SetDepositChannels restricts every checkout to channels; a deposit may narrow
+// them further. Empty allows every channel.
+func (s *WalletService) SetDepositChannels(channels []string) {
+	s.depositChannels = channels
+}
+
+// checkoutOptions validates a client's deposit options and builds what is sent to the gateway.
+func (s *WalletService) checkoutOptions(opts DepositOptions, userID, walletNumber string) (CheckoutOptions, error) {
+	res := CheckoutOptions{Channels: s.depositChannels, Metadata: map[string]string{}}
+	if opts.CallbackURL != "" {
+		if !CallbackURLAllowed(opts.CallbackURL, s.callbackAllowlist) {
+			return res, ErrCallbackURLNotAllowed
+		}
+		res.CallbackURL = opts.CallbackURL
+	}
+	if s.publicURL != "" {
+		res.CallbackURL = s.publicURL + DepositCallbackPath
+	}
+	if len(opts.Channels) > 0 {
+		allowed := s.depositChannels
+		if len(allowed) == 0 {
+			allowed = PaystackChannels
+		}
+		res.Channels = nil
+		for _, channel := range opts.Channels {
+			if !slices.Contains(allowed, channel) {
+				return res, fmt.Errorf("channel %q is not available; use one of %s", channel, strings.Join(allowed, ", "))
+			}
+			if !slices.Contains(res.Channels, channel) {
+				res.Channels = append(res.Channels, channel)
+			}
+		}
+	}
+	if len(opts.Metadata) > MaxDepositMetadataKeys {
+		return res, fmt.Errorf("metadata may have at most %d keys", MaxDepositMetadataKeys)
+	}
+	for key, value := range opts.Metadata {
+		if key == "" || len(key) > maxDepositMetadataKey || len(value) > maxDepositMetadataValue {
+			return res, fmt.Errorf("metadata keys must be 1-%d characters and values at most %d", maxDepositMetadataKey, maxDepositMetadataValue)
+		}
+		if slices.Contains(ReservedMetadataKeys, key) {
+			return res, fmt.Errorf("metadata key %q is reserved", key)
+		}
+		res.Metadata[key] = value
+	}
+	res.Metadata["wallet_number"] = walletNumber
+	res.Metadata["user_id"] = userID
+	return res, nil
+}
+
+// CallbackURLAllowed reports whether raw falls under one of the allowed URL
+// prefixes: same scheme and host, and a path at or below the prefix's path.
+// Custom schemes such as myapp://payments are matched the same way.
+func CallbackURLAllowed(raw string, allowed []string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil {
+		return false
+	}
+	for _, entry := range allowed {
+		prefix, err := url.Parse(entry)
+		if err != nil || !strings.EqualFold(prefix.Scheme, u.Scheme) || !strings.EqualFold(prefix.Host, u.Host) {
+			continue
+		}
+		base := strings.TrimSuffix(prefix.Path, "/")
+		if base == "" || u.Path == base || strings.HasPrefix(u.Path, base+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// DepositReturnURL appends a deposit's reference and status to its callback URL.
+func DepositReturnURL(callbackURL, reference, status string) (string, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("reference", reference)
+	query.Set("status", status)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"net/http"
+)
+
+// Payment providers.
+const (
+	ProviderPaystack    = "paystack"
+	ProviderFlutterwave = "flutterwave"
+)
+
+// PaymentGateway is a processor that collects deposits through a hosted checkout
+// and reports on them by webhook. Calls that reach the provider fail with a *GatewayError.
+type PaymentGateway interface {
+	// Name identifies the provider on transactions, webhook routes and the inbox.
+	Name() string
+	// InitializeTransaction returns a checkout URL for amount in the currency's smallest unit.
+	InitializeTransaction(ctx context.Context, amount int64, currency, email, reference string, opts CheckoutOptions) (string, error)
+	// VerifyTransaction fetches the authoritative state of a charge.
+	VerifyTransaction(ctx context.Context, reference string) (*PaymentVerification, error)
+	// VerifyWebhook reports whether a webhook delivery was signed by the provider.
+	VerifyWebhook(header http.Header, body []byte) bool
+	// ParseWebhook extracts the fields the inbox and dispatcher need from a webhook body.
+	ParseWebhook(body []byte) (*WebhookNotification, error)
+}
+
+// PaymentVerification is what a provider reports for a transaction reference.
+// Amount is in the currency's smallest unit whatever the provider's own convention.
+type PaymentVerification struct {
+	Status        string // success, failed, abandoned, reversed, or a provider's in-progress status
+	Reference     string
+	Amount        int64
+	Currency      string
+	CustomerEmail string
+	// Card is the reusable authorization behind a successful card charge, when the
+	// provider returns one.
+	Card *CardAuthorization
+}
+
+// CardAuthorization identifies a card that can be charged again without the customer.
+type CardAuthorization struct {
+	Code      string
+	Signature string
+	Brand     string
+	Last4     string
+	Bank      string
+	ExpMonth  string
+	ExpYear   string
+}
+
+// WebhookNotification is a provider webhook reduced to what we act on.
+type WebhookNotification struct {
+	ID        string // provider's dedup key; empty falls back to a hash of the body
+	Type      string // provider's event name, e.g. charge.success
+	Reference string
+	// ChargeStatus is set for events reporting on a deposit, using PaymentVerification's statuses.
+	ChargeStatus string
+	// VirtualAccount is the receiving account number of a bank transfer into a
+	// dedicated virtual account; the reference is then the provider's, not ours.
+	VirtualAccount string
+	// RefundID is the provider's id of the refund a refund event reports on; the
+	// reference is then the refunded deposit's.
+	RefundID string
+	// Amount (minor units) and Currency of the refund, for refund events.
+	Amount   int64
+	Currency string
+}
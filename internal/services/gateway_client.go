@@ -0,0 +1,272 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/metrics"
+)
+
+// GatewayErrorKind classifies a failed payment provider call.
+type GatewayErrorKind string
+
+const (
+	// GatewayAuth means the provider refused our credentials.
+	GatewayAuth GatewayErrorKind = "auth"
+	// GatewayRateLimited means the provider asked us to slow down; the request was not acted on.
+	GatewayRateLimited GatewayErrorKind = "rate_limited"
+	// GatewayUnavailable covers network failures, timeouts, 5xx responses and an open circuit.
+	GatewayUnavailable GatewayErrorKind = "unavailable"
+	// GatewayValidation means the provider rejected the request itself.
+	GatewayValidation GatewayErrorKind = "validation"
+)
+
+// ErrCircuitOpen is wrapped by the GatewayError returned without calling a provider
+// whose circuit is open, so callers know the request was never sent.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// GatewayError is returned by every payment provider call that fails.
+type GatewayError struct {
+	Provider   string
+	Kind       GatewayErrorKind
+	StatusCode int           // provider's HTTP status, 0 when no response was received
+	Message    string        // provider's message or a description of the failure
+	RetryAfter time.Duration // set when the provider said when to retry
+	Err        error
+}
+
+func (e *GatewayError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Provider, e.Kind, e.Message)
+}
+
+func (e *GatewayError) Unwrap() error { return e.Err }
+
+// RetryPolicy controls how a gateway client retries and when it stops calling a failing provider.
+type RetryPolicy struct {
+	MaxAttempts      int           // attempts per call, including the first
+	BaseDelay        time.Duration // first backoff, doubled per retry with full jitter
+	MaxDelay         time.Duration
+	AttemptTimeout   time.Duration // deadline per attempt; the caller's context may be shorter
+	BreakerThreshold int           // consecutive unavailable failures that open the circuit
+	BreakerCooldown  time.Duration // how long the circuit stays open before a trial call
+}
+
+// DefaultRetryPolicy is used by gateway clients unless replaced.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:      3,
+	BaseDelay:        250 * time.Millisecond,
+	MaxDelay:         2 * time.Second,
+	AttemptTimeout:   10 * time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// gatewayClient sends authenticated JSON requests to a provider. Reads are retried
+// when the provider is unavailable; writes are retried only when rate limited,
+// because a timed-out write may already have been applied. Consecutive
+// unavailable failures open a circuit so callers fail fast during an outage.
+type gatewayClient struct {
+	provider  string
+	baseURL   string
+	secretKey string
+	http      *http.Client
+	policy    RetryPolicy
+	breaker   circuitBreaker
+}
+
+func newGatewayClient(provider, baseURL, secretKey string) *gatewayClient {
+	return &gatewayClient{
+		provider:  provider,
+		baseURL:   baseURL,
+		secretKey: secretKey,
+		http:      &http.Client{},
+		policy:    DefaultRetryPolicy,
+	}
+}
+
+// do sends the request and decodes a 2xx JSON response into out.
+func (c *gatewayClient) do(ctx context.Context, method, path string, payload interface{}, out interface{}) error {
+	var raw []byte
+	if payload != nil {
+		var err error
+		if raw, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	safe := method == http.MethodGet
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.allow() {
+			return c.fail(&GatewayError{Provider: c.provider, Kind: GatewayUnavailable,
+				Message: "circuit open after repeated failures", Err: ErrCircuitOpen})
+		}
+		gerr := c.attempt(ctx, method, path, raw, out)
+		c.breaker.record(c.policy, gerr != nil && gerr.Kind == GatewayUnavailable)
+		if gerr == nil {
+			return nil
+		}
+		retryable := gerr.Kind == GatewayRateLimited || (safe && gerr.Kind == GatewayUnavailable)
+		if !retryable || attempt >= c.policy.MaxAttempts || ctx.Err() != nil {
+			return c.fail(gerr)
+		}
+		delay := c.backoff(attempt)
+		if gerr.RetryAfter > 0 {
+			if gerr.RetryAfter > c.policy.MaxDelay {
+				return c.fail(gerr)
+			}
+			delay = gerr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return c.fail(&GatewayError{Provider: c.provider, Kind: GatewayUnavailable, Message: ctx.Err().Error(), Err: ctx.Err()})
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *gatewayClient) attempt(ctx context.Context, method, path string, raw []byte, out interface{}) *GatewayError {
+	if c.policy.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.policy.AttemptTimeout)
+		defer cancel()
+	}
+	var body io.Reader
+	if raw != nil {
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return &GatewayError{Provider: c.provider, Kind: GatewayValidation, Message: err.Error(), Err: err}
+	}
+	if raw != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.secretKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &GatewayError{Provider: c.provider, Kind: GatewayUnavailable, Message: err.Error(), Err: err}
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return &GatewayError{Provider: c.provider, Kind: GatewayUnavailable, StatusCode: resp.StatusCode, Message: err.Error(), Err: err}
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return &GatewayError{Provider: c.provider, Kind: GatewayUnavailable, StatusCode: resp.StatusCode,
+				Message: "malformed response: " + err.Error(), Err: err}
+		}
+		return nil
+	}
+	gerr := &GatewayError{Provider: c.provider, StatusCode: resp.StatusCode, Message: responseMessage(resp.StatusCode, respBody)}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		gerr.Kind = GatewayAuth
+	case resp.StatusCode == http.StatusTooManyRequests:
+		gerr.Kind = GatewayRateLimited
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			gerr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	case resp.StatusCode >= 500:
+		gerr.Kind = GatewayUnavailable
+	default:
+		gerr.Kind = GatewayValidation
+	}
+	return gerr
+}
+
+// rejected reports a well-formed response in which the provider declined the request.
+func (c *gatewayClient) rejected(message string) error {
+	return c.fail(&GatewayError{Provider: c.provider, Kind: GatewayValidation, Message: message})
+}
+
+func (c *gatewayClient) fail(gerr *GatewayError) error {
+	metrics.GatewayErrors.Add(c.provider+":"+string(gerr.Kind), 1)
+	return gerr
+}
+
+// backoff returns a full-jitter delay for the given attempt.
+func (c *gatewayClient) backoff(attempt int) time.Duration {
+	ceiling := c.policy.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > c.policy.MaxDelay {
+		ceiling = c.policy.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// responseMessage extracts the provider's "message" field, falling back to the status.
+func responseMessage(status int, body []byte) string {
+	var parsed struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Message != "" {
+		return fmt.Sprintf("%s (status %d)", parsed.Message, status)
+	}
+	return fmt.Sprintf("status %d", status)
+}
+
+// IsGatewayError reports whether err is a payment provider failure of one of kinds
+// (any kind when none are given).
+func IsGatewayError(err error, kinds ...GatewayErrorKind) bool {
+	var gerr *GatewayError
+	if !errors.As(err, &gerr) {
+		return false
+	}
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, kind := range kinds {
+		if gerr.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// circuitBreaker opens after BreakerThreshold consecutive failures and, once the
+// cooldown has passed, lets a single trial call through to decide whether to close.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *circuitBreaker) record(policy RetryPolicy, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		b.failures, b.openUntil, b.trial = 0, time.Time{}, false
+		return
+	}
+	b.failures++
+	if b.trial || (policy.BreakerThreshold > 0 && b.failures >= policy.BreakerThreshold) {
+		b.openUntil = time.Now().Add(policy.BreakerCooldown)
+		b.trial = false
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyTTL is how long a stored response is replayed for.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+var (
+	// ErrIdempotencyKeyReused means the key was already used for a different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used with a different request")
+	// ErrIdempotencyKeyInFlight means the original request has not finished yet.
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still in progress")
+)
+
+// IdempotencyService records Idempotency-Key usage and the responses to replay.
+type IdempotencyService struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyService constructs an IdempotencyService.
+func NewIdempotencyService(db *gorm.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
+}
+
+// Begin claims a key for a principal. It returns the stored record when the key
+// has already completed, so the caller can replay it; a nil record means the
+// caller owns the key and must Complete or Release it.
+func (s *IdempotencyService) Begin(principal, key, endpoint, fingerprint string) (*models.IdempotencyKey, error) {
+	now := time.Now()
+	record := models.IdempotencyKey{
+		ID:          util.MustUUID(),
+		Principal:   principal,
+		Key:         key,
+		Endpoint:    endpoint,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		res := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			return nil, nil
+		}
+		var existing models.IdempotencyKey
+		if err := s.db.First(&existing, "principal = ? AND key = ?", principal, key).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue // released between our insert and read
+			}
+			return nil, err
+		}
+		if now.Sub(existing.CreatedAt) > IdempotencyKeyTTL {
+			if err := s.db.Delete(&existing).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if existing.Fingerprint != fingerprint || existing.Endpoint != endpoint {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if existing.StatusCode == 0 {
+			return nil, ErrIdempotencyKeyInFlight
+		}
+		return &existing, nil
+	}
+	return nil, ErrIdempotencyKeyInFlight
+}
+
+// Complete stores the response for a claimed key.
+func (s *IdempotencyService) Complete(principal, key string, status int, body []byte) error {
+	return s.db.Model(&models.IdempotencyKey{}).
+		Where("principal = ? AND key = ?", principal, key).
+		Updates(map[string]interface{}{
+			"status_code":   status,
+			"response_body": body,
+			"updated_at":    time.Now(),
+		}).Error
+}
+
+// Release drops a claimed key so the request can be retried, e.g. after a server error.
+func (s *IdempotencyService) Release(principal, key string) error {
+	return s.db.Where("principal = ? AND key = ?", principal, key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// PurgeExpired removes keys older than IdempotencyKeyTTL.
+func (s *IdempotencyService) PurgeExpired() error {
+	return s.db.Where("created_at < ?", time.Now().Add(-IdempotencyKeyTTL)).Delete(&models.IdempotencyKey{}).Error
+}
@@ -105,6 +105,7 @@ var allowedPermissions = map[string]struct{}{
 	"deposit":  {},
 	"transfer": {},
 	"read":     {},
+	"withdraw": {},
 }
 
 func validatePermissions(perms []string) error {
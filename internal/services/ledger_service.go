@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LedgerService posts balanced journal entries and keeps wallet balances projected from them.
+type LedgerService struct {
+	db *gorm.DB
+}
+
+// NewLedgerService constructs a LedgerService.
+func NewLedgerService(db *gorm.DB) *LedgerService {
+	return &LedgerService{db: db}
+}
+
+// Posting is a single line of a journal entry before it is written.
+type Posting struct {
+	AccountID string
+	Direction models.EntryDirection
+	Amount    int64
+}
+
+// Debit builds a debit posting against an account.
+func Debit(accountID string, amount int64) Posting {
+	return Posting{AccountID: accountID, Direction: models.EntryDebit, Amount: amount}
+}
+
+// Credit builds a credit posting against an account.
+func Credit(accountID string, amount int64) Posting {
+	return Posting{AccountID: accountID, Direction: models.EntryCredit, Amount: amount}
+}
+
+// Post writes a balanced journal entry inside tx and applies it to cached account and wallet balances.
+func (l *LedgerService) Post(tx *gorm.DB, reference, description string, postings ...Posting) (*models.JournalEntry, error) {
+	return l.post(tx, reference, description, true, postings)
+}
+
+func (l *LedgerService) post(tx *gorm.DB, reference, description string, project bool, postings []Posting) (*models.JournalEntry, error) {
+	if len(postings) < 2 {
+		return nil, errors.New("journal entry needs at least two lines")
+	}
+	var debits, credits int64
+	for _, p := range postings {
+		if p.Amount <= 0 {
+			return nil, errors.New("ledger amounts must be greater than zero")
+		}
+		switch p.Direction {
+		case models.EntryDebit:
+			debits += p.Amount
+		case models.EntryCredit:
+			credits += p.Amount
+		default:
+			return nil, fmt.Errorf("invalid entry direction %q", p.Direction)
+		}
+	}
+	if debits != credits {
+		return nil, fmt.Errorf("unbalanced journal entry: debits %d, credits %d", debits, credits)
+	}
+
+	now := time.Now()
+	entry := models.JournalEntry{
+		ID:          util.MustUUID(),
+		Reference:   reference,
+		Description: description,
+		CreatedAt:   now,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range postings {
+		line := models.LedgerLine{
+			ID:             util.MustUUID(),
+			JournalEntryID: entry.ID,
+			AccountID:      p.AccountID,
+			Direction:      p.Direction,
+			Amount:         p.Amount,
+			CreatedAt:      now,
+		}
+		if err := tx.Create(&line).Error; err != nil {
+			return nil, err
+		}
+		if err := l.apply(tx, p, project, now); err != nil {
+			return nil, err
+		}
+		entry.Lines = append(entry.Lines, line)
+	}
+	return &entry, nil
+}
+
+// apply moves the cached balance of the account and, when project is set, the wallet projection.
+func (l *LedgerService) apply(tx *gorm.DB, p Posting, project bool, now time.Time) error {
+	delta := p.Amount
+	if p.Direction == models.EntryDebit {
+		delta = -delta
+	}
+	var account models.LedgerAccount
+	if err := tx.Clauses(LockClause).First(&account, "id = ?", p.AccountID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("ledger account not found")
+		}
+		return err
+	}
+	if err := tx.Model(&account).Updates(map[string]interface{}{
+		"balance":    gorm.Expr("balance + ?", delta),
+		"updated_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	if !project || account.WalletID == nil {
+		return nil
+	}
+	return tx.Model(&models.Wallet{}).Where("id = ?", *account.WalletID).Updates(map[string]interface{}{
+		"balance":    gorm.Expr("balance + ?", delta),
+		"updated_at": now,
+	}).Error
+}
+
+// WalletAccount returns the ledger account backing a wallet, creating it on first use.
+func (l *LedgerService) WalletAccount(tx *gorm.DB, walletID string) (*models.LedgerAccount, error) {
+	id := walletID
+	return l.ensureAccount(tx, models.LedgerAccount{
+		Code:     fmt.Sprintf("wallet:%s", walletID),
+		Kind:     models.LedgerAccountWallet,
+		WalletID: &id,
+	})
+}
+
+// SystemAccount returns a platform-owned ledger account by code, creating it on first use.
+func (l *LedgerService) SystemAccount(tx *gorm.DB, code string) (*models.LedgerAccount, error) {
+	return l.ensureAccount(tx, models.LedgerAccount{
+		Code: code,
+		Kind: models.LedgerAccountSystem,
+	})
+}
+
+func (l *LedgerService) ensureAccount(tx *gorm.DB, account models.LedgerAccount) (*models.LedgerAccount, error) {
+	var existing models.LedgerAccount
+	err := tx.First(&existing, "code = ?", account.Code).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	now := time.Now()
+	account.ID = util.MustUUID()
+	account.CreatedAt = now
+	account.UpdatedAt = now
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.First(&existing, "code = ?", account.Code).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// AccountBalanceFromLines recomputes an account's balance (credits minus debits) from its lines.
+func (l *LedgerService) AccountBalanceFromLines(accountID string) (int64, error) {
+	var sums struct {
+		Credits int64
+		Debits  int64
+	}
+	err := l.db.Model(&models.LedgerLine{}).
+		Select("COALESCE(SUM(CASE WHEN direction = ? THEN amount ELSE 0 END), 0) AS credits, "+
+			"COALESCE(SUM(CASE WHEN direction = ? THEN amount ELSE 0 END), 0) AS debits",
+			models.EntryCredit, models.EntryDebit).
+		Where("account_id = ?", accountID).
+		Scan(&sums).Error
+	if err != nil {
+		return 0, err
+	}
+	return sums.Credits - sums.Debits, nil
+}
+
+// TrialBalance totals every debit and credit in the ledger; the two must always match.
+func (l *LedgerService) TrialBalance() (debits int64, credits int64, err error) {
+	var sums struct {
+		Credits int64
+		Debits  int64
+	}
+	err = l.db.Model(&models.LedgerLine{}).
+		Select("COALESCE(SUM(CASE WHEN direction = ? THEN amount ELSE 0 END), 0) AS credits, "+
+			"COALESCE(SUM(CASE WHEN direction = ? THEN amount ELSE 0 END), 0) AS debits",
+			models.EntryCredit, models.EntryDebit).
+		Scan(&sums).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return sums.Debits, sums.Credits, nil
+}
+
+// BackfillOpeningBalances gives wallets created before the ledger existed an opening entry
+// so their cached balance is explained by the ledger. Wallet balances are left untouched.
+func (l *LedgerService) BackfillOpeningBalances() error {
+	var wallets []models.Wallet
+	if err := l.db.Where("balance <> 0 AND id NOT IN (?)",
+		l.db.Model(&models.LedgerAccount{}).Select("wallet_id").Where("wallet_id IS NOT NULL"),
+	).Find(&wallets).Error; err != nil {
+		return err
+	}
+	for _, w := range wallets {
+		wallet := w
+		if err := l.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(LockClause).First(&wallet, "id = ?", wallet.ID).Error; err != nil {
+				return err
+			}
+			opening, err := l.SystemAccount(tx, models.SystemAccountOpeningBalances)
+			if err != nil {
+				return err
+			}
+			account, err := l.WalletAccount(tx, wallet.ID)
+			if err != nil {
+				return err
+			}
+			debit, credit := Debit(opening.ID, wallet.Balance), Credit(account.ID, wallet.Balance)
+			if wallet.Balance < 0 {
+				debit, credit = Debit(account.ID, -wallet.Balance), Credit(opening.ID, -wallet.Balance)
+			}
+			// The wallet already holds this balance, so the entry must not be projected onto it again.
+			_, err = l.post(tx, fmt.Sprintf("OPEN-%s", wallet.ID), "opening balance", false, []Posting{debit, credit})
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,27 +1,41 @@
 package services
 
 import (
-	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 )
 
-// PaystackService wraps Paystack HTTP calls.
+// PaystackService wraps Paystack HTTP calls. It is a PaymentGateway and also
+// handles bank payouts, which only Paystack provides.
 type PaystackService struct {
-	secretKey string
-	baseURL   string
-	client    *http.Client
+	secretKey      string
+	client         *gatewayClient
+	webhookSecrets []WebhookSecret
+}
+
+// WebhookSecret is a key accepted for webhook signatures. A zero NotAfter never expires.
+type WebhookSecret struct {
+	Key      string
+	NotAfter time.Time
 }
 
 type paystackInitRequest struct {
-	Amount    int64  `json:"amount"`
-	Email     string `json:"email"`
-	Reference string `json:"reference"`
+	Amount      int64             `json:"amount"`
+	Currency    string            `json:"currency"`
+	Email       string            `json:"email"`
+	Reference   string            `json:"reference"`
+	CallbackURL string            `json:"callback_url,omitempty"`
+	Channels    []string          `json:"channels,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
 }
 
 type paystackInitResponse struct {
@@ -34,59 +48,445 @@ type paystackInitResponse struct {
 	} `json:"data"`
 }
 
+var _ PaymentGateway = (*PaystackService)(nil)
+
 // NewPaystackService constructs a PaystackService.
 func NewPaystackService(secretKey, baseURL string) *PaystackService {
 	return &PaystackService{
 		secretKey: secretKey,
-		// Default base URL is production API.
-		baseURL: baseURL,
-		client:  &http.Client{Timeout: 15 * time.Second},
+		client:    newGatewayClient(ProviderPaystack, baseURL, secretKey),
 	}
 }
 
-// InitializeTransaction requests a Paystack checkout URL.
-func (p *PaystackService) InitializeTransaction(amount int64, email, reference string) (string, error) {
+// SetRetryPolicy replaces DefaultRetryPolicy for calls to Paystack.
+func (p *PaystackService) SetRetryPolicy(policy RetryPolicy) {
+	p.client.policy = policy
+}
+
+// Name implements PaymentGateway.
+func (p *PaystackService) Name() string { return ProviderPaystack }
+
+// InitializeTransaction requests a Paystack checkout URL for amount in the
+// currency's smallest unit. A customer who cancels checkout is also sent to the
+// callback URL, through Paystack's cancel_action metadata. Reserved keys in
+// opts.Metadata are dropped.
+func (p *PaystackService) InitializeTransaction(ctx context.Context, amount int64, currency, email, reference string, opts CheckoutOptions) (string, error) {
 	reqBody := paystackInitRequest{
-		Amount:    amount,
-		Email:     email,
-		Reference: reference,
+		Amount:      amount,
+		Currency:    currency,
+		Email:       email,
+		Reference:   reference,
+		CallbackURL: opts.CallbackURL,
+		Channels:    opts.Channels,
 	}
-	body, err := json.Marshal(reqBody)
-	if err != nil {
+	if len(opts.Metadata) > 0 || opts.CallbackURL != "" {
+		reqBody.Metadata = map[string]string{}
+		for key, value := range opts.Metadata {
+			if !slices.Contains(ReservedMetadataKeys, key) {
+				reqBody.Metadata[key] = value
+			}
+		}
+		// Paystack appends the reference to callback_url but not to cancel_action.
+		if cancel, err := url.Parse(opts.CallbackURL); err == nil && opts.CallbackURL != "" {
+			query := cancel.Query()
+			query.Set("reference", reference)
+			cancel.RawQuery = query.Encode()
+			reqBody.Metadata["cancel_action"] = cancel.String()
+		}
+	}
+	var parsed paystackInitResponse
+	if err := p.client.do(ctx, http.MethodPost, "/transaction/initialize", reqBody, &parsed); err != nil {
 		return "", err
 	}
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/transaction/initialize", p.baseURL), bytes.NewReader(body))
-	if err != nil {
+	if !parsed.Status || parsed.Data.AuthorizationURL == "" {
+		return "", p.client.rejected("init failed: " + parsed.Message)
+	}
+	return parsed.Data.AuthorizationURL, nil
+}
+
+// VerifyTransaction fetches the authoritative state of a charge from Paystack.
+func (p *PaystackService) VerifyTransaction(ctx context.Context, reference string) (*PaymentVerification, error) {
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			Status    string `json:"status"`
+			Reference string `json:"reference"`
+			Amount    int64  `json:"amount"`
+			Currency  string `json:"currency"`
+			Customer  struct {
+				Email string `json:"email"`
+			} `json:"customer"`
+			Authorization struct {
+				AuthorizationCode string `json:"authorization_code"`
+				Signature         string `json:"signature"`
+				Channel           string `json:"channel"`
+				CardType          string `json:"card_type"`
+				Last4             string `json:"last4"`
+				Bank              string `json:"bank"`
+				ExpMonth          string `json:"exp_month"`
+				ExpYear           string `json:"exp_year"`
+				Reusable          bool   `json:"reusable"`
+			} `json:"authorization"`
+		} `json:"data"`
+	}
+	if err := p.client.do(ctx, http.MethodGet, "/transaction/verify/"+url.PathEscape(reference), nil, &parsed); err != nil {
+		return nil, err
+	}
+	if !parsed.Status {
+		return nil, p.client.rejected("verification failed: " + parsed.Message)
+	}
+	verified := &PaymentVerification{
+		Status:        parsed.Data.Status,
+		Reference:     parsed.Data.Reference,
+		Amount:        parsed.Data.Amount,
+		Currency:      parsed.Data.Currency,
+		CustomerEmail: parsed.Data.Customer.Email,
+	}
+	if auth := parsed.Data.Authorization; auth.Reusable && auth.Channel == "card" && auth.AuthorizationCode != "" {
+		verified.Card = &CardAuthorization{
+			Code:      auth.AuthorizationCode,
+			Signature: auth.Signature,
+			Brand:     strings.TrimSpace(auth.CardType),
+			Last4:     auth.Last4,
+			Bank:      auth.Bank,
+			ExpMonth:  auth.ExpMonth,
+			ExpYear:   auth.ExpYear,
+		}
+	}
+	return verified, nil
+}
+
+// ChargeAuthorization charges a saved card authorization for amount without the
+// customer present and returns Paystack's charge status. The charge is settled
+// like any other deposit, through verification or the charge.success webhook.
+func (p *PaystackService) ChargeAuthorization(ctx context.Context, authorizationCode, email string, amount int64, currency, reference string) (string, error) {
+	reqBody := map[string]interface{}{
+		"authorization_code": authorizationCode,
+		"email":              email,
+		"amount":             amount,
+		"currency":           currency,
+		"reference":          reference,
+	}
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := p.client.do(ctx, http.MethodPost, "/transaction/charge_authorization", reqBody, &parsed); err != nil {
 		return "", err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.secretKey))
-	resp, err := p.client.Do(req)
-	if err != nil {
+	if !parsed.Status {
+		return "", p.client.rejected("charge failed: " + parsed.Message)
+	}
+	return parsed.Data.Status, nil
+}
+
+// DeactivateAuthorization stops a saved card authorization from being charged again.
+func (p *PaystackService) DeactivateAuthorization(ctx context.Context, authorizationCode string) error {
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+	}
+	reqBody := map[string]string{"authorization_code": authorizationCode}
+	if err := p.client.do(ctx, http.MethodPost, "/customer/deactivate_authorization", reqBody, &parsed); err != nil {
+		return err
+	}
+	if !parsed.Status {
+		return p.client.rejected("deactivation failed: " + parsed.Message)
+	}
+	return nil
+}
+
+// ResolveAccount looks up the account holder's name for a bank account.
+func (p *PaystackService) ResolveAccount(ctx context.Context, accountNumber, bankCode string) (string, error) {
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			AccountName string `json:"account_name"`
+		} `json:"data"`
+	}
+	q := url.Values{"account_number": {accountNumber}, "bank_code": {bankCode}}
+	if err := p.client.do(ctx, http.MethodGet, "/bank/resolve?"+q.Encode(), nil, &parsed); err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-	var parsed paystackInitResponse
-	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+	if !parsed.Status {
+		return "", p.client.rejected("account resolution failed: " + parsed.Message)
+	}
+	return parsed.Data.AccountName, nil
+}
+
+// DedicatedAccount is a Paystack dedicated virtual account assigned to a customer.
+type DedicatedAccount struct {
+	AccountNumber string
+	AccountName   string
+	BankName      string
+	Currency      string
+}
+
+// CreateCustomer registers a Paystack customer and returns its customer code.
+// Paystack returns the existing customer when the email is already registered.
+func (p *PaystackService) CreateCustomer(ctx context.Context, email, firstName, lastName string) (string, error) {
+	reqBody := map[string]string{
+		"email":      email,
+		"first_name": firstName,
+		"last_name":  lastName,
+	}
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			CustomerCode string `json:"customer_code"`
+		} `json:"data"`
+	}
+	if err := p.client.do(ctx, http.MethodPost, "/customer", reqBody, &parsed); err != nil {
 		return "", err
 	}
+	if !parsed.Status || parsed.Data.CustomerCode == "" {
+		return "", p.client.rejected("customer creation failed: " + parsed.Message)
+	}
+	return parsed.Data.CustomerCode, nil
+}
+
+// CreateDedicatedAccount assigns a dedicated virtual account at preferredBank (a
+// Paystack bank slug such as wema-bank) to a customer. An empty preferredBank
+// leaves the choice to Paystack.
+func (p *PaystackService) CreateDedicatedAccount(ctx context.Context, customerCode, preferredBank string) (*DedicatedAccount, error) {
+	reqBody := map[string]string{"customer": customerCode}
+	if preferredBank != "" {
+		reqBody["preferred_bank"] = preferredBank
+	}
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			AccountNumber string `json:"account_number"`
+			AccountName   string `json:"account_name"`
+			Currency      string `json:"currency"`
+			Bank          struct {
+				Name string `json:"name"`
+			} `json:"bank"`
+		} `json:"data"`
+	}
+	if err := p.client.do(ctx, http.MethodPost, "/dedicated_account", reqBody, &parsed); err != nil {
+		return nil, err
+	}
+	if !parsed.Status || parsed.Data.AccountNumber == "" {
+		return nil, p.client.rejected("dedicated account creation failed: " + parsed.Message)
+	}
+	return &DedicatedAccount{
+		AccountNumber: parsed.Data.AccountNumber,
+		AccountName:   parsed.Data.AccountName,
+		BankName:      parsed.Data.Bank.Name,
+		Currency:      parsed.Data.Currency,
+	}, nil
+}
+
+// recipientTypes maps a currency to the Paystack transfer recipient type for bank accounts.
+var recipientTypes = map[string]string{
+	"NGN": "nuban",
+	"GHS": "ghipss",
+	"KES": "kepss",
+	"ZAR": "basa",
+}
+
+// CreateTransferRecipient registers a bank account for payouts and returns its recipient code.
+func (p *PaystackService) CreateTransferRecipient(ctx context.Context, name, accountNumber, bankCode, currency string) (string, error) {
+	recipientType, ok := recipientTypes[currency]
+	if !ok {
+		return "", fmt.Errorf("bank payouts are not supported in %s", currency)
+	}
+	reqBody := map[string]string{
+		"type":           recipientType,
+		"name":           name,
+		"account_number": accountNumber,
+		"bank_code":      bankCode,
+		"currency":       currency,
+	}
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			RecipientCode string `json:"recipient_code"`
+		} `json:"data"`
+	}
+	if err := p.client.do(ctx, http.MethodPost, "/transferrecipient", reqBody, &parsed); err != nil {
+		return "", err
+	}
+	if !parsed.Status || parsed.Data.RecipientCode == "" {
+		return "", p.client.rejected("recipient creation failed: " + parsed.Message)
+	}
+	return parsed.Data.RecipientCode, nil
+}
+
+// InitiateTransfer starts a payout from the Paystack balance to a recipient and
+// returns Paystack's transfer code and the transfer's status, e.g. pending, or otp
+// when the integration requires OTP confirmation. The outcome arrives later as a
+// transfer.* webhook.
+func (p *PaystackService) InitiateTransfer(ctx context.Context, amount int64, currency, recipientCode, reference, reason string) (string, string, error) {
+	reqBody := map[string]interface{}{
+		"source":    "balance",
+		"amount":    amount,
+		"currency":  currency,
+		"recipient": recipientCode,
+		"reference": reference,
+		"reason":    reason,
+	}
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			TransferCode string `json:"transfer_code"`
+			Status       string `json:"status"`
+		} `json:"data"`
+	}
+	if err := p.client.do(ctx, http.MethodPost, "/transfer", reqBody, &parsed); err != nil {
+		return "", "", err
+	}
 	if !parsed.Status {
-		return "", fmt.Errorf("paystack init failed: %s", parsed.Message)
+		return "", "", p.client.rejected("transfer failed: " + parsed.Message)
 	}
-	return parsed.Data.AuthorizationURL, nil
+	return parsed.Data.TransferCode, parsed.Data.Status, nil
+}
+
+// VerifyTransfer returns the status Paystack reports for the transfer with
+// reference: pending, success, failed, reversed and so on. A reference Paystack
+// never received fails with a GatewayValidation error with StatusCode 404.
+func (p *PaystackService) VerifyTransfer(ctx context.Context, reference string) (string, error) {
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := p.client.do(ctx, http.MethodGet, "/transfer/verify/"+url.PathEscape(reference), nil, &parsed); err != nil {
+		return "", err
+	}
+	if !parsed.Status {
+		return "", p.client.rejected("transfer verification failed: " + parsed.Message)
+	}
+	return parsed.Data.Status, nil
 }
 
-// VerifySignature checks the Paystack webhook signature using the secret key.
+// CreateRefund asks Paystack to return amount of a successful charge to the
+// customer and returns Paystack's refund id. The outcome arrives later as a
+// refund.processed or refund.failed webhook.
+func (p *PaystackService) CreateRefund(ctx context.Context, transactionReference string, amount int64, currency, note string) (string, error) {
+	reqBody := map[string]interface{}{
+		"transaction":   transactionReference,
+		"amount":        amount,
+		"currency":      currency,
+		"merchant_note": note,
+	}
+	var parsed struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			ID json.RawMessage `json:"id"`
+		} `json:"data"`
+	}
+	if err := p.client.do(ctx, http.MethodPost, "/refund", reqBody, &parsed); err != nil {
+		return "", err
+	}
+	if !parsed.Status {
+		return "", p.client.rejected("refund failed: " + parsed.Message)
+	}
+	return rawID(parsed.Data.ID), nil
+}
+
+// rawID renders a JSON id Paystack may send as a number or a string.
+func rawID(raw json.RawMessage) string {
+	id := strings.Trim(string(raw), `"`)
+	if id == "null" {
+		return ""
+	}
+	return id
+}
+
+// SetWebhookSecrets replaces the keys accepted by VerifySignature. With none set,
+// signatures are checked against the API secret key, which is what Paystack signs with.
+func (p *PaystackService) SetWebhookSecrets(secrets []WebhookSecret) {
+	p.webhookSecrets = nil
+	for _, secret := range secrets {
+		if secret.Key != "" {
+			p.webhookSecrets = append(p.webhookSecrets, secret)
+		}
+	}
+}
+
+// VerifySignature checks the Paystack webhook signature against every unexpired webhook secret.
 func (p *PaystackService) VerifySignature(body []byte, signature string) bool {
 	if signature == "" {
 		return false
 	}
-	mac := hmac.New(sha512.New, []byte(p.secretKey))
-	mac.Write(body)
-	expected := mac.Sum(nil)
 	decoded, err := hex.DecodeString(signature)
 	if err != nil {
 		return false
 	}
-	return hmac.Equal(decoded, expected)
+	secrets := p.webhookSecrets
+	if len(secrets) == 0 {
+		secrets = []WebhookSecret{{Key: p.secretKey}}
+	}
+	now := time.Now()
+	for _, secret := range secrets {
+		if !secret.NotAfter.IsZero() && now.After(secret.NotAfter) {
+			continue
+		}
+		mac := hmac.New(sha512.New, []byte(secret.Key))
+		mac.Write(body)
+		if hmac.Equal(decoded, mac.Sum(nil)) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyWebhook implements PaymentGateway using the x-paystack-signature header.
+func (p *PaystackService) VerifyWebhook(header http.Header, body []byte) bool {
+	return p.VerifySignature(body, header.Get("x-paystack-signature"))
+}
+
+// ParseWebhook implements PaymentGateway. Paystack redelivers an event with the
+// same data.id, so event name and id form the dedup key. Bank transfers into a
+// dedicated virtual account arrive as charge.success on the dedicated_nuban channel.
+// Refund events identify the refunded charge by transaction_reference.
+func (p *PaystackService) ParseWebhook(body []byte) (*WebhookNotification, error) {
+	var parsed struct {
+		Event string `json:"event"`
+		Data  struct {
+			ID                   json.RawMessage `json:"id"`
+			Status               string          `json:"status"`
+			Reference            string          `json:"reference"`
+			TransactionReference string          `json:"transaction_reference"`
+			Amount               int64           `json:"amount"`
+			Currency             string          `json:"currency"`
+			Channel              string          `json:"channel"`
+			Authorization        struct {
+				ReceiverAccountNumber string `json:"receiver_bank_account_number"`
+			} `json:"authorization"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+	n := &WebhookNotification{Type: parsed.Event, Reference: parsed.Data.Reference}
+	if id := rawID(parsed.Data.ID); id != "" && parsed.Event != "" {
+		n.ID = parsed.Event + ":" + id
+	}
+	switch {
+	case parsed.Event == "charge.success":
+		n.ChargeStatus = parsed.Data.Status
+		if parsed.Data.Channel == "dedicated_nuban" {
+			n.VirtualAccount = parsed.Data.Authorization.ReceiverAccountNumber
+		}
+	case strings.HasPrefix(parsed.Event, "refund."):
+		n.Reference = parsed.Data.TransactionReference
+		n.RefundID = rawID(parsed.Data.ID)
+		n.Amount, n.Currency = parsed.Data.Amount, parsed.Data.Currency
+	}
+	return n, nil
 }
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Paystack listings are read this many rows at a time, up to paystackMaxPages pages.
+const (
+	paystackPageSize = 100
+	paystackMaxPages = 200
+)
+
+// PaystackCharge is one transaction from a Paystack listing or dashboard export.
+// Amount is in the currency's smallest unit.
+type PaystackCharge struct {
+	Reference string
+	Status    string
+	Amount    int64
+	Currency  string
+	Email     string
+	Channel   string
+	PaidAt    *time.Time
+	// SettlementID is the payout that included the charge, if it has been settled.
+	SettlementID string
+}
+
+// PaystackSettlement is a payout of collected charges from Paystack to our bank account.
+type PaystackSettlement struct {
+	ID          string
+	Status      string
+	Currency    string
+	TotalAmount int64
+	TotalFees   int64
+	SettledAt   *time.Time
+}
+
+type paystackListedCharge struct {
+	Status    string     `json:"status"`
+	Reference string     `json:"reference"`
+	Amount    int64      `json:"amount"`
+	Currency  string     `json:"currency"`
+	Channel   string     `json:"channel"`
+	PaidAt    *time.Time `json:"paid_at"`
+	Customer  struct {
+		Email string `json:"email"`
+	} `json:"customer"`
+}
+
+func (c paystackListedCharge) charge() PaystackCharge {
+	return PaystackCharge{
+		Reference: c.Reference,
+		Status:    c.Status,
+		Amount:    c.Amount,
+		Currency:  c.Currency,
+		Email:     c.Customer.Email,
+		Channel:   c.Channel,
+		PaidAt:    c.PaidAt,
+	}
+}
+
+type paystackPageMeta struct {
+	PageCount int `json:"pageCount"`
+}
+
+// ListTransactions returns every transaction Paystack created between from and to.
+func (p *PaystackService) ListTransactions(ctx context.Context, from, to time.Time) ([]PaystackCharge, error) {
+	var charges []PaystackCharge
+	err := p.listPages(ctx, "/transaction", listingQuery(from, to), func(raw json.RawMessage) error {
+		var rows []paystackListedCharge
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			charges = append(charges, row.charge())
+		}
+		return nil
+	})
+	return charges, err
+}
+
+// ListSettlements returns the settlements Paystack paid out between from and to.
+func (p *PaystackService) ListSettlements(ctx context.Context, from, to time.Time) ([]PaystackSettlement, error) {
+	var settlements []PaystackSettlement
+	err := p.listPages(ctx, "/settlement", listingQuery(from, to), func(raw json.RawMessage) error {
+		var rows []struct {
+			ID             json.RawMessage `json:"id"`
+			Status         string          `json:"status"`
+			Currency       string          `json:"currency"`
+			TotalAmount    int64           `json:"total_amount"`
+			TotalFees      int64           `json:"total_fees"`
+			SettlementDate *time.Time      `json:"settlement_date"`
+		}
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			settlements = append(settlements, PaystackSettlement{
+				ID:          rawID(row.ID),
+				Status:      row.Status,
+				Currency:    row.Currency,
+				TotalAmount: row.TotalAmount,
+				TotalFees:   row.TotalFees,
+				SettledAt:   row.SettlementDate,
+			})
+		}
+		return nil
+	})
+	return settlements, err
+}
+
+// SettlementTransactions returns the charges paid out in a settlement.
+func (p *PaystackService) SettlementTransactions(ctx context.Context, settlementID string) ([]PaystackCharge, error) {
+	var charges []PaystackCharge
+	err := p.listPages(ctx, "/settlement/"+url.PathEscape(settlementID)+"/transactions", url.Values{}, func(raw json.RawMessage) error {
+		var rows []paystackListedCharge
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			charge := row.charge()
+			charge.SettlementID = settlementID
+			charges = append(charges, charge)
+		}
+		return nil
+	})
+	return charges, err
+}
+
+// PaystackRefund is a refund Paystack holds for a charge. Amount is in the
+// currency's smallest unit.
+type PaystackRefund struct {
+	ID       string
+	Status   string
+	Amount   int64
+	Currency string
+}
+
+// ListRefunds returns the refunds Paystack holds for the charge with reference.
+func (p *PaystackService) ListRefunds(ctx context.Context, reference string) ([]PaystackRefund, error) {
+	var refunds []PaystackRefund
+	err := p.listPages(ctx, "/refund", url.Values{"transaction": {reference}}, func(raw json.RawMessage) error {
+		var rows []struct {
+			ID       json.RawMessage `json:"id"`
+			Status   string          `json:"status"`
+			Amount   int64           `json:"amount"`
+			Currency string          `json:"currency"`
+		}
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			refunds = append(refunds, PaystackRefund{ID: rawID(row.ID), Status: row.Status, Amount: row.Amount, Currency: row.Currency})
+		}
+		return nil
+	})
+	return refunds, err
+}
+
+// listPages walks a paginated Paystack listing, handing each page's data to add.
+func (p *PaystackService) listPages(ctx context.Context, path string, query url.Values, add func(json.RawMessage) error) error {
+	query.Set("perPage", strconv.Itoa(paystackPageSize))
+	for page := 1; ; page++ {
+		if page > paystackMaxPages {
+			return fmt.Errorf("paystack listing %s exceeds %d pages; narrow the date range", path, paystackMaxPages)
+		}
+		query.Set("page", strconv.Itoa(page))
+		var parsed struct {
+			Status  bool             `json:"status"`
+			Message string           `json:"message"`
+			Data    json.RawMessage  `json:"data"`
+			Meta    paystackPageMeta `json:"meta"`
+		}
+		if err := p.client.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &parsed); err != nil {
+			return err
+		}
+		if !parsed.Status {
+			return p.client.rejected("listing failed: " + parsed.Message)
+		}
+		if err := add(parsed.Data); err != nil {
+			return fmt.Errorf("paystack listing %s: %w", path, err)
+		}
+		if page >= parsed.Meta.PageCount {
+			return nil
+		}
+	}
+}
+
+func listingQuery(from, to time.Time) url.Values {
+	query := url.Values{}
+	if !from.IsZero() {
+		query.Set("from", from.UTC().Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.UTC().Format(time.RFC3339))
+	}
+	return query
+}
+
+// ParsePaystackAmount converts an amount in major units, as Paystack's dashboard
+// exports show them (e.g. "5,000.50"), to the currency's smallest unit.
+func ParsePaystackAmount(value string) (int64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	whole, frac, _ := strings.Cut(value, ".")
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || strings.HasPrefix(whole, "-") {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	minor, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return major*100 + minor, nil
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// Reconciliation triggers.
+const (
+	ReconciliationTriggerSchedule = "schedule"
+	ReconciliationTriggerManual   = "manual"
+)
+
+// signedAmountSQL turns a transaction row into its effect on the wallet balance.
+// Rows written before Direction existed fall back to the old transfer descriptions.
+const signedAmountSQL = "CASE WHEN direction = 'debit' OR (COALESCE(direction, '') = '' AND description = 'debit transfer') THEN -amount ELSE amount END"
+
+// BalanceMismatch describes a wallet whose stored balance disagrees with its history.
+type BalanceMismatch struct {
+	WalletID        string   `json:"wallet_id"`
+	WalletNumber    string   `json:"wallet_number"`
+	StoredBalance   int64    `json:"stored_balance"`
+	ComputedBalance int64    `json:"computed_balance"`
+	LedgerBalance   int64    `json:"ledger_balance"`
+	Drift           int64    `json:"drift"`
+	References      []string `json:"references"`
+}
+
+// ReconciliationReport is the outcome of a reconciliation run.
+type ReconciliationReport struct {
+	RunID          string            `json:"run_id"`
+	Trigger        string            `json:"trigger"`
+	StartedAt      time.Time         `json:"started_at"`
+	FinishedAt     time.Time         `json:"finished_at"`
+	WalletsChecked int               `json:"wallets_checked"`
+	Mismatches     []BalanceMismatch `json:"mismatches"`
+}
+
+// ReconciliationService recomputes wallet balances from transaction history and reports drift.
+type ReconciliationService struct {
+	db *gorm.DB
+}
+
+// NewReconciliationService constructs a ReconciliationService.
+func NewReconciliationService(db *gorm.DB) *ReconciliationService {
+	return &ReconciliationService{db: db}
+}
+
+// reconciliationTxOptions makes every read in a run see one snapshot, so
+// transfers committing mid-run cannot show up as drift.
+var reconciliationTxOptions = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+// Run checks every wallet, stores the report, and returns it.
+func (s *ReconciliationService) Run(trigger string) (*ReconciliationReport, error) {
+	report := ReconciliationReport{
+		RunID:      util.MustUUID(),
+		Trigger:    trigger,
+		StartedAt:  time.Now(),
+		Mismatches: []BalanceMismatch{},
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.check(tx, &report)
+	}, reconciliationTxOptions)
+	if err != nil {
+		return nil, err
+	}
+	report.FinishedAt = time.Now()
+
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	run := models.ReconciliationRun{
+		ID:             report.RunID,
+		Trigger:        trigger,
+		WalletsChecked: report.WalletsChecked,
+		MismatchCount:  len(report.Mismatches),
+		Report:         raw,
+		StartedAt:      report.StartedAt,
+		FinishedAt:     report.FinishedAt,
+	}
+	if err := s.db.Create(&run).Error; err != nil {
+		return nil, err
+	}
+	if len(report.Mismatches) > 0 {
+		log.Printf("reconciliation %s: %d of %d wallets drifted", report.RunID, len(report.Mismatches), report.WalletsChecked)
+	}
+	return &report, nil
+}
+
+// check compares every wallet's stored balance with its history, reading through db.
+func (s *ReconciliationService) check(db *gorm.DB, report *ReconciliationReport) error {
+	var wallets []models.Wallet
+	return db.Order("id").FindInBatches(&wallets, 500, func(_ *gorm.DB, _ int) error {
+		ids := make([]string, 0, len(wallets))
+		for _, w := range wallets {
+			ids = append(ids, w.ID)
+		}
+		computed, err := s.computedBalances(db, ids)
+		if err != nil {
+			return err
+		}
+		ledger, err := s.ledgerBalances(db, ids)
+		if err != nil {
+			return err
+		}
+		for _, w := range wallets {
+			report.WalletsChecked++
+			if w.Balance == computed[w.ID] {
+				continue
+			}
+			refs, err := s.offendingReferences(db, w.ID)
+			if err != nil {
+				return err
+			}
+			report.Mismatches = append(report.Mismatches, BalanceMismatch{
+				WalletID:        w.ID,
+				WalletNumber:    w.Number,
+				StoredBalance:   w.Balance,
+				ComputedBalance: computed[w.ID],
+				LedgerBalance:   ledger[w.ID],
+				Drift:           w.Balance - computed[w.ID],
+				References:      refs,
+			})
+		}
+		return nil
+	}).Error
+}
+
+// Runs lists the most recent reconciliation runs without their full reports.
+func (s *ReconciliationService) Runs(limit int) ([]models.ReconciliationRun, error) {
+	var runs []models.ReconciliationRun
+	if err := s.db.Omit("report").Order("started_at desc").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Report loads the stored report for a run.
+func (s *ReconciliationService) Report(runID string) (*ReconciliationReport, error) {
+	var run models.ReconciliationRun
+	if err := s.db.First(&run, "id = ?", runID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("reconciliation run not found")
+		}
+		return nil, err
+	}
+	var report ReconciliationReport
+	if err := json.Unmarshal(run.Report, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// balanceStatuses are the row states whose money movement has been applied.
+// Reversed legs still moved money; their compensating reversal rows undo it.
+var balanceStatuses = []models.TransactionStatus{models.TransactionSuccess, models.TransactionReversed}
+
+// computedBalances sums settled deposit, transfer, reversal, withdrawal and refund rows per wallet.
+func (s *ReconciliationService) computedBalances(db *gorm.DB, walletIDs []string) (map[string]int64, error) {
+	var rows []struct {
+		WalletID string
+		Total    int64
+	}
+	err := db.Model(&models.Transaction{}).
+		Select("wallet_id, COALESCE(SUM("+signedAmountSQL+"), 0) AS total").
+		Where("wallet_id IN ? AND status IN ? AND type IN ?", walletIDs, balanceStatuses,
+			[]models.TransactionType{models.TransactionTypeDeposit, models.TransactionTypeTransfer, models.TransactionTypeReversal, models.TransactionTypeWithdrawal, models.TransactionTypeRefund}).
+		Group("wallet_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		res[r.WalletID] = r.Total
+	}
+	return res, nil
+}
+
+func (s *ReconciliationService) ledgerBalances(db *gorm.DB, walletIDs []string) (map[string]int64, error) {
+	var accounts []models.LedgerAccount
+	if err := db.Where("wallet_id IN ?", walletIDs).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	res := make(map[string]int64, len(accounts))
+	for _, a := range accounts {
+		res[*a.WalletID] = a.Balance
+	}
+	return res, nil
+}
+
+// offendingReferences lists successful rows that never reached the ledger and
+// ledger entries on the wallet that have no matching transaction row.
+func (s *ReconciliationService) offendingReferences(db *gorm.DB, walletID string) ([]string, error) {
+	refs := []string{}
+	var unposted []string
+	if err := db.Model(&models.Transaction{}).
+		Where("wallet_id = ? AND status IN ? AND COALESCE(journal_entry_id, '') = ''", walletID, balanceStatuses).
+		Pluck("reference", &unposted).Error; err != nil {
+		return nil, err
+	}
+	refs = append(refs, unposted...)
+
+	var unrecorded []string
+	if err := db.Model(&models.JournalEntry{}).
+		Joins("JOIN ledger_lines ON ledger_lines.journal_entry_id = journal_entries.id").
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = ledger_lines.account_id").
+		Where("ledger_accounts.wallet_id = ? AND journal_entries.reference NOT LIKE ?", walletID, "OPEN-%").
+		Where("CAST(journal_entries.id AS TEXT) NOT IN (?)",
+			db.Model(&models.Transaction{}).Select("journal_entry_id").Where("wallet_id = ? AND journal_entry_id <> ''", walletID)).
+		Distinct().
+		Pluck("journal_entries.reference", &unrecorded).Error; err != nil {
+		return nil, err
+	}
+	return append(refs, unrecorded...), nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// scheduleBatchSize bounds how many due standing orders one executor pass picks up.
+const scheduleBatchSize = 100
+
+// ScheduleRequest describes a scheduled or recurring transfer.
+type ScheduleRequest struct {
+	DestWalletNumber string
+	Amount           int64
+	Narration        string
+	Frequency        models.ScheduleFrequency
+	StartAt          time.Time
+	EndAt            *time.Time
+	MaxRuns          int
+	// Currency picks the source wallet; empty means the default-currency wallet.
+	Currency string
+}
+
+// ScheduleService manages standing orders and executes them as wallet transfers.
+type ScheduleService struct {
+	db      *gorm.DB
+	wallets *WalletService
+}
+
+// NewScheduleService constructs a ScheduleService.
+func NewScheduleService(db *gorm.DB, wallets *WalletService) *ScheduleService {
+	return &ScheduleService{db: db, wallets: wallets}
+}
+
+// Create validates and stores a standing order for the user's wallet in req.Currency.
+func (s *ScheduleService) Create(user *models.User, req ScheduleRequest) (*models.StandingOrder, error) {
+	if user == nil || user.Wallet.ID == "" {
+		return nil, errors.New("wallet not found for user")
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	wallet, err := s.wallets.userWallet(user, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if req.DestWalletNumber == wallet.Number {
+		return nil, errors.New("cannot transfer to the same wallet")
+	}
+	if len(req.Narration) > MaxNarrationLength {
+		return nil, fmt.Errorf("narration must be at most %d characters", MaxNarrationLength)
+	}
+	switch req.Frequency {
+	case models.FrequencyOnce:
+		req.EndAt, req.MaxRuns = nil, 1
+	case models.FrequencyDaily, models.FrequencyWeekly, models.FrequencyMonthly:
+	default:
+		return nil, errors.New("frequency must be once, daily, weekly or monthly")
+	}
+	if req.StartAt.IsZero() || req.StartAt.Before(time.Now().Add(-time.Minute)) {
+		return nil, errors.New("start time must be in the future")
+	}
+	if req.EndAt != nil && req.EndAt.Before(req.StartAt) {
+		return nil, errors.New("end time must be after start time")
+	}
+	if req.MaxRuns < 0 {
+		return nil, errors.New("max runs must not be negative")
+	}
+	var dest models.Wallet
+	if err := s.db.First(&dest, "number = ?", req.DestWalletNumber).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recipient wallet not found")
+		}
+		return nil, err
+	}
+	if dest.Currency != wallet.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+
+	now := time.Now()
+	order := models.StandingOrder{
+		ID:               util.MustUUID(),
+		UserID:           user.ID,
+		WalletID:         wallet.ID,
+		Currency:         wallet.Currency,
+		DestWalletNumber: req.DestWalletNumber,
+		Amount:           req.Amount,
+		Narration:        req.Narration,
+		Frequency:        req.Frequency,
+		StartAt:          req.StartAt,
+		NextRunAt:        req.StartAt,
+		EndAt:            req.EndAt,
+		MaxRuns:          req.MaxRuns,
+		Status:           models.StandingOrderActive,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.db.Create(&order).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// List returns the user's standing orders, newest first.
+func (s *ScheduleService) List(userID string) ([]models.StandingOrder, error) {
+	var orders []models.StandingOrder
+	if err := s.db.Where("user_id = ?", userID).Order("created_at desc").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Cancel stops an active standing order owned by the user.
+func (s *ScheduleService) Cancel(userID, orderID string) error {
+	res := s.db.Model(&models.StandingOrder{}).
+		Where("id = ? AND user_id = ? AND status = ?", orderID, userID, models.StandingOrderActive).
+		Updates(map[string]interface{}{"status": models.StandingOrderCancelled, "updated_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("active scheduled transfer not found")
+	}
+	return nil
+}
+
+// Runs returns the execution history of a standing order owned by the user.
+func (s *ScheduleService) Runs(userID, orderID string) ([]models.StandingOrderRun, error) {
+	var order models.StandingOrder
+	if err := s.db.First(&order, "id = ? AND user_id = ?", orderID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("scheduled transfer not found")
+		}
+		return nil, err
+	}
+	var runs []models.StandingOrderRun
+	if err := s.db.Where("standing_order_id = ?", order.ID).Order("created_at desc").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// RunDue executes every active standing order whose next run is at or before now.
+func (s *ScheduleService) RunDue(now time.Time) error {
+	var due []models.StandingOrder
+	if err := s.db.Where("status = ? AND next_run_at <= ?", models.StandingOrderActive, now).
+		Order("next_run_at").Limit(scheduleBatchSize).Find(&due).Error; err != nil {
+		return err
+	}
+	for _, order := range due {
+		if err := s.execute(order, now); err != nil {
+			log.Printf("standing order %s: %v", order.ID, err)
+		}
+	}
+	return nil
+}
+
+// execute claims the order's current occurrence, advances the schedule past now, then
+// runs the transfer. Claiming first means a crash can skip a run but never pay it
+// twice. Further occurrences missed while the executor was down are skipped, so a
+// schedule catching up pays once rather than once per missed occurrence.
+func (s *ScheduleService) execute(order models.StandingOrder, now time.Time) error {
+	runCount, skipped := order.RunCount+1, order.SkippedRuns
+	next := nextOccurrence(order.StartAt, order.Frequency, runCount+skipped)
+	for order.Frequency != models.FrequencyOnce && !next.After(now) {
+		skipped++
+		next = nextOccurrence(order.StartAt, order.Frequency, runCount+skipped)
+	}
+	status := models.StandingOrderActive
+	if order.Frequency == models.FrequencyOnce ||
+		(order.MaxRuns > 0 && runCount >= order.MaxRuns) ||
+		(order.EndAt != nil && next.After(*order.EndAt)) {
+		status = models.StandingOrderCompleted
+	}
+	claim := s.db.Model(&models.StandingOrder{}).
+		Where("id = ? AND status = ? AND run_count = ?", order.ID, models.StandingOrderActive, order.RunCount).
+		Updates(map[string]interface{}{
+			"run_count":    runCount,
+			"skipped_runs": skipped,
+			"next_run_at":  next,
+			"status":       status,
+			"updated_at":   time.Now(),
+		})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil // another executor took this occurrence or the order was cancelled
+	}
+
+	run := models.StandingOrderRun{
+		ID:              util.MustUUID(),
+		StandingOrderID: order.ID,
+		ScheduledFor:    order.NextRunAt,
+		CreatedAt:       time.Now(),
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		run.TransferID, err = s.wallets.transferTx(tx, order.WalletID, order.DestWalletNumber, order.Amount, TransferOptions{
+			Narration:       order.Narration,
+			ClientReference: order.ID,
+		})
+		return err
+	})
+	if err != nil {
+		run.Status = models.TransactionFailed
+		run.Error = err.Error()
+		if order.Frequency == models.FrequencyOnce {
+			if uerr := s.db.Model(&models.StandingOrder{}).Where("id = ?", order.ID).
+				Update("status", models.StandingOrderFailed).Error; uerr != nil {
+				return uerr
+			}
+		}
+	} else {
+		run.Status = models.TransactionSuccess
+	}
+	return s.db.Create(&run).Error
+}
+
+// nextOccurrence returns the n-th occurrence (zero-based) after start. Monthly
+// schedules keep the start day, clamped to the last day of shorter months.
+func nextOccurrence(start time.Time, freq models.ScheduleFrequency, n int) time.Time {
+	switch freq {
+	case models.FrequencyDaily:
+		return start.AddDate(0, 0, n)
+	case models.FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case models.FrequencyMonthly:
+		firstOfMonth := time.Date(start.Year(), start.Month(), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		target := firstOfMonth.AddDate(0, n, 0)
+		lastDay := target.AddDate(0, 1, -1).Day()
+		day := start.Day()
+		if day > lastDay {
+			day = lastDay
+		}
+		return target.AddDate(0, 0, day-1)
+	default:
+		return start
+	}
+}
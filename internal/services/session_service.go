@@ -0,0 +1,269 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CyberwizD/Wallet-Service/internal/auth"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// Default token lifetimes; see SetTokenTTLs.
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// sessionRetention is how long expired or revoked sessions are kept for the user's records.
+const sessionRetention = 30 * 24 * time.Hour
+
+// refreshReuseGrace is how long after a rotation the previous refresh token is
+// answered with ErrRefreshTokenRotated instead of revoking the session, so two
+// tabs refreshing at once are not taken for token theft.
+const refreshReuseGrace = 10 * time.Second
+
+// refreshTokenPrefix starts every refresh token, making leaked ones easy to spot.
+const refreshTokenPrefix = "rt_"
+
+var (
+	// ErrInvalidRefreshToken means the refresh token is unknown, expired or revoked.
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused means an already rotated refresh token was presented;
+	// the session is revoked because the token has probably been stolen.
+	ErrRefreshTokenReused = errors.New("refresh token reused; session revoked")
+	// ErrRefreshTokenRotated means the refresh token was rotated by a concurrent
+	// refresh a moment ago; the client should retry with the token that refresh returned.
+	ErrRefreshTokenRotated = errors.New("refresh token was just rotated; retry with the new one")
+	// ErrSessionNotFound means the session does not exist or belongs to another user.
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// SessionTokens is what a sign-in or refresh hands the client.
+type SessionTokens struct {
+	SessionID        string
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// SessionService issues short-lived access tokens backed by server-side sessions
+// with rotating refresh tokens.
+type SessionService struct {
+	db         *gorm.DB
+	secret     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewSessionService constructs a SessionService signing access tokens with secret.
+func NewSessionService(db *gorm.DB, secret string) *SessionService {
+	return &SessionService{db: db, secret: secret, accessTTL: DefaultAccessTokenTTL, refreshTTL: DefaultRefreshTokenTTL}
+}
+
+// SetTokenTTLs replaces the access and refresh token lifetimes; zero keeps the current one.
+// A session lapses when its refresh token goes unused for the refresh lifetime.
+func (s *SessionService) SetTokenTTLs(access, refresh time.Duration) {
+	if access > 0 {
+		s.accessTTL = access
+	}
+	if refresh > 0 {
+		s.refreshTTL = refresh
+	}
+}
+
+// Start opens a session for a user who has just signed in.
+func (s *SessionService) Start(user *models.User, userAgent, ip string) (*SessionTokens, error) {
+	refreshToken := newRefreshToken()
+	now := time.Now()
+	session := models.Session{
+		ID:               util.MustUUID(),
+		UserID:           user.ID,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		UserAgent:        truncate(userAgent, 255),
+		IP:               ip,
+		ExpiresAt:        now.Add(s.refreshTTL),
+		LastUsedAt:       now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return s.tokens(&session, user.Email, refreshToken, now)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token; the presented one stops working. Presenting a token that was already
+// rotated revokes the session, unless it was rotated within refreshReuseGrace:
+// then ErrRefreshTokenRotated is returned and the session is left as it is.
+func (s *SessionService) Refresh(refreshToken, userAgent, ip string) (*SessionTokens, error) {
+	hash := hashRefreshToken(refreshToken)
+	var (
+		session models.Session
+		user    models.User
+		next    = newRefreshToken()
+		now     = time.Now()
+		reused  bool
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(LockClause).First(&session, "refresh_token_hash = ?", hash).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Clauses(LockClause).First(&session, "previous_token_hash = ?", hash).Error; err != nil {
+				return ErrInvalidRefreshToken
+			}
+			if now.Sub(session.LastUsedAt) <= refreshReuseGrace {
+				return ErrRefreshTokenRotated
+			}
+			reused = true
+			return s.revokeTx(tx, &session, "refresh token reused", now)
+		} else if err != nil {
+			return err
+		}
+		if !session.Active(now) {
+			return ErrInvalidRefreshToken
+		}
+		if err := tx.First(&user, "id = ?", session.UserID).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"refresh_token_hash":  hashRefreshToken(next),
+			"previous_token_hash": hash,
+			"expires_at":          now.Add(s.refreshTTL),
+			"last_used_at":        now,
+			"updated_at":          now,
+		}
+		if userAgent != "" {
+			updates["user_agent"] = truncate(userAgent, 255)
+		}
+		if ip != "" {
+			updates["ip"] = ip
+		}
+		if err := tx.Model(&session).Updates(updates).Error; err != nil {
+			return err
+		}
+		session.ExpiresAt = now.Add(s.refreshTTL)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		log.Printf("session %s of user %s: rotated refresh token reused, session revoked", session.ID, session.UserID)
+		return nil, ErrRefreshTokenReused
+	}
+	return s.tokens(&session, user.Email, next, now)
+}
+
+// Logout revokes the session a refresh token belongs to, or with all set every
+// session of its user, and returns how many sessions were revoked. Logging out
+// of a session that is already revoked or expired is not an error.
+func (s *SessionService) Logout(refreshToken string, all bool) (int64, error) {
+	var session models.Session
+	if err := s.db.First(&session, "refresh_token_hash = ?", hashRefreshToken(refreshToken)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrInvalidRefreshToken
+		}
+		return 0, err
+	}
+	if all {
+		return s.RevokeAll(session.UserID, "logout")
+	}
+	return s.revoke(&session, "logout")
+}
+
+// Sessions lists a user's active sessions, most recently used first.
+func (s *SessionService) Sessions(userID string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at desc").Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke ends one of the user's sessions; its access and refresh tokens stop working at once.
+func (s *SessionService) Revoke(userID, sessionID string) error {
+	var session models.Session
+	if err := s.db.First(&session, "id = ? AND user_id = ?", sessionID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	_, err := s.revoke(&session, "revoked by user")
+	return err
+}
+
+// RevokeAll ends every active session of a user and returns how many there were.
+func (s *SessionService) RevokeAll(userID, reason string) (int64, error) {
+	now := time.Now()
+	res := s.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": reason, "updated_at": now})
+	return res.RowsAffected, res.Error
+}
+
+// PurgeExpired deletes sessions that expired or were revoked more than
+// sessionRetention ago.
+func (s *SessionService) PurgeExpired() error {
+	cutoff := time.Now().Add(-sessionRetention)
+	return s.db.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&models.Session{}).Error
+}
+
+func (s *SessionService) revoke(session *models.Session, reason string) (int64, error) {
+	if session.RevokedAt != nil {
+		return 0, nil
+	}
+	if err := s.revokeTx(s.db, session, reason, time.Now()); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (s *SessionService) revokeTx(tx *gorm.DB, session *models.Session, reason string, now time.Time) error {
+	if session.RevokedAt != nil {
+		return nil
+	}
+	session.RevokedAt, session.RevokeReason = &now, reason
+	return tx.Model(session).Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": reason, "updated_at": now}).Error
+}
+
+func (s *SessionService) tokens(session *models.Session, email, refreshToken string, now time.Time) (*SessionTokens, error) {
+	access, err := auth.GenerateToken(session.UserID, email, session.ID, s.secret, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{
+		SessionID:        session.ID,
+		AccessToken:      access,
+		AccessExpiresAt:  now.Add(s.accessTTL),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+func newRefreshToken() string {
+	return refreshTokenPrefix + rand.Text() + rand.Text()
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// Settlement report sources.
+const (
+	SettlementSourceAPI = "api"
+	SettlementSourceCSV = "csv"
+)
+
+// SettlementScheduleWindow is how far back a scheduled settlement run looks.
+// Overlapping daily runs catch charges that straddled the previous window's edge.
+const SettlementScheduleWindow = 7 * 24 * time.Hour
+
+// Settlement discrepancy kinds.
+const (
+	// DiscrepancyMissingCredit is a charge Paystack reports paid whose deposit was never credited.
+	DiscrepancyMissingCredit = "missing_credit"
+	// DiscrepancyOrphanCharge is a paid charge with no deposit recorded under its reference.
+	DiscrepancyOrphanCharge = "orphan_charge"
+	// DiscrepancyAmountMismatch is a charge whose amount or currency differs from its deposit.
+	DiscrepancyAmountMismatch = "amount_mismatch"
+	// DiscrepancyUnconfirmedCredit is a credited deposit Paystack does not report as paid.
+	DiscrepancyUnconfirmedCredit = "unconfirmed_credit"
+)
+
+// SettlementRequest selects the period and options of a settlement run.
+type SettlementRequest struct {
+	// From and To bound the charges listed from Paystack and the credited deposits
+	// checked against them, by creation time.
+	From time.Time
+	To   time.Time
+	// Fix credits missing credits after re-verifying each charge with Paystack.
+	Fix     bool
+	Trigger string
+}
+
+// SettlementDiscrepancy is one charge or deposit that did not reconcile.
+type SettlementDiscrepancy struct {
+	Kind           string                   `json:"kind"`
+	Reference      string                   `json:"reference"`
+	WalletID       string                   `json:"wallet_id,omitempty"`
+	ChargeStatus   string                   `json:"charge_status,omitempty"`
+	ChargeAmount   int64                    `json:"charge_amount"`
+	ChargeCurrency string                   `json:"charge_currency,omitempty"`
+	DepositStatus  models.TransactionStatus `json:"deposit_status,omitempty"`
+	DepositAmount  int64                    `json:"deposit_amount"`
+	SettlementID   string                   `json:"settlement_id,omitempty"`
+	Detail         string                   `json:"detail"`
+	Fixed          bool                     `json:"fixed"`
+	FixError       string                   `json:"fix_error,omitempty"`
+}
+
+// SettlementSummary describes a Paystack payout seen during a run.
+type SettlementSummary struct {
+	ID           string     `json:"id"`
+	Status       string     `json:"status"`
+	Currency     string     `json:"currency"`
+	TotalAmount  int64      `json:"total_amount"`
+	TotalFees    int64      `json:"total_fees"`
+	SettledAt    *time.Time `json:"settled_at"`
+	ChargeCount  int        `json:"charge_count"`
+	ChargeAmount int64      `json:"charge_amount"`
+}
+
+// SettlementReport is the outcome of a settlement run.
+type SettlementReport struct {
+	RunID          string                  `json:"run_id"`
+	Source         string                  `json:"source"`
+	Trigger        string                  `json:"trigger"`
+	From           time.Time               `json:"from"`
+	To             time.Time               `json:"to"`
+	StartedAt      time.Time               `json:"started_at"`
+	FinishedAt     time.Time               `json:"finished_at"`
+	ChargesChecked int                     `json:"charges_checked"`
+	Matched        int                     `json:"matched"`
+	Fixed          int                     `json:"fixed"`
+	Settlements    []SettlementSummary     `json:"settlements"`
+	Discrepancies  []SettlementDiscrepancy `json:"discrepancies"`
+}
+
+// SettlementService reconciles Paystack's record of charges and payouts with our deposits.
+type SettlementService struct {
+	db      *gorm.DB
+	wallets *WalletService
+}
+
+// NewSettlementService constructs a SettlementService.
+func NewSettlementService(db *gorm.DB, wallets *WalletService) *SettlementService {
+	return &SettlementService{db: db, wallets: wallets}
+}
+
+// RunFromPaystack lists Paystack's transactions and settlements for the period,
+// matches them to deposits and stores the report.
+func (s *SettlementService) RunFromPaystack(ctx context.Context, req SettlementRequest) (*SettlementReport, error) {
+	if err := validatePeriod(req); err != nil {
+		return nil, err
+	}
+	charges, err := s.wallets.paystack.ListTransactions(ctx, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	settlements, err := s.wallets.paystack.ListSettlements(ctx, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	byReference := make(map[string]int, len(charges))
+	for i, charge := range charges {
+		byReference[charge.Reference] = i
+	}
+	summaries := make([]SettlementSummary, 0, len(settlements))
+	for _, settlement := range settlements {
+		summary := SettlementSummary{
+			ID:          settlement.ID,
+			Status:      settlement.Status,
+			Currency:    settlement.Currency,
+			TotalAmount: settlement.TotalAmount,
+			TotalFees:   settlement.TotalFees,
+			SettledAt:   settlement.SettledAt,
+		}
+		settled, err := s.wallets.paystack.SettlementTransactions(ctx, settlement.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, charge := range settled {
+			summary.ChargeCount++
+			summary.ChargeAmount += charge.Amount
+			// Charges created before the period are still checked once they settle in it.
+			if i, ok := byReference[charge.Reference]; ok {
+				charges[i].SettlementID = settlement.ID
+				continue
+			}
+			byReference[charge.Reference] = len(charges)
+			charges = append(charges, charge)
+		}
+		summaries = append(summaries, summary)
+	}
+	return s.reconcile(ctx, req, SettlementSourceAPI, charges, summaries)
+}
+
+// RunFromExport matches charges from a Paystack dashboard export to deposits and
+// stores the report. Credited deposits in the period missing from the export are
+// reported as unconfirmed, so the export should cover the whole period.
+func (s *SettlementService) RunFromExport(ctx context.Context, req SettlementRequest, charges []PaystackCharge) (*SettlementReport, error) {
+	if err := validatePeriod(req); err != nil {
+		return nil, err
+	}
+	return s.reconcile(ctx, req, SettlementSourceCSV, charges, []SettlementSummary{})
+}
+
+func validatePeriod(req SettlementRequest) error {
+	if req.From.IsZero() || req.To.IsZero() {
+		return errors.New("from and to are required")
+	}
+	if !req.From.Before(req.To) {
+		return errors.New("from must be before to")
+	}
+	return nil
+}
+
+// paidChargeStatuses are the Paystack statuses of charges that collected money;
+// reversed charges were paid and then refunded in full.
+var paidChargeStatuses = map[string]bool{"success": true, "reversed": true}
+
+// reconcile compares paid charges with the deposits recorded under their references,
+// then looks for deposits credited in the period that no paid charge accounts for.
+func (s *SettlementService) reconcile(ctx context.Context, req SettlementRequest, source string, charges []PaystackCharge, summaries []SettlementSummary) (*SettlementReport, error) {
+	report := SettlementReport{
+		RunID:         util.MustUUID(),
+		Source:        source,
+		Trigger:       req.Trigger,
+		From:          req.From,
+		To:            req.To,
+		StartedAt:     time.Now(),
+		Settlements:   summaries,
+		Discrepancies: []SettlementDiscrepancy{},
+	}
+	paid := map[string]PaystackCharge{}
+	statuses := map[string]string{}
+	var references []string
+	for _, charge := range charges {
+		if charge.Reference == "" {
+			continue
+		}
+		report.ChargesChecked++
+		statuses[charge.Reference] = strings.ToLower(charge.Status)
+		if paidChargeStatuses[strings.ToLower(charge.Status)] {
+			if _, seen := paid[charge.Reference]; !seen {
+				references = append(references, charge.Reference)
+			}
+			paid[charge.Reference] = charge
+		}
+	}
+
+	deposits := map[string]models.Transaction{}
+	for start := 0; start < len(references); start += 500 {
+		end := min(start+500, len(references))
+		var rows []models.Transaction
+		if err := s.db.Where("reference IN ? AND type = ?", references[start:end], models.TransactionTypeDeposit).
+			Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			deposits[row.Reference] = row
+		}
+	}
+	for _, reference := range references {
+		charge := paid[reference]
+		d := SettlementDiscrepancy{
+			Reference:      reference,
+			ChargeStatus:   charge.Status,
+			ChargeAmount:   charge.Amount,
+			ChargeCurrency: charge.Currency,
+			SettlementID:   charge.SettlementID,
+		}
+		deposit, ok := deposits[reference]
+		if !ok {
+			d.Kind = DiscrepancyOrphanCharge
+			d.Detail = fmt.Sprintf("paid %s charge from %s has no deposit", charge.Channel, charge.Email)
+			report.Discrepancies = append(report.Discrepancies, d)
+			continue
+		}
+		d.WalletID, d.DepositStatus, d.DepositAmount = deposit.WalletID, deposit.Status, deposit.Amount
+		switch {
+		case deposit.Amount != charge.Amount || !strings.EqualFold(deposit.Currency, charge.Currency):
+			d.Kind = DiscrepancyAmountMismatch
+			d.Detail = fmt.Sprintf("charge %d %s, deposit %d %s", charge.Amount, charge.Currency, deposit.Amount, deposit.Currency)
+		case deposit.Status == models.TransactionSuccess && strings.EqualFold(charge.Status, "reversed"):
+			var refunded int64
+			if err := s.db.Model(&models.Transaction{}).
+				Where("original_reference = ? AND type = ? AND status = ?", reference, models.TransactionTypeRefund, models.TransactionSuccess).
+				Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+				return nil, err
+			}
+			if refunded >= deposit.Amount {
+				report.Matched++
+				continue
+			}
+			d.Kind = DiscrepancyUnconfirmedCredit
+			d.Detail = fmt.Sprintf("paystack reports the charge reversed but only %d was refunded here", refunded)
+		case deposit.Status == models.TransactionSuccess:
+			report.Matched++
+			continue
+		case deposit.Status == models.TransactionFlagged:
+			report.Matched++
+			continue // already held for review with its own reason
+		case strings.EqualFold(charge.Status, "reversed"):
+			report.Matched++
+			continue // paid and refunded in full before it was ever credited
+		default:
+			d.Kind = DiscrepancyMissingCredit
+			d.Detail = fmt.Sprintf("paystack reports the charge paid but the deposit is %s", deposit.Status)
+			if req.Fix {
+				s.fix(ctx, &d)
+				if d.Fixed {
+					report.Fixed++
+				}
+			}
+		}
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+
+	var credited []models.Transaction
+	err := s.db.Where("type = ? AND status = ? AND COALESCE(provider, '') IN ? AND created_at >= ? AND created_at < ?",
+		models.TransactionTypeDeposit, models.TransactionSuccess, []string{"", ProviderPaystack}, req.From, req.To).
+		FindInBatches(&credited, 500, func(_ *gorm.DB, _ int) error {
+			for _, deposit := range credited {
+				if _, ok := paid[deposit.Reference]; ok {
+					continue
+				}
+				detail := "not in the paystack listing"
+				if status, ok := statuses[deposit.Reference]; ok {
+					detail = "paystack reports the charge " + status
+				}
+				report.Discrepancies = append(report.Discrepancies, SettlementDiscrepancy{
+					Kind:          DiscrepancyUnconfirmedCredit,
+					Reference:     deposit.Reference,
+					WalletID:      deposit.WalletID,
+					DepositStatus: deposit.Status,
+					DepositAmount: deposit.Amount,
+					Detail:        detail,
+				})
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	report.FinishedAt = time.Now()
+
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	run := models.SettlementRun{
+		ID:               report.RunID,
+		Source:           source,
+		Trigger:          req.Trigger,
+		PeriodStart:      req.From,
+		PeriodEnd:        req.To,
+		ChargesChecked:   report.ChargesChecked,
+		DiscrepancyCount: len(report.Discrepancies),
+		FixedCount:       report.Fixed,
+		Report:           raw,
+		StartedAt:        report.StartedAt,
+		FinishedAt:       report.FinishedAt,
+	}
+	if err := s.db.Create(&run).Error; err != nil {
+		return nil, err
+	}
+	if len(report.Discrepancies) > 0 {
+		log.Printf("settlement %s: %d discrepancies in %d charges, %d fixed",
+			report.RunID, len(report.Discrepancies), report.ChargesChecked, report.Fixed)
+	}
+	return &report, nil
+}
+
+// fix re-applies a missing credit as a charge.success would, which re-verifies the
+// charge with Paystack before crediting.
+func (s *SettlementService) fix(ctx context.Context, d *SettlementDiscrepancy) {
+	if err := s.wallets.ApplyDepositWebhook(ctx, d.Reference, "success", nil); err != nil {
+		d.FixError = err.Error()
+		return
+	}
+	deposit, err := s.wallets.depositRecord(d.Reference)
+	if err != nil {
+		d.FixError = err.Error()
+		return
+	}
+	if deposit.Status != models.TransactionSuccess {
+		d.FixError = fmt.Sprintf("deposit is %s after verification", deposit.Status)
+		return
+	}
+	d.Fixed = true
+}
+
+// Runs lists the most recent settlement runs without their full reports.
+func (s *SettlementService) Runs(limit int) ([]models.SettlementRun, error) {
+	var runs []models.SettlementRun
+	if err := s.db.Omit("report").Order("started_at desc").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Report loads the stored report for a run.
+func (s *SettlementService) Report(id string) (*SettlementReport, error) {
+	var run models.SettlementRun
+	if err := s.db.First(&run, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("settlement run not found")
+		}
+		return nil, err
+	}
+	var report SettlementReport
+	if err := json.Unmarshal(run.Report, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -23,13 +23,13 @@ func NewUserService(db *gorm.DB) *UserService {
 // UpsertGoogleUser ensures the user and wallet exist for a Google-authenticated account.
 func (s *UserService) UpsertGoogleUser(email, name string) (*models.User, error) {
 	var user models.User
-	if err := s.db.Preload("Wallet").First(&user, "email = ?", email).Error; err == nil {
+	if err := s.db.Preload("Wallet", "currency = ?", models.DefaultCurrency).First(&user, "email = ?", email).Error; err == nil {
 		return &user, nil
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
-	walletNumber, err := s.generateWalletNumber()
+	walletNumber, err := generateWalletNumber(s.db)
 	if err != nil {
 		return nil, err
 	}
@@ -44,6 +44,7 @@ func (s *UserService) UpsertGoogleUser(email, name string) (*models.User, error)
 	wallet := models.Wallet{
 		ID:        util.MustUUID(),
 		UserID:    user.ID,
+		Currency:  models.DefaultCurrency,
 		Number:    walletNumber,
 		Balance:   0,
 		CreatedAt: time.Now(),
@@ -64,14 +65,15 @@ func (s *UserService) UpsertGoogleUser(email, name string) (*models.User, error)
 	return &user, nil
 }
 
-func (s *UserService) generateWalletNumber() (string, error) {
+// generateWalletNumber returns a random wallet number not yet in use.
+func generateWalletNumber(db *gorm.DB) (string, error) {
 	for i := 0; i < 5; i++ {
 		num, err := util.RandomDigits(12)
 		if err != nil {
 			return "", err
 		}
 		var count int64
-		if err := s.db.Model(&models.Wallet{}).Where("number = ?", num).Count(&count).Error; err != nil {
+		if err := db.Model(&models.Wallet{}).Where("number = ?", num).Count(&count).Error; err != nil {
 			return "", err
 		}
 		if count == 0 {
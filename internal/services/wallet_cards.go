@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCardNotFound is returned when a saved card does not exist or belongs to another user.
+var ErrCardNotFound = errors.New("saved card not found")
+
+// saveCard stores the reusable authorization from a verified card charge for
+// walletID's owner, refreshing it if the same card was saved before.
+func (s *WalletService) saveCard(walletID, email string, card *CardAuthorization) error {
+	var wallet models.Wallet
+	if err := s.db.First(&wallet, "id = ?", walletID).Error; err != nil {
+		return err
+	}
+	signature := card.Signature
+	if signature == "" {
+		signature = card.Code
+	}
+	now := time.Now()
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "signature"}},
+		DoUpdates: clause.AssignmentColumns([]string{"authorization_code", "email", "bank", "exp_month", "exp_year", "updated_at"}),
+	}).Create(&models.SavedCard{
+		ID:                util.MustUUID(),
+		UserID:            wallet.UserID,
+		Signature:         signature,
+		AuthorizationCode: card.Code,
+		Email:             email,
+		Brand:             card.Brand,
+		Last4:             card.Last4,
+		Bank:              card.Bank,
+		ExpMonth:          card.ExpMonth,
+		ExpYear:           card.ExpYear,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}).Error
+}
+
+// Cards lists the user's saved cards, newest first.
+func (s *WalletService) Cards(userID string) ([]models.SavedCard, error) {
+	var cards []models.SavedCard
+	if err := s.db.Where("user_id = ?", userID).Order("created_at desc").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+func (s *WalletService) card(userID, cardID string) (*models.SavedCard, error) {
+	var card models.SavedCard
+	if err := s.db.First(&card, "id = ? AND user_id = ?", cardID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCardNotFound
+		}
+		return nil, err
+	}
+	return &card, nil
+}
+
+// RemoveCard deactivates a saved card with Paystack and deletes it together with
+// any auto top-up rule charging it.
+func (s *WalletService) RemoveCard(ctx context.Context, userID, cardID string) error {
+	if s.paystack == nil {
+		return errors.New("paystack is not configured")
+	}
+	card, err := s.card(userID, cardID)
+	if err != nil {
+		return err
+	}
+	// An authorization Paystack no longer recognises is as good as deactivated.
+	if err := s.paystack.DeactivateAuthorization(ctx, card.AuthorizationCode); err != nil && !IsGatewayError(err, GatewayValidation) {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("card_id = ?", card.ID).Delete(&models.AutoTopUp{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(card).Error
+	})
+}
+
+// ChargeCard tops up the user's wallet in currency (the default currency when empty)
+// by charging a saved card, without redirecting the customer.
+func (s *WalletService) ChargeCard(ctx context.Context, user *models.User, cardID string, amount int64, currency string) (*models.Transaction, error) {
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	card, err := s.card(user.ID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.WalletFor(user.ID, currency)
+	if err != nil {
+		return nil, err
+	}
+	return s.chargeSavedCard(ctx, card, wallet, amount, fmt.Sprintf("DEP-%s", util.MustUUID()), "card top-up")
+}
+
+// chargeSavedCard records a pending deposit under reference, charges the card and
+// verifies the charge straight away. A declined charge fails the deposit; one whose
+// outcome Paystack has not confirmed stays pending for the webhook or expiry job.
+func (s *WalletService) chargeSavedCard(ctx context.Context, card *models.SavedCard, wallet *models.Wallet, amount int64, reference, description string) (*models.Transaction, error) {
+	if s.paystack == nil {
+		return nil, errors.New("paystack is not configured")
+	}
+	now := time.Now()
+	record := models.Transaction{
+		ID:          util.MustUUID(),
+		Reference:   reference,
+		Type:        models.TransactionTypeDeposit,
+		Status:      models.TransactionPending,
+		Amount:      amount,
+		Currency:    wallet.Currency,
+		WalletID:    wallet.ID,
+		Direction:   models.EntryCredit,
+		Provider:    ProviderPaystack,
+		Description: fmt.Sprintf("%s: %s ending %s", description, card.Brand, card.Last4),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	if _, err := s.paystack.ChargeAuthorization(ctx, card.AuthorizationCode, card.Email, amount, wallet.Currency, reference); err != nil {
+		if IsGatewayError(err, GatewayUnavailable) && !errors.Is(err, ErrCircuitOpen) {
+			// Paystack may have charged the card before failing; let verification decide.
+			log.Printf("card charge %s: outcome unknown, left pending: %v", reference, err)
+			return &record, nil
+		}
+		if ferr := s.settleDeposit(reference, models.TransactionFailed, "", nil); ferr != nil {
+			return nil, fmt.Errorf("%v (marking failed: %v)", err, ferr)
+		}
+		return nil, err
+	}
+	if err := s.verifyDeposit(ctx, &record, nil); err != nil {
+		log.Printf("card charge %s: verification deferred: %v", reference, err)
+	}
+	return s.depositRecord(reference)
+}
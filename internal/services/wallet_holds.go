@@ -0,0 +1,244 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// Hold lifetime limits.
+const (
+	DefaultHoldTTL = 7 * 24 * time.Hour
+	MaxHoldTTL     = 30 * 24 * time.Hour
+)
+
+// PlaceHold reserves part of the available balance of the user's wallet in currency
+// (the default-currency wallet when empty) until it is captured, released, or expires after ttl.
+func (s *WalletService) PlaceHold(user *models.User, currency string, amount int64, ttl time.Duration, description string) (*models.Hold, error) {
+	if user == nil || user.Wallet.ID == "" {
+		return nil, errors.New("wallet not found for user")
+	}
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	if ttl <= 0 {
+		ttl = DefaultHoldTTL
+	}
+	if ttl > MaxHoldTTL {
+		return nil, fmt.Errorf("hold expiry cannot exceed %s", MaxHoldTTL)
+	}
+	source, err := s.userWallet(user, currency)
+	if err != nil {
+		return nil, err
+	}
+	var hold models.Hold
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var wallet models.Wallet
+		if err := tx.Clauses(LockClause).First(&wallet, "id = ?", source.ID).Error; err != nil {
+			return err
+		}
+		if err := s.expireHoldsTx(tx, &wallet); err != nil {
+			return err
+		}
+		if wallet.Available() < amount {
+			return errors.New("insufficient balance")
+		}
+		now := time.Now()
+		hold = models.Hold{
+			ID:          util.MustUUID(),
+			Reference:   fmt.Sprintf("HLD-%s", util.MustUUID()),
+			WalletID:    wallet.ID,
+			Currency:    wallet.Currency,
+			Amount:      amount,
+			Status:      models.HoldActive,
+			Description: description,
+			ExpiresAt:   now.Add(ttl),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := tx.Create(&hold).Error; err != nil {
+			return err
+		}
+		return s.adjustHeld(tx, &wallet, amount)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// CaptureHold settles a hold into a transfer to destWalletNumber. An amount of zero
+// captures the full hold; any uncaptured remainder is released.
+func (s *WalletService) CaptureHold(user *models.User, holdID, destWalletNumber string, amount int64, opts TransferOptions) (string, error) {
+	if user == nil || user.Wallet.ID == "" {
+		return "", errors.New("wallet not found for user")
+	}
+	if amount < 0 {
+		return "", errors.New("amount must not be negative")
+	}
+	var transferID string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var wallet models.Wallet
+		if err := s.lockHoldWalletTx(tx, user.ID, holdID, &wallet); err != nil {
+			return err
+		}
+		hold, err := s.activeHoldTx(tx, &wallet, holdID)
+		if err != nil {
+			return err
+		}
+		capture := amount
+		if capture == 0 {
+			capture = hold.Amount
+		}
+		if capture > hold.Amount {
+			return errors.New("capture amount exceeds hold")
+		}
+		if err := s.adjustHeld(tx, &wallet, -hold.Amount); err != nil {
+			return err
+		}
+		transferID, err = s.transferTx(tx, wallet.ID, destWalletNumber, capture, opts)
+		if err != nil {
+			return err
+		}
+		return tx.Model(hold).Updates(map[string]interface{}{
+			"status":          models.HoldCaptured,
+			"captured_amount": capture,
+			"transfer_id":     transferID,
+			"updated_at":      time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return transferID, nil
+}
+
+// ReleaseHold returns a hold's funds to the available balance without moving money.
+func (s *WalletService) ReleaseHold(user *models.User, holdID string) error {
+	if user == nil || user.Wallet.ID == "" {
+		return errors.New("wallet not found for user")
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var wallet models.Wallet
+		if err := s.lockHoldWalletTx(tx, user.ID, holdID, &wallet); err != nil {
+			return err
+		}
+		hold, err := s.activeHoldTx(tx, &wallet, holdID)
+		if err != nil {
+			return err
+		}
+		return s.finishHold(tx, &wallet, hold, models.HoldReleased)
+	})
+}
+
+// Holds lists a user's holds across their wallets, newest first.
+func (s *WalletService) Holds(userID string) ([]models.Hold, error) {
+	var holds []models.Hold
+	if err := s.db.Where("wallet_id IN (?)", s.db.Model(&models.Wallet{}).Select("id").Where("user_id = ?", userID)).
+		Order("created_at desc").Limit(200).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+// ExpireHolds releases every active hold past its expiry across all wallets.
+func (s *WalletService) ExpireHolds() error {
+	var walletIDs []string
+	if err := s.db.Model(&models.Hold{}).
+		Where("status = ? AND expires_at <= ? AND COALESCE(withdrawal_id, '') = ''", models.HoldActive, time.Now()).
+		Distinct().Pluck("wallet_id", &walletIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range walletIDs {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			var wallet models.Wallet
+			if err := tx.Clauses(LockClause).First(&wallet, "id = ?", id).Error; err != nil {
+				return err
+			}
+			return s.expireHoldsTx(tx, &wallet)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expireHoldsTx expires the locked wallet's stale holds and updates its held balance.
+func (s *WalletService) expireHoldsTx(tx *gorm.DB, wallet *models.Wallet) error {
+	var stale []models.Hold
+	if err := tx.Clauses(LockClause).
+		Where("wallet_id = ? AND status = ? AND expires_at <= ? AND COALESCE(withdrawal_id, '') = ''", wallet.ID, models.HoldActive, time.Now()).
+		Find(&stale).Error; err != nil {
+		return err
+	}
+	for i := range stale {
+		if err := s.finishHold(tx, wallet, &stale[i], models.HoldExpired); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockHoldWalletTx locks the wallet holding holdID, provided it belongs to the user.
+func (s *WalletService) lockHoldWalletTx(tx *gorm.DB, userID, holdID string, wallet *models.Wallet) error {
+	var hold models.Hold
+	if err := tx.Select("wallet_id").First(&hold, "id = ?", holdID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("hold not found")
+		}
+		return err
+	}
+	if err := tx.Clauses(LockClause).First(wallet, "id = ? AND user_id = ?", hold.WalletID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("hold not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// activeHoldTx loads and locks an unexpired active hold on the locked wallet.
+// Holds backing a pending withdrawal are not visible here.
+func (s *WalletService) activeHoldTx(tx *gorm.DB, wallet *models.Wallet, holdID string) (*models.Hold, error) {
+	var hold models.Hold
+	if err := tx.Clauses(LockClause).First(&hold, "id = ? AND wallet_id = ?", holdID, wallet.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("hold not found")
+		}
+		return nil, err
+	}
+	if hold.WithdrawalID != "" {
+		return nil, errors.New("hold belongs to a pending withdrawal or refund")
+	}
+	if hold.Status == models.HoldActive && !time.Now().Before(hold.ExpiresAt) {
+		if err := s.finishHold(tx, wallet, &hold, models.HoldExpired); err != nil {
+			return nil, err
+		}
+	}
+	if hold.Status != models.HoldActive {
+		return nil, fmt.Errorf("hold is %s", hold.Status)
+	}
+	return &hold, nil
+}
+
+// finishHold closes an active hold without capturing it.
+func (s *WalletService) finishHold(tx *gorm.DB, wallet *models.Wallet, hold *models.Hold, status models.HoldStatus) error {
+	if err := s.adjustHeld(tx, wallet, -hold.Amount); err != nil {
+		return err
+	}
+	hold.Status = status
+	hold.UpdatedAt = time.Now()
+	return tx.Model(hold).Updates(map[string]interface{}{"status": status, "updated_at": hold.UpdatedAt}).Error
+}
+
+// adjustHeld moves the locked wallet's held balance by delta.
+func (s *WalletService) adjustHeld(tx *gorm.DB, wallet *models.Wallet, delta int64) error {
+	wallet.HeldBalance += delta
+	return tx.Model(&models.Wallet{}).Where("id = ?", wallet.ID).
+		Update("held_balance", gorm.Expr("held_balance + ?", delta)).Error
+}
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// Paystack refund webhook events. refund.pending and refund.processing are informational.
+const (
+	PaystackRefundProcessed = "refund.processed"
+	PaystackRefundFailed    = "refund.failed"
+)
+
+// refundPrefix starts every refund reference.
+const refundPrefix = "RFD-"
+
+// ErrRefundAmbiguous means a refund event could match more than one pending refund
+// whose Paystack id is not stored yet; the event is retried once the ids are known.
+var ErrRefundAmbiguous = errors.New("refund event matches several pending refunds")
+
+// ErrRefundExceedsDeposit means the refund is larger than what is left of the deposit
+// after earlier refunds that have not failed.
+var ErrRefundExceedsDeposit = errors.New("refund exceeds the unrefunded amount of the deposit")
+
+// RefundRequest describes an operator-initiated deposit refund.
+type RefundRequest struct {
+	DepositReference string
+	// Amount to refund; zero refunds whatever is left of the deposit.
+	Amount   int64
+	Operator string
+	Reason   string
+}
+
+// RefundDeposit holds the refund amount on the deposit's wallet, records a pending
+// refund linked to the deposit and asks Paystack to return the money to the payer.
+// The hold is settled by ApplyRefundWebhook; if Paystack rejects the refund outright
+// it fails and the hold is released immediately. If Paystack cannot be reached
+// mid-request the refund stays pending, since it may have been created.
+func (s *WalletService) RefundDeposit(ctx context.Context, req RefundRequest) (*models.Transaction, error) {
+	if s.paystack == nil {
+		return nil, errors.New("paystack is not configured")
+	}
+	if req.Reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if len(req.Reason) > MaxNarrationLength {
+		return nil, fmt.Errorf("reason must be at most %d characters", MaxNarrationLength)
+	}
+	if req.Amount < 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	deposit, err := s.depositRecord(req.DepositReference)
+	if err != nil {
+		return nil, err
+	}
+	if deposit.Provider != "" && deposit.Provider != ProviderPaystack {
+		return nil, fmt.Errorf("refunds are only supported for paystack deposits, not %s", deposit.Provider)
+	}
+
+	reference := refundPrefix + util.MustUUID()
+	var record models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Locking the deposit serialises concurrent refunds of it.
+		if err := tx.Clauses(LockClause).First(deposit, "id = ?", deposit.ID).Error; err != nil {
+			return err
+		}
+		if deposit.Status != models.TransactionSuccess {
+			return fmt.Errorf("only successful deposits can be refunded; deposit is %s", deposit.Status)
+		}
+		var refunded int64
+		if err := tx.Model(&models.Transaction{}).
+			Where("original_reference = ? AND type = ? AND status IN ?", deposit.Reference,
+				models.TransactionTypeRefund, []models.TransactionStatus{models.TransactionPending, models.TransactionSuccess}).
+			Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+			return err
+		}
+		amount := req.Amount
+		if amount == 0 {
+			amount = deposit.Amount - refunded
+		}
+		if amount <= 0 || refunded+amount > deposit.Amount {
+			return ErrRefundExceedsDeposit
+		}
+
+		var wallet models.Wallet
+		if err := tx.Clauses(LockClause).First(&wallet, "id = ?", deposit.WalletID).Error; err != nil {
+			return err
+		}
+		if err := s.expireHoldsTx(tx, &wallet); err != nil {
+			return err
+		}
+		if wallet.Available() < amount {
+			return errors.New("insufficient balance")
+		}
+		now := time.Now()
+		description := fmt.Sprintf("refund of %s: %s", deposit.Reference, req.Reason)
+		hold := models.Hold{
+			ID:           util.MustUUID(),
+			Reference:    fmt.Sprintf("HLD-%s", util.MustUUID()),
+			WalletID:     wallet.ID,
+			Currency:     wallet.Currency,
+			Amount:       amount,
+			Status:       models.HoldActive,
+			Description:  description,
+			WithdrawalID: reference,
+			ExpiresAt:    now.Add(MaxHoldTTL),
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if err := tx.Create(&hold).Error; err != nil {
+			return err
+		}
+		if err := s.adjustHeld(tx, &wallet, amount); err != nil {
+			return err
+		}
+		record = models.Transaction{
+			ID:                util.MustUUID(),
+			Reference:         reference,
+			OriginalReference: deposit.Reference,
+			Type:              models.TransactionTypeRefund,
+			Status:            models.TransactionPending,
+			Amount:            amount,
+			Currency:          deposit.Currency,
+			WalletID:          wallet.ID,
+			Direction:         models.EntryDebit,
+			Provider:          ProviderPaystack,
+			Description:       description,
+			Narration:         req.Reason,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("refund %s of %s for %d requested by %s", reference, deposit.Reference, record.Amount, req.Operator)
+
+	refundID, err := s.paystack.CreateRefund(ctx, deposit.Reference, record.Amount, record.Currency, req.Reason)
+	if err != nil {
+		if IsGatewayError(err, GatewayUnavailable) && !errors.Is(err, ErrCircuitOpen) {
+			// Paystack may have created the refund before failing; keep the hold
+			// and let the refund webhook settle it.
+			log.Printf("refund %s: outcome unknown, left pending: %v", reference, err)
+			return &record, nil
+		}
+		if ferr := s.settleRefund(&record, PaystackRefundFailed, nil); ferr != nil {
+			return nil, fmt.Errorf("%w (releasing hold: %v)", err, ferr)
+		}
+		return nil, err
+	}
+	record.ProviderReference = refundID
+	if err := s.db.Model(&record).Update("provider_reference", refundID).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Refunds lists the refunds of a deposit, oldest first.
+func (s *WalletService) Refunds(depositReference string) ([]models.Transaction, error) {
+	if _, err := s.depositRecord(depositReference); err != nil {
+		return nil, err
+	}
+	var refunds []models.Transaction
+	if err := s.db.Where("original_reference = ? AND type = ?", depositReference, models.TransactionTypeRefund).
+		Order("created_at asc").Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// ApplyRefundWebhook settles a refund from a Paystack refund.* event. The refund is
+// matched on Paystack's refund id. When the event arrived before the id was stored,
+// it falls back to the deposit's pending refund without an id and with the event's
+// amount and currency; if several could match, ErrRefundAmbiguous leaves the event
+// to be retried. Events for refunds not started here, e.g. from the Paystack
+// dashboard, are logged and ignored.
+func (s *WalletService) ApplyRefundWebhook(n *WebhookNotification, payload []byte) error {
+	if n.Type != PaystackRefundProcessed && n.Type != PaystackRefundFailed {
+		return nil
+	}
+	var record models.Transaction
+	err := gorm.ErrRecordNotFound
+	if n.RefundID != "" {
+		err = s.db.First(&record, "provider_reference = ? AND type = ?", n.RefundID, models.TransactionTypeRefund).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var candidates []models.Transaction
+		if err := s.db.Where("original_reference = ? AND type = ? AND status = ? AND COALESCE(provider_reference, '') = '' AND amount = ? AND currency = ?",
+			n.Reference, models.TransactionTypeRefund, models.TransactionPending, n.Amount, n.Currency).
+			Limit(2).Find(&candidates).Error; err != nil {
+			return err
+		}
+		switch len(candidates) {
+		case 0:
+			log.Printf("refund %s of %s: no matching refund, ignoring %s", n.RefundID, n.Reference, n.Type)
+			return nil
+		case 1:
+			record = candidates[0]
+		default:
+			return ErrRefundAmbiguous
+		}
+	} else if err != nil {
+		return err
+	}
+	return s.settleRefund(&record, n.Type, payload)
+}
+
+// refundVerifyBatchSize bounds how many refunds one VerifyRefunds run checks.
+const refundVerifyBatchSize = 100
+
+// VerifyRefunds asks Paystack about refunds still pending age after their last
+// check, for when the refund webhook never arrived or could not be matched, and
+// settles the ones Paystack reports as final. A refund Paystack never created
+// failed, so its hold is released. The rest are checked again on a later run,
+// after the ones not yet checked. It returns how many refunds were settled.
+func (s *WalletService) VerifyRefunds(ctx context.Context, now time.Time, age time.Duration) (int, error) {
+	if s.paystack == nil {
+		return 0, errors.New("paystack is not configured")
+	}
+	var pending []models.Transaction
+	if err := s.db.Where("type = ? AND status = ? AND updated_at <= ?",
+		models.TransactionTypeRefund, models.TransactionPending, now.Add(-age)).
+		Order("updated_at").Limit(refundVerifyBatchSize).Find(&pending).Error; err != nil {
+		return 0, err
+	}
+	settled := 0
+	for i := range pending {
+		if ctx.Err() != nil {
+			return settled, ctx.Err()
+		}
+		record := &pending[i]
+		event, err := s.verifyRefund(ctx, record)
+		if err != nil {
+			log.Printf("refund %s: verify: %v", record.Reference, err)
+		}
+		if event == "" {
+			if err := s.db.Model(&models.Transaction{}).
+				Where("id = ? AND status = ?", record.ID, models.TransactionPending).
+				UpdateColumn("updated_at", now).Error; err != nil {
+				return settled, err
+			}
+			continue
+		}
+		if err := s.settleRefund(record, event, nil); err != nil {
+			return settled, err
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// verifyRefund finds a pending refund among Paystack's refunds of its deposit and
+// returns the event its Paystack status amounts to, or "" while it is in progress.
+// Without a stored Paystack id it takes a refund with the same amount and currency
+// that no other refund of the deposit claims, and stores that id.
+func (s *WalletService) verifyRefund(ctx context.Context, record *models.Transaction) (string, error) {
+	refunds, err := s.paystack.ListRefunds(ctx, record.OriginalReference)
+	if err != nil {
+		return "", err
+	}
+	var found *PaystackRefund
+	if record.ProviderReference != "" {
+		for i := range refunds {
+			if refunds[i].ID == record.ProviderReference {
+				found = &refunds[i]
+			}
+		}
+		if found == nil {
+			return "", fmt.Errorf("paystack has no refund %s", record.ProviderReference)
+		}
+	} else {
+		var claimed []string
+		if err := s.db.Model(&models.Transaction{}).
+			Where("original_reference = ? AND type = ? AND id <> ? AND COALESCE(provider_reference, '') <> ''",
+				record.OriginalReference, models.TransactionTypeRefund, record.ID).
+			Pluck("provider_reference", &claimed).Error; err != nil {
+			return "", err
+		}
+		for i := range refunds {
+			rf := &refunds[i]
+			if rf.Amount == record.Amount && strings.EqualFold(rf.Currency, record.Currency) && !slices.Contains(claimed, rf.ID) {
+				found = rf
+				break
+			}
+		}
+		if found == nil {
+			return PaystackRefundFailed, nil // the create request never reached Paystack
+		}
+		if err := s.db.Model(&models.Transaction{}).
+			Where("id = ? AND COALESCE(provider_reference, '') = ''", record.ID).
+			Update("provider_reference", found.ID).Error; err != nil {
+			return "", err
+		}
+		record.ProviderReference = found.ID
+	}
+	switch found.Status {
+	case "processed":
+		return PaystackRefundProcessed, nil
+	case "failed":
+		return PaystackRefundFailed, nil
+	}
+	return "", nil
+}
+
+// settleRefund captures the hold and debits the wallet when Paystack processed the
+// refund, or releases the hold when it failed. Settled refunds are left untouched.
+func (s *WalletService) settleRefund(refund *models.Transaction, event string, payload []byte) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var record models.Transaction
+		if err := tx.Clauses(LockClause).First(&record, "id = ?", refund.ID).Error; err != nil {
+			return err
+		}
+		if record.Status != models.TransactionPending {
+			return nil // idempotent
+		}
+		var wallet models.Wallet
+		if err := tx.Clauses(LockClause).First(&wallet, "id = ?", record.WalletID).Error; err != nil {
+			return err
+		}
+		hold, err := s.withdrawalHoldTx(tx, record.Reference)
+		if err != nil {
+			return err
+		}
+
+		switch event {
+		case PaystackRefundProcessed:
+			if err := s.adjustHeld(tx, &wallet, -hold.Amount); err != nil {
+				return err
+			}
+			walletAccount, err := s.ledger.WalletAccount(tx, wallet.ID)
+			if err != nil {
+				return err
+			}
+			clearing, err := s.ledger.SystemAccount(tx, models.GatewayClearingAccountCode(ProviderPaystack, record.Currency))
+			if err != nil {
+				return err
+			}
+			entry, err := s.ledger.Post(tx, record.Reference, "paystack refund",
+				Debit(walletAccount.ID, record.Amount),
+				Credit(clearing.ID, record.Amount),
+			)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(hold).Updates(map[string]interface{}{
+				"status":          models.HoldCaptured,
+				"captured_amount": hold.Amount,
+				"updated_at":      time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+			record.Status = models.TransactionSuccess
+			record.JournalEntryID = entry.ID
+		case PaystackRefundFailed:
+			if err := s.finishHold(tx, &wallet, hold, models.HoldReleased); err != nil {
+				return err
+			}
+			record.Status = models.TransactionFailed
+		default:
+			return nil
+		}
+		if payload != nil {
+			record.RawPayload = payload
+		}
+		record.UpdatedAt = time.Now()
+		if err := tx.Save(&record).Error; err != nil {
+			return err
+		}
+		*refund = record
+		return nil
+	})
+}
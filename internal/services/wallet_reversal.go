@@ -0,0 +1,146 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// ErrRecipientInsufficientFunds means the recipient's available balance cannot cover a reversal that was not forced.
+var ErrRecipientInsufficientFunds = errors.New("recipient no longer has the funds; use force to reverse anyway")
+
+// ReversalRequest describes an operator-initiated transfer reversal.
+type ReversalRequest struct {
+	TransferID string
+	Operator   string
+	Reason     string
+	// Force allows the recipient wallet to go negative.
+	Force bool
+}
+
+// ReverseTransfer atomically posts compensating legs for both sides of a transfer,
+// marks the original legs reversed, and returns the reversal ID.
+func (s *WalletService) ReverseTransfer(req ReversalRequest) (string, error) {
+	if req.Reason == "" {
+		return "", errors.New("reason is required")
+	}
+	reversalID := fmt.Sprintf("REV-%s", util.MustUUID())
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var legs []models.Transaction
+		if err := tx.Clauses(LockClause).
+			Where("transfer_id = ? AND type = ?", req.TransferID, models.TransactionTypeTransfer).
+			Find(&legs).Error; err != nil {
+			return err
+		}
+		var debitLeg, creditLeg *models.Transaction
+		for i := range legs {
+			switch legs[i].Direction {
+			case models.EntryDebit:
+				debitLeg = &legs[i]
+			case models.EntryCredit:
+				creditLeg = &legs[i]
+			}
+		}
+		if debitLeg == nil || creditLeg == nil {
+			return errors.New("transfer not found")
+		}
+		if debitLeg.Status == models.TransactionReversed || creditLeg.Status == models.TransactionReversed {
+			return errors.New("transfer already reversed")
+		}
+		if debitLeg.Status != models.TransactionSuccess || creditLeg.Status != models.TransactionSuccess {
+			return errors.New("only successful transfers can be reversed")
+		}
+
+		var recipient models.Wallet
+		if err := tx.Clauses(LockClause).First(&recipient, "id = ?", creditLeg.WalletID).Error; err != nil {
+			return err
+		}
+		if err := s.expireHoldsTx(tx, &recipient); err != nil {
+			return err
+		}
+		// Held funds back pending withdrawals and refunds, so only available money can be taken back.
+		if recipient.Available() < creditLeg.Amount && !req.Force {
+			return ErrRecipientInsufficientFunds
+		}
+		recipientAccount, err := s.ledger.WalletAccount(tx, creditLeg.WalletID)
+		if err != nil {
+			return err
+		}
+		senderAccount, err := s.ledger.WalletAccount(tx, debitLeg.WalletID)
+		if err != nil {
+			return err
+		}
+		entry, err := s.ledger.Post(tx, reversalID, "transfer reversal",
+			Debit(recipientAccount.ID, creditLeg.Amount),
+			Credit(senderAccount.ID, debitLeg.Amount),
+		)
+		if err != nil {
+			return err
+		}
+
+		meta, err := json.Marshal(map[string]interface{}{
+			"operator": req.Operator,
+			"reason":   req.Reason,
+			"force":    req.Force,
+		})
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		description := fmt.Sprintf("reversal of %s: %s", req.TransferID, req.Reason)
+		compensating := []models.Transaction{
+			{
+				ID:                 util.MustUUID(),
+				Reference:          reversalID + "-DR",
+				TransferID:         reversalID,
+				OriginalReference:  creditLeg.Reference,
+				Type:               models.TransactionTypeReversal,
+				Status:             models.TransactionSuccess,
+				Amount:             creditLeg.Amount,
+				Currency:           creditLeg.Currency,
+				WalletID:           creditLeg.WalletID,
+				CounterpartyWallet: creditLeg.CounterpartyWallet,
+				Direction:          models.EntryDebit,
+				Description:        description,
+				RawPayload:         meta,
+				JournalEntryID:     entry.ID,
+				CreatedAt:          now,
+				UpdatedAt:          now,
+			},
+			{
+				ID:                 util.MustUUID(),
+				Reference:          reversalID + "-CR",
+				TransferID:         reversalID,
+				OriginalReference:  debitLeg.Reference,
+				Type:               models.TransactionTypeReversal,
+				Status:             models.TransactionSuccess,
+				Amount:             debitLeg.Amount,
+				Currency:           debitLeg.Currency,
+				WalletID:           debitLeg.WalletID,
+				CounterpartyWallet: debitLeg.CounterpartyWallet,
+				Direction:          models.EntryCredit,
+				Description:        description,
+				RawPayload:         meta,
+				JournalEntryID:     entry.ID,
+				CreatedAt:          now,
+				UpdatedAt:          now,
+			},
+		}
+		if err := tx.Create(&compensating).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Transaction{}).
+			Where("transfer_id = ? AND type = ?", req.TransferID, models.TransactionTypeTransfer).
+			Updates(map[string]interface{}{"status": models.TransactionReversed, "updated_at": now}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return reversalID, nil
+}
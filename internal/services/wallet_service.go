@@ -1,8 +1,12 @@
 package services
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,159 +17,574 @@ import (
 	"gorm.io/gorm/clause"
 )
 
-// WalletService encapsulates wallet operations and Paystack integration.
+// WalletService encapsulates wallet operations and payment provider integration.
+// Every balance change is posted through the ledger; Wallet.Balance is its cached projection.
 type WalletService struct {
 	db       *gorm.DB
-	paystack *PaystackService
+	paystack *PaystackService // bank payouts
+	gateways []PaymentGateway // deposit providers in order of preference
+	ledger   *LedgerService
+	dvaBank  string // Paystack bank slug for dedicated virtual accounts
+
+	publicURL         string   // base URL checkout returns customers to, if set
+	callbackAllowlist []string // callback URL prefixes clients may send
+	depositChannels   []string // channels every checkout is restricted to
 }
 
 // LockClause serializes balance updates.
 var LockClause = clause.Locking{Strength: "UPDATE"}
 
 // NewWalletService constructs a WalletService.
+// Paystack, when given, is also the only deposit gateway until SetGateways is called.
 func NewWalletService(db *gorm.DB, paystack *PaystackService) *WalletService {
-	return &WalletService{db: db, paystack: paystack}
+	s := &WalletService{db: db, paystack: paystack, ledger: NewLedgerService(db)}
+	if paystack != nil {
+		s.gateways = []PaymentGateway{paystack}
+	}
+	return s
+}
+
+// SetGateways replaces the deposit gateways, in order of preference. A deposit
+// falls back to the next gateway when one cannot start a checkout; each deposit is
+// verified with the gateway that started it. Payouts always go through Paystack.
+func (s *WalletService) SetGateways(gateways ...PaymentGateway) {
+	s.gateways = gateways
+}
+
+// Gateways lists the deposit gateways in order of preference.
+func (s *WalletService) Gateways() []PaymentGateway {
+	return s.gateways
+}
+
+// Gateway returns the deposit gateway called name. An empty name means Paystack,
+// which handled every deposit recorded before providers were tracked.
+func (s *WalletService) Gateway(name string) (PaymentGateway, error) {
+	if name == "" {
+		name = ProviderPaystack
+	}
+	for _, gateway := range s.gateways {
+		if gateway.Name() == name {
+			return gateway, nil
+		}
+	}
+	return nil, fmt.Errorf("payment gateway %s is not configured", name)
 }
 
-// InitiateDeposit records a pending transaction and returns a Paystack checkout URL.
-func (s *WalletService) InitiateDeposit(user *models.User, amount int64) (string, string, error) {
+// ErrCurrencyMismatch is returned when a transfer would move money between wallets of different currencies.
+var ErrCurrencyMismatch = errors.New("cannot transfer between wallets of different currencies")
+
+// OpenWallet creates the user's wallet in currency.
+func (s *WalletService) OpenWallet(user *models.User, currency string) (*models.Wallet, error) {
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	code, ok := models.NormalizeCurrency(currency)
+	if !ok {
+		return nil, fmt.Errorf("unsupported currency %s", code)
+	}
+	var count int64
+	if err := s.db.Model(&models.Wallet{}).Where("user_id = ? AND currency = ?", user.ID, code).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%s wallet already exists", code)
+	}
+	number, err := generateWalletNumber(s.db)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	wallet := models.Wallet{
+		ID:        util.MustUUID(),
+		UserID:    user.ID,
+		Currency:  code,
+		Number:    number,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.db.Create(&wallet).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// Wallets lists the user's wallets in the order they were opened, so the default wallet comes first.
+func (s *WalletService) Wallets(userID string) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// WalletFor returns the user's wallet in currency (the default currency when empty).
+func (s *WalletService) WalletFor(userID, currency string) (*models.Wallet, error) {
+	code, ok := models.NormalizeCurrency(currency)
+	if !ok {
+		return nil, fmt.Errorf("unsupported currency %s", code)
+	}
+	var wallet models.Wallet
+	if err := s.db.First(&wallet, "user_id = ? AND currency = ?", userID, code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no %s wallet found", code)
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// userWallet returns the user's wallet in currency; empty means the preloaded default wallet.
+func (s *WalletService) userWallet(user *models.User, currency string) (*models.Wallet, error) {
+	if code, _ := models.NormalizeCurrency(currency); code == user.Wallet.Currency {
+		return &user.Wallet, nil
+	}
+	return s.WalletFor(user.ID, currency)
+}
+
+// InitiateDeposit records a pending transaction into the user's wallet in currency
+// (the default currency when empty) and returns it with a checkout URL from the
+// first gateway able to start one. The transaction records that gateway as its Provider.
+// opts carry the client's callback URL, channels and metadata.
+func (s *WalletService) InitiateDeposit(ctx context.Context, user *models.User, amount int64, currency string, opts DepositOptions) (*models.Transaction, string, error) {
 	if amount <= 0 {
-		return "", "", errors.New("amount must be greater than zero")
+		return nil, "", errors.New("amount must be greater than zero")
 	}
 	if user == nil || user.Wallet.ID == "" {
-		return "", "", errors.New("wallet not found for user")
+		return nil, "", errors.New("wallet not found for user")
+	}
+	if len(s.gateways) == 0 {
+		return nil, "", errors.New("no payment gateway configured")
+	}
+	wallet, err := s.WalletFor(user.ID, currency)
+	if err != nil {
+		return nil, "", err
+	}
+	checkout, err := s.checkoutOptions(opts, user.ID, wallet.Number)
+	if err != nil {
+		return nil, "", err
 	}
 	ref := fmt.Sprintf("DEP-%s", util.MustUUID())
 	tx := models.Transaction{
-		ID:        util.MustUUID(),
-		Reference: ref,
-		Type:      models.TransactionTypeDeposit,
-		Status:    models.TransactionPending,
-		Amount:    amount,
-		WalletID:  user.Wallet.ID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          util.MustUUID(),
+		Reference:   ref,
+		Type:        models.TransactionTypeDeposit,
+		Status:      models.TransactionPending,
+		Amount:      amount,
+		Currency:    wallet.Currency,
+		WalletID:    wallet.ID,
+		Direction:   models.EntryCredit,
+		Provider:    s.gateways[0].Name(),
+		CallbackURL: opts.CallbackURL,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 	if err := s.db.Create(&tx).Error; err != nil {
-		return "", "", err
+		return nil, "", err
 	}
-	authURL, err := s.paystack.InitializeTransaction(amount, user.Email, ref)
+	authURL, provider, err := s.startCheckout(ctx, amount, wallet.Currency, user.Email, ref, checkout)
 	if err != nil {
-		return "", "", err
+		// The customer never received a checkout link, so the deposit cannot complete.
+		if ferr := s.db.Model(&tx).Updates(map[string]interface{}{
+			"status":      models.TransactionFailed,
+			"description": err.Error(),
+			"updated_at":  time.Now(),
+		}).Error; ferr != nil {
+			log.Printf("deposit %s: marking failed: %v", ref, ferr)
+		}
+		return nil, "", err
 	}
-	return ref, authURL, nil
+	if provider != tx.Provider {
+		tx.Provider = provider
+		if err := s.db.Model(&tx).Update("provider", provider).Error; err != nil {
+			return nil, "", err
+		}
+	}
+	return &tx, authURL, nil
 }
 
-// ApplyDepositWebhook verifies idempotency and credits wallet on success.
-func (s *WalletService) ApplyDepositWebhook(reference string, status string, payload []byte) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var record models.Transaction
-		if err := tx.Clauses(LockClause).First(&record, "reference = ?", reference).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("transaction reference not found")
-			}
-			return err
+// startCheckout asks each gateway in turn for a checkout URL and returns the first
+// one issued together with the gateway's name.
+func (s *WalletService) startCheckout(ctx context.Context, amount int64, currency, email, reference string, opts CheckoutOptions) (string, string, error) {
+	var failures []error
+	for _, gateway := range s.gateways {
+		authURL, err := gateway.InitializeTransaction(ctx, amount, currency, email, reference, opts)
+		if err == nil {
+			return authURL, gateway.Name(), nil
 		}
-		if record.Type != models.TransactionTypeDeposit {
-			return errors.New("reference is not a deposit transaction")
+		log.Printf("deposit %s: %s checkout failed: %v", reference, gateway.Name(), err)
+		failures = append(failures, err)
+		if ctx.Err() != nil {
+			break
 		}
-		if record.Status == models.TransactionSuccess {
-			return nil // idempotent
+	}
+	// Joined, so the first gateway's *GatewayError decides how the failure is reported.
+	return "", "", fmt.Errorf("checkout failed: %w", errors.Join(failures...))
+}
+
+// ApplyDepositWebhook settles a deposit from a provider's charge event. A reported
+// success is only credited once the deposit's gateway confirms the charge with the
+// amount, currency and customer we recorded; a mismatch flags the deposit instead.
+// Repeated events are ignored.
+func (s *WalletService) ApplyDepositWebhook(ctx context.Context, reference string, status string, payload []byte) error {
+	record, err := s.depositRecord(reference)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(status) {
+	case "success":
+		return s.verifyDeposit(ctx, record, payload)
+	case "failed":
+		return s.settleDeposit(reference, models.TransactionFailed, "", payload)
+	default:
+		// do not update wallet for pending/unknown
+		return nil
+	}
+}
+
+// RefreshDeposit re-verifies a still-pending deposit with its gateway, settles it if
+// the charge has reached a final state, and returns the up-to-date record.
+func (s *WalletService) RefreshDeposit(ctx context.Context, reference string) (*models.Transaction, error) {
+	record, err := s.depositRecord(reference)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != models.TransactionPending && record.Status != models.TransactionExpired {
+		return record, nil
+	}
+	if err := s.verifyDeposit(ctx, record, nil); err != nil {
+		return nil, err
+	}
+	return s.depositRecord(reference)
+}
+
+// depositExpiryBatchSize bounds how many stale deposits one ExpireDeposits run checks.
+const depositExpiryBatchSize = 100
+
+// ExpireDeposits re-verifies deposits still pending ttl after they were created.
+// Charges the gateway reports as final are settled as usual; the rest, including
+// references the gateway never saw, are moved to expired. Deposits whose gateway
+// cannot be reached stay pending for the next run, behind the deposits not yet
+// checked, so a batch of failing ones never starves the rest. It returns how many
+// were expired.
+func (s *WalletService) ExpireDeposits(ctx context.Context, now time.Time, ttl time.Duration) (int, error) {
+	var stale []models.Transaction
+	if err := s.db.Where("type = ? AND status = ? AND created_at <= ?",
+		models.TransactionTypeDeposit, models.TransactionPending, now.Add(-ttl)).
+		Order("updated_at").Limit(depositExpiryBatchSize).Find(&stale).Error; err != nil {
+		return 0, err
+	}
+	expired := 0
+	for i := range stale {
+		if ctx.Err() != nil {
+			return expired, ctx.Err()
 		}
-		var wallet models.Wallet
-		if err := tx.Clauses(LockClause).First(&wallet, "id = ?", record.WalletID).Error; err != nil {
-			return err
+		record := &stale[i]
+		if err := s.verifyDeposit(ctx, record, nil); err != nil && !IsGatewayError(err, GatewayValidation) {
+			log.Printf("deposit %s: expiry check: %v", record.Reference, err)
+			if err := s.db.Model(&models.Transaction{}).
+				Where("id = ? AND status = ?", record.ID, models.TransactionPending).
+				UpdateColumn("updated_at", now).Error; err != nil {
+				return expired, err
+			}
+			continue
 		}
-		switch strings.ToLower(status) {
-		case "success":
-			wallet.Balance += record.Amount
-			record.Status = models.TransactionSuccess
-			record.RawPayload = payload
-		case "failed":
-			record.Status = models.TransactionFailed
-			record.RawPayload = payload
-		default:
-			// do not update wallet for pending/unknown
-			return nil
+		provider := record.Provider
+		if provider == "" {
+			provider = ProviderPaystack
 		}
-		if err := tx.Save(&wallet).Error; err != nil {
-			return err
+		reason := fmt.Sprintf("expired: no payment confirmed by %s within %s", provider, ttl)
+		if err := s.settleDeposit(record.Reference, models.TransactionExpired, reason, nil); err != nil {
+			return expired, err
 		}
-		if err := tx.Save(&record).Error; err != nil {
-			return err
+		current, err := s.depositRecord(record.Reference)
+		if err != nil {
+			return expired, err
 		}
-		return nil
-	})
+		if current.Status == models.TransactionExpired {
+			expired++
+		}
+	}
+	return expired, nil
 }
 
-// Transfer moves balance between two wallets atomically and records transactions.
-func (s *WalletService) Transfer(sender *models.User, destWalletNumber string, amount int64) error {
-	if sender == nil || sender.Wallet.ID == "" {
-		return errors.New("sender wallet not found")
+func (s *WalletService) depositRecord(reference string) (*models.Transaction, error) {
+	var record models.Transaction
+	if err := s.db.First(&record, "reference = ?", reference).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("transaction reference not found")
+		}
+		return nil, err
 	}
-	if amount <= 0 {
-		return errors.New("amount must be greater than zero")
+	if record.Type != models.TransactionTypeDeposit {
+		return nil, errors.New("reference is not a deposit transaction")
+	}
+	return &record, nil
+}
+
+// verifyDeposit asks the deposit's gateway for the charge behind it and settles it
+// accordingly, saving the card of a successful card charge for later top-ups.
+func (s *WalletService) verifyDeposit(ctx context.Context, record *models.Transaction, payload []byte) error {
+	if record.Status == models.TransactionSuccess || record.Status == models.TransactionFlagged {
+		return nil // idempotent
 	}
-	if sender.Wallet.Number == destWalletNumber {
-		return errors.New("cannot transfer to the same wallet")
+	gateway, err := s.Gateway(record.Provider)
+	if err != nil {
+		return err
 	}
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var senderWallet models.Wallet
-		if err := tx.Clauses(LockClause).First(&senderWallet, "id = ?", sender.Wallet.ID).Error; err != nil {
-			return err
+	verified, err := gateway.VerifyTransaction(ctx, record.Reference)
+	if err != nil {
+		return err
+	}
+	var emails []string
+	if err := s.db.Model(&models.User{}).
+		Joins("JOIN wallets ON wallets.user_id = users.id").
+		Where("wallets.id = ?", record.WalletID).
+		Pluck("users.email", &emails).Error; err != nil {
+		return err
+	}
+	email := ""
+	if len(emails) > 0 {
+		email = emails[0]
+	}
+
+	switch strings.ToLower(verified.Status) {
+	case "success":
+		var problems []string
+		if verified.Amount != record.Amount {
+			problems = append(problems, fmt.Sprintf("amount %d, expected %d", verified.Amount, record.Amount))
+		}
+		if !strings.EqualFold(verified.Currency, record.Currency) {
+			problems = append(problems, fmt.Sprintf("currency %s, expected %s", verified.Currency, record.Currency))
 		}
-		if senderWallet.Balance < amount {
-			return errors.New("insufficient balance")
+		if !strings.EqualFold(verified.CustomerEmail, email) {
+			problems = append(problems, fmt.Sprintf("customer %s, expected %s", verified.CustomerEmail, email))
 		}
-		var destWallet models.Wallet
-		if err := tx.Clauses(LockClause).First(&destWallet, "number = ?", destWalletNumber).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("recipient wallet not found")
+		if len(problems) > 0 {
+			reason := gateway.Name() + " verification mismatch: " + strings.Join(problems, "; ")
+			log.Printf("deposit %s flagged: %s", record.Reference, reason)
+			return s.settleDeposit(record.Reference, models.TransactionFlagged, reason, payload)
+		}
+		if verified.Card != nil {
+			// The payment succeeded either way; a card that cannot be saved must not hold up the credit.
+			if err := s.saveCard(record.WalletID, email, verified.Card); err != nil {
+				log.Printf("deposit %s: card not saved: %v", record.Reference, err)
 			}
-			return err
 		}
-		senderWallet.Balance -= amount
-		destWallet.Balance += amount
+		return s.settleDeposit(record.Reference, models.TransactionSuccess, "", payload)
+	case "failed", "abandoned", "reversed":
+		return s.settleDeposit(record.Reference, models.TransactionFailed, "", payload)
+	default:
+		return nil // still in progress at the provider
+	}
+}
 
-		now := time.Now()
-		if err := tx.Save(&senderWallet).Error; err != nil {
+// settleDeposit moves a deposit to its final status, crediting the wallet through
+// the ledger on success. Deposits already credited or flagged are left untouched;
+// only pending deposits can fail or expire, while an expired one can still succeed.
+func (s *WalletService) settleDeposit(reference string, status models.TransactionStatus, reason string, payload []byte) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var record models.Transaction
+		if err := tx.Clauses(LockClause).First(&record, "reference = ?", reference).Error; err != nil {
 			return err
 		}
-		if err := tx.Save(&destWallet).Error; err != nil {
-			return err
+		if record.Status == models.TransactionSuccess || record.Status == models.TransactionFlagged {
+			return nil // idempotent
 		}
-		senderTx := models.Transaction{
-			ID:                 util.MustUUID(),
-			Reference:          fmt.Sprintf("TRF-%s", util.MustUUID()),
-			Type:               models.TransactionTypeTransfer,
-			Status:             models.TransactionSuccess,
-			Amount:             amount,
-			WalletID:           senderWallet.ID,
-			CounterpartyWallet: destWallet.Number,
-			Description:        "debit transfer",
-			CreatedAt:          now,
-			UpdatedAt:          now,
-		}
-		receiverTx := models.Transaction{
-			ID:                 util.MustUUID(),
-			Reference:          fmt.Sprintf("TRF-%s", util.MustUUID()),
-			Type:               models.TransactionTypeTransfer,
-			Status:             models.TransactionSuccess,
-			Amount:             amount,
-			WalletID:           destWallet.ID,
-			CounterpartyWallet: senderWallet.Number,
-			Description:        "credit transfer",
-			CreatedAt:          now,
-			UpdatedAt:          now,
-		}
-		if err := tx.Create(&senderTx).Error; err != nil {
-			return err
+		if (status == models.TransactionFailed || status == models.TransactionExpired) && record.Status != models.TransactionPending {
+			return nil
 		}
-		if err := tx.Create(&receiverTx).Error; err != nil {
-			return err
+		switch status {
+		case models.TransactionSuccess:
+			if record.Status == models.TransactionExpired {
+				log.Printf("deposit %s: payment confirmed after expiry, crediting", record.Reference)
+			}
+			var wallet models.Wallet
+			if err := tx.Clauses(LockClause).First(&wallet, "id = ?", record.WalletID).Error; err != nil {
+				return err
+			}
+			provider := record.Provider
+			if provider == "" {
+				provider = ProviderPaystack
+			}
+			clearing, err := s.ledger.SystemAccount(tx, models.GatewayClearingAccountCode(provider, wallet.Currency))
+			if err != nil {
+				return err
+			}
+			walletAccount, err := s.ledger.WalletAccount(tx, wallet.ID)
+			if err != nil {
+				return err
+			}
+			entry, err := s.ledger.Post(tx, record.Reference, provider+" deposit",
+				Debit(clearing.ID, record.Amount),
+				Credit(walletAccount.ID, record.Amount),
+			)
+			if err != nil {
+				return err
+			}
+			record.JournalEntryID = entry.ID
+		case models.TransactionFlagged, models.TransactionExpired:
+			record.Description = reason
 		}
-		return nil
+		record.Status = status
+		if payload != nil {
+			record.RawPayload = payload
+		}
+		record.UpdatedAt = time.Now()
+		return tx.Save(&record).Error
+	})
+}
+
+// MaxNarrationLength bounds caller-supplied transfer narrations and client references.
+const MaxNarrationLength = 255
+
+// TransferOptions carries optional caller-supplied details for a transfer.
+type TransferOptions struct {
+	Narration       string
+	ClientReference string
+	// Currency picks the sender's wallet; empty means the default-currency wallet.
+	Currency string
+}
+
+// Transfer moves balance between two wallets of the same currency atomically and
+// records one leg per wallet. Both legs share the returned transfer ID.
+func (s *WalletService) Transfer(sender *models.User, destWalletNumber string, amount int64, opts TransferOptions) (string, error) {
+	if sender == nil || sender.Wallet.ID == "" {
+		return "", errors.New("sender wallet not found")
+	}
+	if amount <= 0 {
+		return "", errors.New("amount must be greater than zero")
+	}
+	if len(opts.Narration) > MaxNarrationLength || len(opts.ClientReference) > MaxNarrationLength {
+		return "", fmt.Errorf("narration and client reference must be at most %d characters", MaxNarrationLength)
+	}
+	senderWallet, err := s.userWallet(sender, opts.Currency)
+	if err != nil {
+		return "", err
+	}
+	var transferID string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transferID, err = s.transferTx(tx, senderWallet.ID, destWalletNumber, amount, opts)
+		return err
 	})
+	if err != nil {
+		return "", err
+	}
+	return transferID, nil
+}
+
+// transferTx moves funds inside an existing DB transaction. The sender wallet is
+// locked, stale holds are expired, and only the available balance may be spent.
+func (s *WalletService) transferTx(tx *gorm.DB, senderWalletID, destWalletNumber string, amount int64, opts TransferOptions) (string, error) {
+	var senderWallet models.Wallet
+	if err := tx.Clauses(LockClause).First(&senderWallet, "id = ?", senderWalletID).Error; err != nil {
+		return "", err
+	}
+	if senderWallet.Number == destWalletNumber {
+		return "", errors.New("cannot transfer to the same wallet")
+	}
+	if err := s.expireHoldsTx(tx, &senderWallet); err != nil {
+		return "", err
+	}
+	if senderWallet.Available() < amount {
+		return "", errors.New("insufficient balance")
+	}
+	var destWallet models.Wallet
+	if err := tx.Clauses(LockClause).First(&destWallet, "number = ?", destWalletNumber).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.New("recipient wallet not found")
+		}
+		return "", err
+	}
+	if destWallet.Currency != senderWallet.Currency {
+		return "", ErrCurrencyMismatch
+	}
+	senderAccount, err := s.ledger.WalletAccount(tx, senderWallet.ID)
+	if err != nil {
+		return "", err
+	}
+	destAccount, err := s.ledger.WalletAccount(tx, destWallet.ID)
+	if err != nil {
+		return "", err
+	}
+	transferID := fmt.Sprintf("TRF-%s", util.MustUUID())
+	entry, err := s.ledger.Post(tx, transferID, "wallet transfer",
+		Debit(senderAccount.ID, amount),
+		Credit(destAccount.ID, amount),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	debitDesc, creditDesc := "debit transfer", "credit transfer"
+	if opts.Narration != "" {
+		debitDesc, creditDesc = opts.Narration, opts.Narration
+	}
+	now := time.Now()
+	senderTx := models.Transaction{
+		ID:                 util.MustUUID(),
+		Reference:          transferID + "-DR",
+		TransferID:         transferID,
+		Type:               models.TransactionTypeTransfer,
+		Status:             models.TransactionSuccess,
+		Amount:             amount,
+		Currency:           senderWallet.Currency,
+		WalletID:           senderWallet.ID,
+		CounterpartyWallet: destWallet.Number,
+		Direction:          models.EntryDebit,
+		Description:        debitDesc,
+		Narration:          opts.Narration,
+		ClientReference:    opts.ClientReference,
+		JournalEntryID:     entry.ID,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	receiverTx := models.Transaction{
+		ID:                 util.MustUUID(),
+		Reference:          transferID + "-CR",
+		TransferID:         transferID,
+		Type:               models.TransactionTypeTransfer,
+		Status:             models.TransactionSuccess,
+		Amount:             amount,
+		Currency:           destWallet.Currency,
+		WalletID:           destWallet.ID,
+		CounterpartyWallet: senderWallet.Number,
+		Direction:          models.EntryCredit,
+		Description:        creditDesc,
+		Narration:          opts.Narration,
+		ClientReference:    opts.ClientReference,
+		JournalEntryID:     entry.ID,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if err := tx.Create(&senderTx).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Create(&receiverTx).Error; err != nil {
+		return "", err
+	}
+	return transferID, nil
+}
+
+// TransferLegs returns both legs of a transfer, debit first, if one of the user's wallets is on either side.
+func (s *WalletService) TransferLegs(userID, transferID string) ([]models.Transaction, error) {
+	var walletIDs []string
+	if err := s.db.Model(&models.Wallet{}).Where("user_id = ?", userID).Pluck("id", &walletIDs).Error; err != nil {
+		return nil, err
+	}
+	var legs []models.Transaction
+	if err := s.db.Where("transfer_id = ? AND type = ?", transferID, models.TransactionTypeTransfer).
+		Order("direction desc").Find(&legs).Error; err != nil {
+		return nil, err
+	}
+	for _, leg := range legs {
+		for _, id := range walletIDs {
+			if leg.WalletID == id {
+				return legs, nil
+			}
+		}
+	}
+	return nil, errors.New("transfer not found")
 }
 
 // DepositStatus fetches a deposit transaction status.
@@ -177,24 +596,135 @@ func (s *WalletService) DepositStatus(reference string) (*models.Transaction, er
 	return &tx, nil
 }
 
-// Balance returns the wallet balance for a user.
-func (s *WalletService) Balance(userID string) (int64, error) {
-	var wallet models.Wallet
-	if err := s.db.First(&wallet, "user_id = ?", userID).Error; err != nil {
-		return 0, err
+// DepositStatusFor fetches a transaction by reference if it belongs to one of
+// the user's wallets, and fails with gorm.ErrRecordNotFound otherwise.
+func (s *WalletService) DepositStatusFor(userID, reference string) (*models.Transaction, error) {
+	var tx models.Transaction
+	if err := s.db.Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+		Where("transactions.reference = ? AND wallets.user_id = ?", reference, userID).
+		First(&tx).Error; err != nil {
+		return nil, err
 	}
-	return wallet.Balance, nil
+	return &tx, nil
 }
 
-// Transactions lists wallet transactions for a user ordered newest first.
-func (s *WalletService) Transactions(userID string) ([]models.Transaction, error) {
-	var wallet models.Wallet
-	if err := s.db.First(&wallet, "user_id = ?", userID).Error; err != nil {
+// Balance returns the user's wallet in currency (the default currency when empty)
+// with its ledger and held balances.
+func (s *WalletService) Balance(userID, currency string) (*models.Wallet, error) {
+	return s.WalletFor(userID, currency)
+}
+
+// Transaction history page size limits.
+const (
+	DefaultTransactionPageSize = 50
+	MaxTransactionPageSize     = 200
+)
+
+// TransactionFilter narrows a wallet's transaction history. Zero values are ignored.
+type TransactionFilter struct {
+	Type            models.TransactionType
+	Status          models.TransactionStatus
+	From            *time.Time
+	To              *time.Time
+	MinAmount       *int64
+	MaxAmount       *int64
+	Counterparty    string
+	ReferencePrefix string
+	Currency        string // selects the wallet; empty means the default currency
+	Cursor          string
+	Limit           int
+}
+
+// TransactionPage is one page of history plus the cursor for the next page.
+type TransactionPage struct {
+	Items      []models.Transaction
+	NextCursor string
+}
+
+// Transactions lists wallet transactions for a user newest first, one page at a time.
+func (s *WalletService) Transactions(userID string, filter TransactionFilter) (*TransactionPage, error) {
+	wallet, err := s.WalletFor(userID, filter.Currency)
+	if err != nil {
 		return nil, err
 	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultTransactionPageSize
+	}
+	if limit > MaxTransactionPageSize {
+		limit = MaxTransactionPageSize
+	}
+
+	q := s.db.Where("wallet_id = ?", wallet.ID)
+	if filter.Type != "" {
+		q = q.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		q = q.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		q = q.Where("created_at < ?", *filter.To)
+	}
+	if filter.MinAmount != nil {
+		q = q.Where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		q = q.Where("amount <= ?", *filter.MaxAmount)
+	}
+	if filter.Counterparty != "" {
+		q = q.Where("counterparty_wallet = ?", filter.Counterparty)
+	}
+	if filter.ReferencePrefix != "" {
+		q = q.Where("reference LIKE ? ESCAPE '\\'", likeEscaper.Replace(filter.ReferencePrefix)+"%")
+	}
+	if filter.Cursor != "" {
+		createdAt, id, err := decodeTransactionCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Where("created_at < ? OR (created_at = ? AND id < ?)", createdAt, createdAt, id)
+	}
+
 	var list []models.Transaction
-	if err := s.db.Where("wallet_id = ?", wallet.ID).Order("created_at desc").Find(&list).Error; err != nil {
+	// Fetch one extra row to learn whether another page exists.
+	if err := q.Order("created_at desc, id desc").Limit(limit + 1).Find(&list).Error; err != nil {
 		return nil, err
 	}
-	return list, nil
+	page := &TransactionPage{Items: list}
+	if len(list) > limit {
+		page.Items = list[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeTransactionCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+func encodeTransactionCursor(createdAt time.Time, id string) string {
+	raw := fmt.Sprintf("%d|%s", createdAt.UnixNano(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTransactionCursor(cursor string) (time.Time, string, error) {
+	invalid := ErrInvalidCursor
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", invalid
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return time.Time{}, "", invalid
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", invalid
+	}
+	return time.Unix(0, nanos), parts[1], nil
 }
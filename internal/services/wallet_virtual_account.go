@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVirtualAccountCurrency is returned when a virtual account is requested for a non-NGN wallet.
+var ErrVirtualAccountCurrency = errors.New("virtual accounts are only available for NGN wallets")
+
+// SetVirtualAccountBank sets the Paystack bank slug (PAYSTACK_DVA_BANK) dedicated
+// virtual accounts are opened at; Paystack test mode uses test-bank.
+func (s *WalletService) SetVirtualAccountBank(slug string) {
+	s.dvaBank = slug
+}
+
+// AssignVirtualAccount gives the user's wallet in currency a Paystack dedicated
+// virtual account, registering the user as a Paystack customer first. It returns
+// the wallet and whether a new account was assigned; a wallet that already has
+// one is returned unchanged.
+func (s *WalletService) AssignVirtualAccount(ctx context.Context, user *models.User, currency string) (*models.Wallet, bool, error) {
+	if user == nil {
+		return nil, false, errors.New("user not found")
+	}
+	if s.paystack == nil {
+		return nil, false, errors.New("paystack is not configured")
+	}
+	wallet, err := s.WalletFor(user.ID, currency)
+	if err != nil {
+		return nil, false, err
+	}
+	if wallet.VirtualAccountNumber != "" {
+		return wallet, false, nil
+	}
+	if wallet.Currency != models.DefaultCurrency {
+		return nil, false, ErrVirtualAccountCurrency
+	}
+	customerCode := wallet.PaystackCustomerCode
+	if customerCode == "" {
+		first, last, _ := strings.Cut(strings.TrimSpace(user.Name), " ")
+		if customerCode, err = s.paystack.CreateCustomer(ctx, user.Email, first, strings.TrimSpace(last)); err != nil {
+			return nil, false, err
+		}
+	}
+	account, err := s.paystack.CreateDedicatedAccount(ctx, customerCode, s.dvaBank)
+	if err != nil {
+		// Keep the customer code so a retry skips straight to the account.
+		if uerr := s.db.Model(wallet).Update("paystack_customer_code", customerCode).Error; uerr != nil {
+			log.Printf("wallet %s: saving paystack customer: %v", wallet.ID, uerr)
+		}
+		return nil, false, err
+	}
+	res := s.db.Model(&models.Wallet{}).
+		Where("id = ? AND COALESCE(virtual_account_number, '') = ''", wallet.ID).
+		Updates(map[string]interface{}{
+			"virtual_account_number": account.AccountNumber,
+			"virtual_account_name":   account.AccountName,
+			"virtual_account_bank":   account.BankName,
+			"paystack_customer_code": customerCode,
+			"updated_at":             time.Now(),
+		})
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	// A concurrent request may have stored the account first; either way reload it.
+	if err := s.db.First(wallet, "id = ?", wallet.ID).Error; err != nil {
+		return nil, false, err
+	}
+	return wallet, res.RowsAffected > 0, nil
+}
+
+// ApplyVirtualAccountDeposit credits a bank transfer into a dedicated virtual
+// account. There is no pending deposit to match, so the charge is verified with
+// Paystack and recorded under Paystack's reference, which makes redeliveries no-ops.
+func (s *WalletService) ApplyVirtualAccountDeposit(ctx context.Context, reference, accountNumber string, payload []byte) error {
+	var wallet models.Wallet
+	if err := s.db.First(&wallet, "virtual_account_number = ?", accountNumber).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("no wallet has virtual account %s", accountNumber)
+		}
+		return err
+	}
+	verified, err := s.paystack.VerifyTransaction(ctx, reference)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(verified.Status, "success") {
+		return nil
+	}
+	now := time.Now()
+	record := models.Transaction{
+		ID:          util.MustUUID(),
+		Reference:   reference,
+		Type:        models.TransactionTypeDeposit,
+		Status:      models.TransactionPending,
+		Amount:      verified.Amount,
+		Currency:    wallet.Currency,
+		WalletID:    wallet.ID,
+		Direction:   models.EntryCredit,
+		Provider:    ProviderPaystack,
+		Description: fmt.Sprintf("bank transfer to %s %s", wallet.VirtualAccountBank, accountNumber),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+		return err
+	}
+	if !strings.EqualFold(verified.Currency, wallet.Currency) {
+		reason := fmt.Sprintf("paystack verification mismatch: currency %s, expected %s", verified.Currency, wallet.Currency)
+		return s.settleDeposit(reference, models.TransactionFlagged, reason, payload)
+	}
+	return s.settleDeposit(reference, models.TransactionSuccess, "", payload)
+}
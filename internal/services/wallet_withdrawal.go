@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+)
+
+// Paystack transfer webhook events.
+const (
+	PaystackTransferSuccess  = "transfer.success"
+	PaystackTransferFailed   = "transfer.failed"
+	PaystackTransferReversed = "transfer.reversed"
+)
+
+// withdrawalPrefix starts every withdrawal reference. Paystack only accepts
+// lowercase transfer references, so it is sent and echoed back lowercased.
+const withdrawalPrefix = "WDR-"
+
+// ErrTransferOTPRequired means Paystack held a withdrawal's transfer for OTP
+// confirmation, which this service cannot give; transfer OTP must be disabled on
+// the Paystack integration.
+var ErrTransferOTPRequired = errors.New("paystack requires OTP confirmation for transfers; disable transfer OTP on the integration")
+
+// IsWithdrawalReference reports whether a Paystack reference belongs to a withdrawal.
+func IsWithdrawalReference(reference string) bool {
+	return strings.HasPrefix(strings.ToUpper(reference), withdrawalPrefix)
+}
+
+// AddRecipient resolves a bank account's holder name and registers it with
+// Paystack as a transfer recipient for the user.
+func (s *WalletService) AddRecipient(ctx context.Context, user *models.User, bankCode, accountNumber, currency string) (*models.BankRecipient, error) {
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if s.paystack == nil {
+		return nil, errors.New("paystack is not configured")
+	}
+	if bankCode == "" || accountNumber == "" {
+		return nil, errors.New("bank code and account number are required")
+	}
+	code, ok := models.NormalizeCurrency(currency)
+	if !ok {
+		return nil, fmt.Errorf("unsupported currency %s", code)
+	}
+	var existing models.BankRecipient
+	err := s.db.First(&existing, "user_id = ? AND bank_code = ? AND account_number = ? AND currency = ?",
+		user.ID, bankCode, accountNumber, code).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	name, err := s.paystack.ResolveAccount(ctx, accountNumber, bankCode)
+	if err != nil {
+		return nil, err
+	}
+	recipientCode, err := s.paystack.CreateTransferRecipient(ctx, name, accountNumber, bankCode, code)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	recipient := models.BankRecipient{
+		ID:            util.MustUUID(),
+		UserID:        user.ID,
+		Currency:      code,
+		BankCode:      bankCode,
+		AccountNumber: accountNumber,
+		AccountName:   name,
+		RecipientCode: recipientCode,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.db.Create(&recipient).Error; err != nil {
+		return nil, err
+	}
+	return &recipient, nil
+}
+
+// Recipients lists the user's saved bank accounts, newest first.
+func (s *WalletService) Recipients(userID string) ([]models.BankRecipient, error) {
+	var recipients []models.BankRecipient
+	if err := s.db.Where("user_id = ?", userID).Order("created_at desc").Find(&recipients).Error; err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+// InitiateWithdrawal holds amount on the wallet matching the recipient's currency,
+// records a pending withdrawal and asks Paystack to pay it out. The hold is
+// settled by ApplyWithdrawalWebhook; if Paystack rejects the request outright the
+// withdrawal fails and the hold is released immediately. If Paystack cannot be
+// reached mid-request the withdrawal stays pending, since it may have been queued.
+func (s *WalletService) InitiateWithdrawal(ctx context.Context, user *models.User, recipientID string, amount int64, reason string) (*models.Transaction, error) {
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if s.paystack == nil {
+		return nil, errors.New("paystack is not configured")
+	}
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	if len(reason) > MaxNarrationLength {
+		return nil, fmt.Errorf("reason must be at most %d characters", MaxNarrationLength)
+	}
+	var recipient models.BankRecipient
+	if err := s.db.First(&recipient, "id = ? AND user_id = ?", recipientID, user.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recipient not found")
+		}
+		return nil, err
+	}
+	wallet, err := s.WalletFor(user.ID, recipient.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := withdrawalPrefix + util.MustUUID()
+	description := reason
+	if description == "" {
+		description = fmt.Sprintf("withdrawal to %s", recipient.AccountNumber)
+	}
+	var record models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(LockClause).First(wallet, "id = ?", wallet.ID).Error; err != nil {
+			return err
+		}
+		if err := s.expireHoldsTx(tx, wallet); err != nil {
+			return err
+		}
+		if wallet.Available() < amount {
+			return errors.New("insufficient balance")
+		}
+		now := time.Now()
+		hold := models.Hold{
+			ID:           util.MustUUID(),
+			Reference:    fmt.Sprintf("HLD-%s", util.MustUUID()),
+			WalletID:     wallet.ID,
+			Currency:     wallet.Currency,
+			Amount:       amount,
+			Status:       models.HoldActive,
+			Description:  description,
+			WithdrawalID: reference,
+			ExpiresAt:    now.Add(MaxHoldTTL),
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if err := tx.Create(&hold).Error; err != nil {
+			return err
+		}
+		if err := s.adjustHeld(tx, wallet, amount); err != nil {
+			return err
+		}
+		record = models.Transaction{
+			ID:                 util.MustUUID(),
+			Reference:          reference,
+			Type:               models.TransactionTypeWithdrawal,
+			Status:             models.TransactionPending,
+			Amount:             amount,
+			Currency:           wallet.Currency,
+			WalletID:           wallet.ID,
+			CounterpartyWallet: recipient.RecipientCode,
+			Direction:          models.EntryDebit,
+			Description:        description,
+			Narration:          reason,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, status, err := s.paystack.InitiateTransfer(ctx, amount, wallet.Currency, recipient.RecipientCode, strings.ToLower(reference), description)
+	if err == nil && status == "otp" {
+		// The transfer will not move until finalized with an OTP; fail it now
+		// rather than holding the funds until the verification job gives up.
+		err = ErrTransferOTPRequired
+	}
+	if err != nil {
+		if IsGatewayError(err, GatewayUnavailable) && !errors.Is(err, ErrCircuitOpen) {
+			// Paystack may have queued the transfer before failing; keep the hold
+			// and let the transfer webhook settle it.
+			log.Printf("withdrawal %s: outcome unknown, left pending: %v", reference, err)
+			return &record, nil
+		}
+		if ferr := s.ApplyWithdrawalWebhook(reference, PaystackTransferFailed, nil); ferr != nil {
+			return nil, fmt.Errorf("%w (releasing hold: %v)", err, ferr)
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ApplyWithdrawalWebhook settles a withdrawal from a Paystack transfer.* event.
+// Success captures the hold and posts the payout; failure or reversal of a pending
+// withdrawal releases the hold; reversal of a paid withdrawal credits the wallet back.
+// Repeated events are ignored.
+func (s *WalletService) ApplyWithdrawalWebhook(reference, event string, payload []byte) error {
+	if IsWithdrawalReference(reference) {
+		reference = withdrawalPrefix + reference[len(withdrawalPrefix):]
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var record models.Transaction
+		if err := tx.Clauses(LockClause).First(&record, "reference = ?", reference).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("transaction reference not found")
+			}
+			return err
+		}
+		if record.Type != models.TransactionTypeWithdrawal {
+			return errors.New("reference is not a withdrawal transaction")
+		}
+		var wallet models.Wallet
+		if err := tx.Clauses(LockClause).First(&wallet, "id = ?", record.WalletID).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case record.Status == models.TransactionPending && event == PaystackTransferSuccess:
+			hold, err := s.withdrawalHoldTx(tx, reference)
+			if err != nil {
+				return err
+			}
+			if err := s.adjustHeld(tx, &wallet, -hold.Amount); err != nil {
+				return err
+			}
+			walletAccount, err := s.ledger.WalletAccount(tx, wallet.ID)
+			if err != nil {
+				return err
+			}
+			clearing, err := s.ledger.SystemAccount(tx, models.ClearingAccountCode(record.Currency))
+			if err != nil {
+				return err
+			}
+			entry, err := s.ledger.Post(tx, record.Reference, "paystack withdrawal",
+				Debit(walletAccount.ID, record.Amount),
+				Credit(clearing.ID, record.Amount),
+			)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(hold).Updates(map[string]interface{}{
+				"status":          models.HoldCaptured,
+				"captured_amount": hold.Amount,
+				"updated_at":      time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+			record.Status = models.TransactionSuccess
+			record.JournalEntryID = entry.ID
+
+		case record.Status == models.TransactionPending && (event == PaystackTransferFailed || event == PaystackTransferReversed):
+			hold, err := s.withdrawalHoldTx(tx, reference)
+			if err != nil {
+				return err
+			}
+			if err := s.finishHold(tx, &wallet, hold, models.HoldReleased); err != nil {
+				return err
+			}
+			record.Status = models.TransactionFailed
+
+		case record.Status == models.TransactionSuccess && event == PaystackTransferReversed:
+			if err := s.refundWithdrawalTx(tx, &record, payload); err != nil {
+				return err
+			}
+			record.Status = models.TransactionReversed
+
+		default:
+			return nil // already settled or not a state change
+		}
+		if payload != nil {
+			record.RawPayload = payload
+		}
+		record.UpdatedAt = time.Now()
+		return tx.Save(&record).Error
+	})
+}
+
+// withdrawalVerifyBatchSize bounds how many withdrawals one VerifyWithdrawals run checks.
+const withdrawalVerifyBatchSize = 100
+
+// VerifyWithdrawals asks Paystack about withdrawals still pending age after their
+// last check, for when the transfer webhook never arrived, and settles the ones
+// Paystack reports as final. A withdrawal Paystack never received failed, so its
+// hold is released. The rest are checked again on a later run, after the ones not
+// yet checked. It returns how many withdrawals were settled.
+func (s *WalletService) VerifyWithdrawals(ctx context.Context, now time.Time, age time.Duration) (int, error) {
+	if s.paystack == nil {
+		return 0, errors.New("paystack is not configured")
+	}
+	var pending []models.Transaction
+	if err := s.db.Where("type = ? AND status = ? AND updated_at <= ?",
+		models.TransactionTypeWithdrawal, models.TransactionPending, now.Add(-age)).
+		Order("updated_at").Limit(withdrawalVerifyBatchSize).Find(&pending).Error; err != nil {
+		return 0, err
+	}
+	settled := 0
+	for i := range pending {
+		if ctx.Err() != nil {
+			return settled, ctx.Err()
+		}
+		record := &pending[i]
+		event := ""
+		status, err := s.paystack.VerifyTransfer(ctx, strings.ToLower(record.Reference))
+		var gerr *GatewayError
+		switch {
+		case errors.As(err, &gerr) && gerr.Kind == GatewayValidation && gerr.StatusCode == http.StatusNotFound:
+			event = PaystackTransferFailed
+		case err != nil:
+			log.Printf("withdrawal %s: verify: %v", record.Reference, err)
+		case status == "success":
+			event = PaystackTransferSuccess
+		case status == "failed" || status == "abandoned" || status == "rejected":
+			event = PaystackTransferFailed
+		case status == "reversed":
+			event = PaystackTransferReversed
+		}
+		if event == "" {
+			if err := s.db.Model(&models.Transaction{}).
+				Where("id = ? AND status = ?", record.ID, models.TransactionPending).
+				UpdateColumn("updated_at", now).Error; err != nil {
+				return settled, err
+			}
+			continue
+		}
+		if err := s.ApplyWithdrawalWebhook(record.Reference, event, nil); err != nil {
+			return settled, err
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// withdrawalHoldTx locks the active hold reserving funds for a withdrawal.
+func (s *WalletService) withdrawalHoldTx(tx *gorm.DB, reference string) (*models.Hold, error) {
+	var hold models.Hold
+	if err := tx.Clauses(LockClause).
+		First(&hold, "withdrawal_id = ? AND status = ?", reference, models.HoldActive).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("withdrawal hold not found")
+		}
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// refundWithdrawalTx credits a paid-out withdrawal back to its wallet after Paystack reversed it.
+func (s *WalletService) refundWithdrawalTx(tx *gorm.DB, record *models.Transaction, payload []byte) error {
+	reversalRef := record.Reference + "-REV"
+	walletAccount, err := s.ledger.WalletAccount(tx, record.WalletID)
+	if err != nil {
+		return err
+	}
+	clearing, err := s.ledger.SystemAccount(tx, models.ClearingAccountCode(record.Currency))
+	if err != nil {
+		return err
+	}
+	entry, err := s.ledger.Post(tx, reversalRef, "paystack withdrawal reversal",
+		Debit(clearing.ID, record.Amount),
+		Credit(walletAccount.ID, record.Amount),
+	)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return tx.Create(&models.Transaction{
+		ID:                 util.MustUUID(),
+		Reference:          reversalRef,
+		OriginalReference:  record.Reference,
+		Type:               models.TransactionTypeReversal,
+		Status:             models.TransactionSuccess,
+		Amount:             record.Amount,
+		Currency:           record.Currency,
+		WalletID:           record.WalletID,
+		CounterpartyWallet: record.CounterpartyWallet,
+		Direction:          models.EntryCredit,
+		Description:        fmt.Sprintf("reversal of %s: paystack reversed the payout", record.Reference),
+		RawPayload:         payload,
+		JournalEntryID:     entry.ID,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}).Error
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Webhook inbox retry policy.
+const (
+	MaxWebhookAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = time.Hour
+	webhookBatchSize    = 100
+	webhookListMaxLimit = 200
+	// rejectedWebhookRetention is how long records of deliveries with a bad signature are kept.
+	rejectedWebhookRetention = 7 * 24 * time.Hour
+)
+
+// ErrWebhookNotReplayable is returned when replaying an event whose signature was invalid.
+var ErrWebhookNotReplayable = errors.New("events with an invalid signature cannot be replayed")
+
+// WebhookFilter narrows the inbox listing. Zero values are ignored.
+type WebhookFilter struct {
+	Status    models.WebhookEventStatus
+	EventType string
+	Reference string
+	Limit     int
+}
+
+// WebhookService stores inbound webhooks before acting on them and processes them
+// asynchronously with retries, so no event is lost to a bug or outage.
+type WebhookService struct {
+	db      *gorm.DB
+	wallets *WalletService
+}
+
+// NewWebhookService constructs a WebhookService.
+func NewWebhookService(db *gorm.DB, wallets *WalletService) *WebhookService {
+	return &WebhookService{db: db, wallets: wallets}
+}
+
+// Record stores a webhook from provider. It returns the stored event and whether
+// it duplicates one already received; duplicates are not stored again. Events with
+// an invalid signature are recorded as rejected, without their headers or payload,
+// and never processed.
+func (s *WebhookService) Record(provider string, headers map[string][]string, body []byte, signatureValid bool) (*models.WebhookEvent, bool, error) {
+	gateway, err := s.wallets.Gateway(provider)
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	event := models.WebhookEvent{
+		ID:             util.MustUUID(),
+		Provider:       gateway.Name(),
+		SignatureValid: signatureValid,
+		Status:         models.WebhookPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if !signatureValid {
+		// Anyone can post a forged delivery, so only its size and hash are kept. Its
+		// own ID as dedup key keeps it from claiming the key of a genuine event.
+		sum := sha256.Sum256(body)
+		event.EventID = "rejected:" + event.ID
+		event.Status = models.WebhookRejected
+		event.LastError = fmt.Sprintf("invalid signature (%d byte body, sha256 %s)", len(body), hex.EncodeToString(sum[:]))
+		return &event, false, s.db.Create(&event).Error
+	}
+	if event.Headers, err = json.Marshal(headers); err != nil {
+		return nil, false, err
+	}
+	event.Body = body
+	parsed, parseErr := gateway.ParseWebhook(body)
+	if parseErr == nil {
+		event.EventType = parsed.Type
+		event.Reference = parsed.Reference
+	}
+	event.EventID = webhookEventID(parsed, body)
+	if parseErr != nil {
+		event.Status = models.WebhookFailed
+		event.LastError = "invalid payload: " + parseErr.Error()
+	}
+
+	res := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		var existing models.WebhookEvent
+		if err := s.db.First(&existing, "provider = ? AND event_id = ?", event.Provider, event.EventID).Error; err != nil {
+			return nil, false, err
+		}
+		return &existing, true, nil
+	}
+	return &event, false, nil
+}
+
+// webhookEventID derives the dedup key: the provider's event ID, or a hash of the
+// payload for bodies without one.
+func webhookEventID(parsed *WebhookNotification, body []byte) string {
+	if parsed != nil && parsed.ID != "" {
+		return parsed.ID
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// PurgeRejected deletes records of rejected deliveries older than rejectedWebhookRetention.
+func (s *WebhookService) PurgeRejected(now time.Time) error {
+	return s.db.Where("status = ? AND created_at < ?", models.WebhookRejected, now.Add(-rejectedWebhookRetention)).
+		Delete(&models.WebhookEvent{}).Error
+}
+
+// ProcessDue attempts every pending event whose next attempt is due.
+func (s *WebhookService) ProcessDue(ctx context.Context, now time.Time) error {
+	var due []models.WebhookEvent
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", models.WebhookPending, now).
+		Order("created_at").Limit(webhookBatchSize).Find(&due).Error; err != nil {
+		return err
+	}
+	for _, event := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.process(ctx, event); err != nil {
+			log.Printf("webhook %s (%s): %v", event.ID, event.EventType, err)
+		}
+	}
+	return nil
+}
+
+// Replay resets a stored event and processes it immediately, returning the outcome.
+func (s *WebhookService) Replay(ctx context.Context, id string) (*models.WebhookEvent, error) {
+	event, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !event.SignatureValid {
+		return nil, ErrWebhookNotReplayable
+	}
+	now := time.Now()
+	if err := s.db.Model(&models.WebhookEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.WebhookPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"updated_at":      now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	event.Status, event.Attempts, event.NextAttemptAt = models.WebhookPending, 0, now
+	if err := s.process(ctx, *event); err != nil {
+		log.Printf("webhook %s replay: %v", id, err)
+	}
+	return s.Get(id)
+}
+
+// Get loads a stored event.
+func (s *WebhookService) Get(id string) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := s.db.First(&event, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook event not found")
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// List returns stored events newest first, without headers and bodies.
+func (s *WebhookService) List(filter WebhookFilter) ([]models.WebhookEvent, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > webhookListMaxLimit {
+		limit = webhookListMaxLimit
+	}
+	q := s.db.Omit("headers", "body")
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.EventType != "" {
+		q = q.Where("event_type = ?", filter.EventType)
+	}
+	if filter.Reference != "" {
+		q = q.Where("reference = ?", filter.Reference)
+	}
+	var events []models.WebhookEvent
+	if err := q.Order("created_at desc").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// process claims one attempt at an event, dispatches it, and records the outcome.
+// The claim pushes next_attempt_at out first, so a crash mid-dispatch is retried later.
+func (s *WebhookService) process(ctx context.Context, event models.WebhookEvent) error {
+	attempts := event.Attempts + 1
+	claim := s.db.Model(&models.WebhookEvent{}).
+		Where("id = ? AND status = ? AND attempts = ?", event.ID, models.WebhookPending, event.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": time.Now().Add(webhookBackoff(attempts)),
+			"updated_at":      time.Now(),
+		})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil // another worker took this attempt
+	}
+
+	dispatchErr := s.dispatch(ctx, event)
+	now := time.Now()
+	updates := map[string]interface{}{"updated_at": now}
+	switch {
+	case dispatchErr == nil:
+		updates["status"] = models.WebhookProcessed
+		updates["last_error"] = ""
+		updates["processed_at"] = now
+	case attempts >= MaxWebhookAttempts:
+		updates["status"] = models.WebhookFailed
+		updates["last_error"] = dispatchErr.Error()
+	default:
+		updates["last_error"] = dispatchErr.Error()
+	}
+	if err := s.db.Model(&models.WebhookEvent{}).Where("id = ?", event.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	return dispatchErr
+}
+
+// dispatch applies a provider event to wallets. Events we do not act on succeed as no-ops.
+func (s *WebhookService) dispatch(ctx context.Context, event models.WebhookEvent) error {
+	gateway, err := s.wallets.Gateway(event.Provider)
+	if err != nil {
+		return err
+	}
+	parsed, err := gateway.ParseWebhook(event.Body)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	if parsed.Reference == "" {
+		return errors.New("missing reference")
+	}
+	switch {
+	case event.Provider == ProviderPaystack && strings.HasPrefix(parsed.Type, "transfer."):
+		return s.wallets.ApplyWithdrawalWebhook(parsed.Reference, parsed.Type, event.Body)
+	case event.Provider == ProviderPaystack && strings.HasPrefix(parsed.Type, "refund."):
+		return s.wallets.ApplyRefundWebhook(parsed, event.Body)
+	case parsed.VirtualAccount != "":
+		return s.wallets.ApplyVirtualAccountDeposit(ctx, parsed.Reference, parsed.VirtualAccount, event.Body)
+	case parsed.ChargeStatus != "":
+		return s.wallets.ApplyDepositWebhook(ctx, parsed.Reference, parsed.ChargeStatus, event.Body)
+	default:
+		return nil
+	}
+}
+
+// webhookBackoff doubles the delay after each attempt, capped at webhookMaxBackoff.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxBackoff {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
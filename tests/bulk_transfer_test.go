@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBulkTransferAllOrNothingRollsBack(t *testing.T) {
+	db := newTestDB(t)
+	wallets := services.NewWalletService(db, nil)
+	bulk := services.NewBulkTransferService(db, wallets)
+
+	payer := seedUserWithWallet(db, "bulk-aon-payer@test.com", 10_000)
+	a := seedUserWithWallet(db, "bulk-aon-a@test.com", 0)
+	b := seedUserWithWallet(db, "bulk-aon-b@test.com", 0)
+
+	batch, err := bulk.Create(&payer, models.BatchAllOrNothing, "", []services.BulkTransferItem{
+		{WalletNumber: a.Wallet.Number, Amount: 4_000},
+		{WalletNumber: b.Wallet.Number, Amount: 4_000},
+		{WalletNumber: "does-not-exist", Amount: 1_000},
+	})
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	if batch.Status != models.BatchFailed || batch.SuccessCount != 0 {
+		t.Fatalf("expected failed batch with no successes, got %s/%d", batch.Status, batch.SuccessCount)
+	}
+	if batch.Items[0].Status != models.BatchItemRolledBack || batch.Items[2].Status != models.BatchItemFailed {
+		t.Fatalf("unexpected item statuses: %s, %s", batch.Items[0].Status, batch.Items[2].Status)
+	}
+	var w models.Wallet
+	_ = db.First(&w, "id = ?", payer.Wallet.ID).Error
+	if w.Balance != 10_000 {
+		t.Fatalf("expected payer balance untouched, got %d", w.Balance)
+	}
+}
+
+func TestBulkTransferBestEffortCommitsSuccesses(t *testing.T) {
+	db := newTestDB(t)
+	wallets := services.NewWalletService(db, nil)
+	bulk := services.NewBulkTransferService(db, wallets)
+
+	payer := seedUserWithWallet(db, "bulk-be-payer@test.com", 6_000)
+	a := seedUserWithWallet(db, "bulk-be-a@test.com", 0)
+	b := seedUserWithWallet(db, "bulk-be-b@test.com", 0)
+
+	batch, err := bulk.Create(&payer, models.BatchBestEffort, "", []services.BulkTransferItem{
+		{WalletNumber: a.Wallet.Number, Amount: 4_000, Narration: "salary"},
+		{WalletNumber: b.Wallet.Number, Amount: 4_000, Narration: "salary"},
+	})
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	if batch.Status != models.BatchPartiallyCompleted || batch.SuccessCount != 1 || batch.FailedCount != 1 {
+		t.Fatalf("unexpected batch outcome: %+v", batch)
+	}
+
+	stored, err := bulk.Get(payer.ID, batch.ID)
+	if err != nil {
+		t.Fatalf("get batch: %v", err)
+	}
+	if stored.Items[0].Status != models.BatchItemSuccess || stored.Items[0].TransferID == "" {
+		t.Fatalf("expected first item to succeed with a transfer ID, got %+v", stored.Items[0])
+	}
+	if stored.Items[1].Error != "insufficient balance" {
+		t.Fatalf("expected insufficient balance on second item, got %q", stored.Items[1].Error)
+	}
+	// The totals come from the saved items, even if the batch row was never updated.
+	db.Model(&models.TransferBatch{}).Where("id = ?", batch.ID).
+		Updates(map[string]interface{}{"status": models.BatchProcessing, "success_count": 0, "failed_count": 0})
+	if stored, _ := bulk.Get(payer.ID, batch.ID); stored.Status != models.BatchPartiallyCompleted || stored.SuccessCount != 1 || stored.FailedCount != 1 {
+		t.Fatalf("expected the outcome derived from the items, got %+v", stored)
+	}
+	if _, err := bulk.Get(a.ID, batch.ID); err == nil {
+		t.Fatalf("expected other users to be refused")
+	}
+}
+
+func TestBulkTransferCSVLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	const secret = "test-secret"
+	payer := seedUserWithWallet(db, "bulk-csv-payer@test.com", 10_000)
+	token := signIn(t, db, &payer, secret).AccessToken
+	r := gin.New()
+	r.POST("/wallet/bulk-transfers", middleware.AuthMiddleware(db, secret),
+		handlers.NewBulkTransferHandler(services.NewBulkTransferService(db, services.NewWalletService(db, nil))).Create)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallet/bulk-transfers", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(strings.Repeat("WAL-x,100,"+strings.Repeat("n", 200_000)+"\n", 6)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an oversized body, got %d %s", w.Code, w.Body.String())
+	}
+	w := post(strings.Repeat("WAL-x,100\n", services.MaxBulkTransferItems+1))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "at most") {
+		t.Fatalf("expected the item cap enforced while reading, got %d %s", w.Code, w.Body.String())
+	}
+	// Only the expected header is skipped; a mistyped first row is an error, not a header.
+	if w := post("WAL123,1o00\n"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "line 1") {
+		t.Fatalf("expected a line 1 error, got %d %s", w.Code, w.Body.String())
+	}
+	a := seedUserWithWallet(db, "bulk-csv-a@test.com", 0)
+	w = post("Wallet_Number,Amount,Narration\n" + a.Wallet.Number + ",2500,rent\n")
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"success_count":1`) {
+		t.Fatalf("expected the header skipped and the row paid, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+)
+
+func TestOpenWalletPerCurrency(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+	user := seedUserWithWallet(db, "fx-open@test.com", 0)
+
+	ghs, err := svc.OpenWallet(&user, "ghs")
+	if err != nil {
+		t.Fatalf("open GHS wallet: %v", err)
+	}
+	if ghs.Currency != "GHS" || ghs.Number == user.Wallet.Number {
+		t.Fatalf("unexpected wallet: %+v", ghs)
+	}
+	if _, err := svc.OpenWallet(&user, "GHS"); err == nil {
+		t.Fatalf("expected duplicate currency to be rejected")
+	}
+	if _, err := svc.OpenWallet(&user, "XYZ"); err == nil {
+		t.Fatalf("expected unsupported currency to be rejected")
+	}
+	wallets, err := svc.Wallets(user.ID)
+	if err != nil {
+		t.Fatalf("list wallets: %v", err)
+	}
+	if len(wallets) != 2 || wallets[0].Currency != models.DefaultCurrency {
+		t.Fatalf("expected default wallet first of two, got %+v", wallets)
+	}
+}
+
+func TestTransferRejectsCrossCurrency(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+	sender := seedUserWithWallet(db, "fx-sender@test.com", 10_000)
+	receiver := seedUserWithWallet(db, "fx-receiver@test.com", 0)
+
+	receiverUSD, err := svc.OpenWallet(&receiver, "USD")
+	if err != nil {
+		t.Fatalf("open USD wallet: %v", err)
+	}
+	if _, err := svc.Transfer(&sender, receiverUSD.Number, 1_000, services.TransferOptions{}); !errors.Is(err, services.ErrCurrencyMismatch) {
+		t.Fatalf("expected currency mismatch, got %v", err)
+	}
+
+	senderUSD, err := svc.OpenWallet(&sender, "USD")
+	if err != nil {
+		t.Fatalf("open USD wallet: %v", err)
+	}
+	db.Model(&models.Wallet{}).Where("id = ?", senderUSD.ID).Update("balance", 500)
+	if _, err := svc.Transfer(&sender, receiverUSD.Number, 300, services.TransferOptions{Currency: "USD"}); err != nil {
+		t.Fatalf("USD transfer: %v", err)
+	}
+	usd, _ := svc.Balance(receiver.ID, "USD")
+	ngn, _ := svc.Balance(sender.ID, "")
+	if usd.Balance != 300 || ngn.Balance != 10_000 {
+		t.Fatalf("expected only USD balances to move, got usd=%d ngn=%d", usd.Balance, ngn.Balance)
+	}
+}
+
+func TestDepositSendsWalletCurrency(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	svc := services.NewWalletService(db, paystack.Service)
+	user := seedUserWithWallet(db, "fx-deposit@test.com", 0)
+	kes, err := svc.OpenWallet(&user, "KES")
+	if err != nil {
+		t.Fatalf("open KES wallet: %v", err)
+	}
+
+	ref := paystack.checkout(t, svc, &user, 2_500, "KES")
+	if charge, err := paystack.Service.VerifyTransaction(context.Background(), ref); err != nil || charge.Currency != "KES" || charge.Amount != 2_500 {
+		t.Fatalf("expected KES to be sent to Paystack, got %+v err=%v", charge, err)
+	}
+	if err := paystack.Approve(ref); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if err := svc.ApplyDepositWebhook(context.Background(), ref, "success", []byte(`{}`)); err != nil {
+		t.Fatalf("apply webhook: %v", err)
+	}
+	wallet, _ := svc.Balance(user.ID, "KES")
+	if wallet.ID != kes.ID || wallet.Balance != 2_500 {
+		t.Fatalf("expected KES wallet credited, got %+v", wallet)
+	}
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// noRedirect returns the first response instead of following redirects.
+var noRedirect = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+func TestDepositCallbackRedirectsToAppAfterVerifying(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+
+	var api http.Handler
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { api.ServeHTTP(w, r) }))
+	t.Cleanup(app.Close)
+	var emuHandler http.Handler
+	emuServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { emuHandler.ServeHTTP(w, r) }))
+	t.Cleanup(emuServer.Close)
+	emu := paystackemu.New(paystackemu.Config{SecretKey: "sk_test_emulator", PublicURL: emuServer.URL})
+	emuHandler = emu.Handler()
+
+	paystack := services.NewPaystackService("sk_test_emulator", emuServer.URL)
+	wallets := services.NewWalletService(db, paystack)
+	wallets.SetDepositCallback(app.URL+"/", []string{"myapp://wallet", "https://shop.example.com/paid"})
+	router := gin.New()
+	router.GET(services.DepositCallbackPath, handlers.NewWalletHandler(wallets).DepositCallback)
+	api = router
+	user := seedUserWithWallet(db, "deposit-callback@test.com", 0)
+	ctx := context.Background()
+
+	for _, bad := range []string{"myapp://other", "https://shop.example.com/paid-evil", "https://evil.example.com/paid", "https://user@shop.example.com/paid"} {
+		if _, _, err := wallets.InitiateDeposit(ctx, &user, 1_000, "", services.DepositOptions{CallbackURL: bad}); !errors.Is(err, services.ErrCallbackURLNotAllowed) {
+			t.Fatalf("expected %s to be rejected, got %v", bad, err)
+		}
+	}
+	if _, _, err := wallets.InitiateDeposit(ctx, &user, 1_000, "", services.DepositOptions{Channels: []string{"crypto"}}); err == nil {
+		t.Fatal("expected an unknown channel to be rejected")
+	}
+	for _, key := range services.ReservedMetadataKeys {
+		opts := services.DepositOptions{Metadata: map[string]string{key: "https://evil.example.com"}}
+		if _, _, err := wallets.InitiateDeposit(ctx, &user, 1_000, "", opts); err == nil {
+			t.Fatalf("expected reserved metadata key %s to be rejected", key)
+		}
+	}
+
+	// No webhook is configured: the callback page itself verifies and credits the deposit.
+	deposit, authURL, err := wallets.InitiateDeposit(ctx, &user, 4_000, "", services.DepositOptions{
+		CallbackURL: "myapp://wallet/paid?screen=home",
+		Channels:    []string{"card", "bank_transfer"},
+		Metadata:    map[string]string{"order_id": "ORD-42", "user_id": "spoofed"},
+	})
+	if err != nil {
+		t.Fatalf("initiate deposit: %v", err)
+	}
+	resp, err := noRedirect.Post(authURL+"/approve", "", nil)
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusSeeOther || callback.Path != services.DepositCallbackPath || callback.Query().Get("reference") != deposit.Reference {
+		t.Fatalf("expected checkout to return to the callback page, got %d %s", resp.StatusCode, callback)
+	}
+	resp, err = noRedirect.Get(app.URL + callback.RequestURI())
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	resp.Body.Close()
+	target, _ := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || target.Scheme != "myapp" || target.Path != "/paid" ||
+		target.Query().Get("screen") != "home" || target.Query().Get("reference") != deposit.Reference || target.Query().Get("status") != "success" {
+		t.Fatalf("expected a deep link with the final status, got %d %s", resp.StatusCode, target)
+	}
+	if wallet, _ := wallets.Balance(user.ID, ""); wallet.Balance != 4_000 {
+		t.Fatalf("expected the callback to credit the deposit, got %d", wallet.Balance)
+	}
+	req, _ := http.NewRequest(http.MethodGet, emuServer.URL+"/transaction/verify/"+deposit.Reference, nil)
+	req.Header.Set("Authorization", "Bearer sk_test_emulator")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	defer resp.Body.Close()
+	var charge struct {
+		Data struct {
+			Metadata map[string]string `json:"metadata"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&charge); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if m := charge.Data.Metadata; m["order_id"] != "ORD-42" || m["user_id"] != user.ID ||
+		m["wallet_number"] != user.Wallet.Number || !strings.HasPrefix(m["cancel_action"], app.URL+services.DepositCallbackPath) {
+		t.Fatalf("expected the payment tagged at Paystack, got %v", m)
+	}
+
+	// Without a callback_url the page renders the outcome.
+	plain, _, err := wallets.InitiateDeposit(ctx, &user, 1_500, "", services.DepositOptions{})
+	if err != nil {
+		t.Fatalf("initiate deposit: %v", err)
+	}
+	if err := emu.Decline(plain.Reference); err != nil {
+		t.Fatalf("decline: %v", err)
+	}
+	page := fetch(t, http.MethodGet, app.URL+services.DepositCallbackPath+"?trxref="+plain.Reference)
+	if !strings.Contains(page, "Payment not completed") || !strings.Contains(page, "NGN 15.00") {
+		t.Fatalf("unexpected callback page: %s", page)
+	}
+	resp, err = http.Get(app.URL + services.DepositCallbackPath + "?reference=DEP-unknown")
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown reference, got %d", resp.StatusCode)
+	}
+}
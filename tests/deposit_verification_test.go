@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDepositFlaggedWhenPaystackAmountDiffers(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	svc := services.NewWalletService(db, paystack.Service)
+	user := seedUserWithWallet(db, "verify-flagged@test.com", 0)
+
+	// Paystack charged 100 under the reference of a 50000 deposit.
+	ref := seedPendingDeposit(t, db, user.Wallet.ID, 50_000)
+	if _, err := paystack.Service.InitializeTransaction(context.Background(), 100, "NGN", user.Email, ref, services.CheckoutOptions{}); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if err := paystack.Approve(ref); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if err := svc.ApplyDepositWebhook(context.Background(), ref, "success", []byte(`{}`)); err != nil {
+		t.Fatalf("apply webhook: %v", err)
+	}
+	record, _ := svc.DepositStatus(ref)
+	wallet, _ := svc.Balance(user.ID, "")
+	if record.Status != models.TransactionFlagged || wallet.Balance != 0 {
+		t.Fatalf("expected flagged and uncredited deposit, got %s with balance %d", record.Status, wallet.Balance)
+	}
+
+	if err := svc.ApplyDepositWebhook(context.Background(), ref, "success", []byte(`{}`)); err != nil {
+		t.Fatalf("replay webhook: %v", err)
+	}
+	if wallet, _ = svc.Balance(user.ID, ""); wallet.Balance != 0 {
+		t.Fatalf("expected flagged deposit to stay uncredited, got %d", wallet.Balance)
+	}
+}
+
+func TestDepositNotCreditedWhenPaystackDisagreesWithWebhook(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	svc := services.NewWalletService(db, paystack.Service)
+	user := seedUserWithWallet(db, "verify-abandoned@test.com", 0)
+
+	// A forged charge.success for a checkout the customer declined.
+	ref := paystack.checkout(t, svc, &user, 7_000, "")
+	if err := paystack.Decline(ref); err != nil {
+		t.Fatalf("decline: %v", err)
+	}
+	if err := svc.ApplyDepositWebhook(context.Background(), ref, "success", []byte(`{}`)); err != nil {
+		t.Fatalf("apply webhook: %v", err)
+	}
+	record, _ := svc.DepositStatus(ref)
+	wallet, _ := svc.Balance(user.ID, "")
+	if record.Status != models.TransactionFailed || wallet.Balance != 0 {
+		t.Fatalf("expected failed deposit, got %s with balance %d", record.Status, wallet.Balance)
+	}
+}
+
+func TestRefreshDepositCreditsVerifiedCharge(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	svc := services.NewWalletService(db, paystack.Service)
+	user := seedUserWithWallet(db, "verify-refresh@test.com", 0)
+	ref := paystack.checkout(t, svc, &user, 3_000, "")
+
+	record, err := svc.RefreshDeposit(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if record.Status != models.TransactionPending {
+		t.Fatalf("expected deposit to stay pending while Paystack is in progress, got %s", record.Status)
+	}
+
+	if err := paystack.Approve(ref); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if record, err = svc.RefreshDeposit(context.Background(), ref); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	wallet, _ := svc.Balance(user.ID, "")
+	if record.Status != models.TransactionSuccess || wallet.Balance != 3_000 {
+		t.Fatalf("expected credited deposit, got %s with balance %d", record.Status, wallet.Balance)
+	}
+}
+
+func TestDepositStatusOnlyShowsTheCallersDeposits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	var verified int32
+	paystack.setIntercept(func(_ http.ResponseWriter, r *http.Request) bool {
+		if strings.HasPrefix(r.URL.Path, "/transaction/verify/") {
+			atomic.AddInt32(&verified, 1)
+		}
+		return false
+	})
+	svc := services.NewWalletService(db, paystack.Service)
+	owner := seedUserWithWallet(db, "status-owner@test.com", 0)
+	other := seedUserWithWallet(db, "status-other@test.com", 0)
+	ref := paystack.paidCheckout(t, svc, &owner, 2_500)
+	r := gin.New()
+	r.GET("/wallet/deposit/:reference/status", middleware.AuthMiddleware(db, "test-secret"), middleware.RequirePermission("read"),
+		handlers.NewWalletHandler(svc).DepositStatus)
+	status := func(user *models.User) int {
+		req := httptest.NewRequest(http.MethodGet, "/wallet/deposit/"+ref+"/status?verify=true", nil)
+		req.Header.Set("Authorization", "Bearer "+signIn(t, db, user, "test-secret").AccessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := status(&other); code != http.StatusNotFound || atomic.LoadInt32(&verified) != 0 {
+		t.Fatalf("expected another user's deposit hidden and not verified, got %d after %d verifications", code, atomic.LoadInt32(&verified))
+	}
+	if code := status(&owner); code != http.StatusOK || atomic.LoadInt32(&verified) != 1 {
+		t.Fatalf("expected the owner's deposit verified, got %d after %d verifications", code, atomic.LoadInt32(&verified))
+	}
+	if wallet, _ := svc.Balance(owner.ID, ""); wallet.Balance != 2_500 {
+		t.Fatalf("expected the deposit credited, got %d", wallet.Balance)
+	}
+}
+
+func TestExpireDepositsSettlesOrExpiresStaleDeposits(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	svc := services.NewWalletService(db, paystack.Service)
+	user := seedUserWithWallet(db, "expiry-sweep@test.com", 0)
+
+	paid := paystack.paidCheckout(t, svc, &user, 1_000)
+	declined := paystack.checkout(t, svc, &user, 2_000, "")
+	unpaid := paystack.checkout(t, svc, &user, 4_000, "")
+	recent := paystack.checkout(t, svc, &user, 8_000, "")
+	if err := paystack.Decline(declined); err != nil {
+		t.Fatalf("decline: %v", err)
+	}
+	now := time.Now().Add(2 * time.Hour)
+	db.Model(&models.Transaction{}).Where("reference = ?", recent).Update("created_at", now.Add(-time.Minute))
+
+	if _, err := svc.ExpireDeposits(context.Background(), now, time.Hour); err != nil {
+		t.Fatalf("expire deposits: %v", err)
+	}
+	want := map[string]models.TransactionStatus{
+		paid:     models.TransactionSuccess,
+		declined: models.TransactionFailed,
+		unpaid:   models.TransactionExpired,
+		recent:   models.TransactionPending,
+	}
+	for ref, status := range want {
+		if record, _ := svc.DepositStatus(ref); record.Status != status {
+			t.Fatalf("deposit %s: expected %s, got %s", ref, status, record.Status)
+		}
+	}
+	if wallet, _ := svc.Balance(user.ID, ""); wallet.Balance != 1_000 {
+		t.Fatalf("expected only the paid deposit credited, got %d", wallet.Balance)
+	}
+
+	// The customer completes the expired checkout; the late webhook still credits it.
+	if err := paystack.Approve(unpaid); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if err := svc.ApplyDepositWebhook(context.Background(), unpaid, "success", []byte(`{}`)); err != nil {
+		t.Fatalf("late webhook: %v", err)
+	}
+	if record, _ := svc.DepositStatus(unpaid); record.Status != models.TransactionSuccess {
+		t.Fatalf("expected late payment to succeed, got %s", record.Status)
+	}
+	if wallet, _ := svc.Balance(user.ID, ""); wallet.Balance != 5_000 {
+		t.Fatalf("expected late payment credited, got %d", wallet.Balance)
+	}
+}
+
+func TestExpireDepositsLeavesDepositPendingWhenGatewayDown(t *testing.T) {
+	db := newTestDB(t)
+	paystack := services.NewPaystackService("sk_test", "http://127.0.0.1:1")
+	paystack.SetRetryPolicy(services.RetryPolicy{MaxAttempts: 1})
+	svc := services.NewWalletService(db, paystack)
+	user := seedUserWithWallet(db, "expiry-down@test.com", 0)
+	ref := seedPendingDeposit(t, db, user.Wallet.ID, 1_000)
+
+	now := time.Now().Add(2 * time.Hour)
+	if _, err := svc.ExpireDeposits(context.Background(), now, time.Hour); err != nil {
+		t.Fatalf("expire deposits: %v", err)
+	}
+	record, _ := svc.DepositStatus(ref)
+	if record.Status != models.TransactionPending {
+		t.Fatalf("expected deposit to stay pending while Paystack is unreachable, got %s", record.Status)
+	}
+	// The failed check moves it behind deposits that have not been checked yet.
+	if !record.UpdatedAt.Equal(now) {
+		t.Fatalf("expected the skipped deposit's updated_at bumped to %s, got %s", now, record.UpdatedAt)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var fastRetries = services.RetryPolicy{
+	MaxAttempts:      3,
+	BaseDelay:        time.Millisecond,
+	MaxDelay:         5 * time.Millisecond,
+	AttemptTimeout:   time.Second,
+	BreakerThreshold: 100,
+	BreakerCooldown:  time.Hour,
+}
+
+// flakyPaystack runs the emulator with a checkout for reference "R" and answers
+// with status for the first failures requests that follow.
+func flakyPaystack(t *testing.T, failures int32, status int, header http.Header) (*services.PaystackService, *int32) {
+	t.Helper()
+	emu := newEmulatedPaystack(t, paystackemu.Config{})
+	if _, err := emu.Service.InitializeTransaction(context.Background(), 100, "NGN", "a@b.c", "R", services.CheckoutOptions{}); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	var hits int32
+	emu.setIntercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if atomic.AddInt32(&hits, 1) > failures {
+			return false
+		}
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"status":false,"message":"try later"}`))
+		return true
+	})
+	emu.Service.SetRetryPolicy(fastRetries)
+	return emu.Service, &hits
+}
+
+func TestGatewayClientRetriesReadsButNotWritesWhenUnavailable(t *testing.T) {
+	ctx := context.Background()
+	paystack, hits := flakyPaystack(t, 2, http.StatusBadGateway, nil)
+	if _, err := paystack.VerifyTransaction(ctx, "R"); err != nil || atomic.LoadInt32(hits) != 3 {
+		t.Fatalf("expected verify to succeed on the third attempt, hits=%d err=%v", atomic.LoadInt32(hits), err)
+	}
+
+	paystack, hits = flakyPaystack(t, 2, http.StatusBadGateway, nil)
+	_, err := paystack.InitializeTransaction(ctx, 100, "NGN", "a@b.c", "DEP-retry", services.CheckoutOptions{})
+	var gerr *services.GatewayError
+	if !errors.As(err, &gerr) || gerr.Kind != services.GatewayUnavailable || gerr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected an unavailable gateway error, got %v", err)
+	}
+	if atomic.LoadInt32(hits) != 1 {
+		t.Fatalf("expected initialize not to be retried, hits=%d", atomic.LoadInt32(hits))
+	}
+}
+
+func TestGatewayClientRetriesRateLimitedWritesAndClassifiesAuth(t *testing.T) {
+	ctx := context.Background()
+	paystack, hits := flakyPaystack(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}})
+	if _, err := paystack.InitializeTransaction(ctx, 100, "NGN", "a@b.c", "DEP-429", services.CheckoutOptions{}); err != nil || atomic.LoadInt32(hits) != 2 {
+		t.Fatalf("expected a rate-limited initialize to be retried, hits=%d err=%v", atomic.LoadInt32(hits), err)
+	}
+
+	paystack, hits = flakyPaystack(t, 10, http.StatusUnauthorized, nil)
+	_, err := paystack.VerifyTransaction(ctx, "R")
+	if !services.IsGatewayError(err, services.GatewayAuth) || atomic.LoadInt32(hits) != 1 {
+		t.Fatalf("expected a single auth failure, hits=%d err=%v", atomic.LoadInt32(hits), err)
+	}
+}
+
+func TestGatewayCircuitOpensAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+	paystack, hits := flakyPaystack(t, 1_000, http.StatusServiceUnavailable, nil)
+	policy := fastRetries
+	policy.MaxAttempts, policy.BreakerThreshold = 1, 2
+	paystack.SetRetryPolicy(policy)
+
+	for i := 0; i < 2; i++ {
+		if _, err := paystack.VerifyTransaction(ctx, "R"); errors.Is(err, services.ErrCircuitOpen) {
+			t.Fatalf("circuit opened too early on call %d", i+1)
+		}
+	}
+	_, err := paystack.VerifyTransaction(ctx, "R")
+	if !errors.Is(err, services.ErrCircuitOpen) || !services.IsGatewayError(err, services.GatewayUnavailable) {
+		t.Fatalf("expected the open circuit to fail fast, got %v", err)
+	}
+	if atomic.LoadInt32(hits) != 2 {
+		t.Fatalf("expected no request while the circuit is open, hits=%d", atomic.LoadInt32(hits))
+	}
+}
+
+func TestDepositHandlerMapsGatewayOutageAndFailsPendingDeposit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	const secret = "test-secret"
+	paystack, _ := flakyPaystack(t, 1_000, http.StatusServiceUnavailable, nil)
+	user := seedUserWithWallet(db, "gateway-outage@test.com", 0)
+	token := signIn(t, db, &user, secret).AccessToken
+	r := gin.New()
+	r.POST("/wallet/deposit", middleware.AuthMiddleware(db, secret),
+		handlers.NewWalletHandler(services.NewWalletService(db, paystack)).Deposit)
+
+	req := httptest.NewRequest(http.MethodPost, "/wallet/deposit", strings.NewReader(`{"amount":5000}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"provider_error":"unavailable"`) {
+		t.Fatalf("expected 503 for a Paystack outage, got %d %s", w.Code, w.Body.String())
+	}
+
+	var deposits []models.Transaction
+	db.Where("wallet_id = ? AND type = ?", user.Wallet.ID, models.TransactionTypeDeposit).Find(&deposits)
+	if len(deposits) != 1 || deposits[0].Status != models.TransactionFailed {
+		t.Fatalf("expected the deposit to be marked failed, got %+v", deposits)
+	}
+}
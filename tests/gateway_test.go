@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// newFakeFlutterwave serves Flutterwave checkout links and verifies by echoing the
+// deposit recorded in the test DB, in major units as Flutterwave reports them.
+func newFakeFlutterwave(t *testing.T, db *gorm.DB, inits *[]map[string]interface{}) *services.FlutterwaveService {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/payments":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			*inits = append(*inits, body)
+			_, _ = w.Write([]byte(`{"status":"success","data":{"link":"https://checkout.flutterwave.test/x"}}`))
+		case "/transactions/verify_by_reference":
+			ref := r.URL.Query().Get("tx_ref")
+			var row struct {
+				Amount   int64
+				Currency string
+				Email    string
+			}
+			db.Table("transactions").
+				Select("transactions.amount, transactions.currency, users.email").
+				Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+				Joins("JOIN users ON users.id = wallets.user_id").
+				Where("transactions.reference = ?", ref).
+				Scan(&row)
+			fmt.Fprintf(w, `{"status":"success","data":{"status":"successful","tx_ref":%q,"amount":%d.%02d,"currency":%q,"customer":{"email":%q}}}`,
+				ref, row.Amount/100, row.Amount%100, row.Currency, row.Email)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return services.NewFlutterwaveService("FLWSECK_TEST", srv.URL, "flw-hash", "https://app.test/deposits/done")
+}
+
+func TestDepositFallsBackToNextGatewayAndCreditsFromItsWebhook(t *testing.T) {
+	db := newTestDB(t)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"status":false,"message":"service unavailable"}`))
+	}))
+	t.Cleanup(down.Close)
+	paystack := services.NewPaystackService("sk_test", down.URL)
+	var inits []map[string]interface{}
+	flutterwave := newFakeFlutterwave(t, db, &inits)
+
+	wallets := services.NewWalletService(db, paystack)
+	wallets.SetGateways(paystack, flutterwave)
+	inbox := services.NewWebhookService(db, wallets)
+	user := seedUserWithWallet(db, "gateway-fallback@test.com", 0)
+
+	deposit, authURL, err := wallets.InitiateDeposit(context.Background(), &user, 2_550, "", services.DepositOptions{})
+	if err != nil {
+		t.Fatalf("initiate deposit: %v", err)
+	}
+	if deposit.Provider != services.ProviderFlutterwave || authURL != "https://checkout.flutterwave.test/x" {
+		t.Fatalf("expected fallback to flutterwave, got %s %s", deposit.Provider, authURL)
+	}
+	if len(inits) != 1 || inits[0]["amount"] != 25.5 || inits[0]["tx_ref"] != deposit.Reference {
+		t.Fatalf("expected amount in major units and our reference, got %v", inits)
+	}
+	stored, _ := wallets.DepositStatus(deposit.Reference)
+	if stored.Provider != services.ProviderFlutterwave {
+		t.Fatalf("expected provider recorded on the deposit, got %q", stored.Provider)
+	}
+
+	body := []byte(fmt.Sprintf(`{"event":"charge.completed","data":{"id":920001,"tx_ref":%q,"status":"successful"}}`, deposit.Reference))
+	header := http.Header{"Verif-Hash": {"flw-hash"}}
+	if !flutterwave.VerifyWebhook(header, body) || flutterwave.VerifyWebhook(http.Header{"Verif-Hash": {"wrong"}}, body) {
+		t.Fatalf("expected only the configured secret hash to verify")
+	}
+	event, _, err := inbox.Record(services.ProviderFlutterwave, header, body, true)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if event.EventID != "charge.completed:920001" || event.Reference != deposit.Reference {
+		t.Fatalf("unexpected inbox event %+v", event)
+	}
+	if err := inbox.ProcessDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	wallet, _ := wallets.Balance(user.ID, "")
+	if wallet.Balance != 2_550 {
+		t.Fatalf("expected wallet credited 2550, got %d", wallet.Balance)
+	}
+	var clearing models.LedgerAccount
+	if err := db.First(&clearing, "code = ?", models.GatewayClearingAccountCode(services.ProviderFlutterwave, "NGN")).Error; err != nil {
+		t.Fatalf("expected a flutterwave clearing account: %v", err)
+	}
+}
+
+func TestDepositFailsWhenEveryGatewayFails(t *testing.T) {
+	db := newTestDB(t)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":false,"message":"service unavailable"}`))
+	}))
+	t.Cleanup(down.Close)
+	wallets := services.NewWalletService(db, services.NewPaystackService("sk_test", down.URL))
+	user := seedUserWithWallet(db, "gateway-down@test.com", 0)
+
+	if _, _, err := wallets.InitiateDeposit(context.Background(), &user, 1_000, "", services.DepositOptions{}); err == nil {
+		t.Fatalf("expected an error when no gateway can start a checkout")
+	}
+}
@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/auth"
+	"github.com/CyberwizD/Wallet-Service/internal/config"
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// fakeGoogle issues ID tokens signed with its own key for codes whose PKCE
+// verifier matches the challenge of the last sign-in redirect.
+type fakeGoogle struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	// claims adjusts the ID token of the next exchange.
+	claims func(jwt.MapClaims)
+}
+
+func newFakeGoogle(t *testing.T) *fakeGoogle {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	g := &fakeGoogle{key: key, claims: func(jwt.MapClaims) {}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /certs", func(w http.ResponseWriter, r *http.Request) {
+		e := big.NewInt(int64(key.E)).Bytes()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "test-key", "kty": "RSA", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(e),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.PostFormValue("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss": "https://accounts.google.com", "aud": "client-123", "sub": "1001",
+			"email": "oauth@test.com", "email_verified": true, "name": "OAuth User", "nonce": g.nonce,
+			"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+		}
+		g.claims(claims)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test-key"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			t.Errorf("sign id token: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access", "token_type": "Bearer", "expires_in": 3600, "id_token": idToken,
+		})
+	})
+	g.server = httptest.NewServer(mux)
+	t.Cleanup(g.server.Close)
+	return g
+}
+
+func newAuthRouter(t *testing.T, g *fakeGoogle, redirectURL string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{
+		JWTSecret:          "test-secret",
+		GoogleClientID:     "client-123",
+		GoogleClientSecret: "shh",
+		GoogleRedirectURL:  "http://localhost:8080/auth/google/callback",
+		AuthRedirectURL:    redirectURL,
+	}
+	db := newTestDB(t)
+	h := handlers.NewAuthHandler(cfg, services.NewUserService(db), services.NewSessionService(db, cfg.JWTSecret))
+	h.SetGoogleEndpoints(oauth2.Endpoint{
+		AuthURL:  g.server.URL + "/auth",
+		TokenURL: g.server.URL + "/token",
+	}, g.server.URL+"/certs")
+	r := gin.New()
+	r.GET("/auth/google", h.StartGoogleAuth)
+	r.GET("/auth/google/callback", h.GoogleCallback)
+	r.POST("/auth/refresh", h.Refresh)
+	r.POST("/auth/logout", h.Logout)
+	return r
+}
+
+// startSignIn follows /auth/google and returns the state sent to Google and the state cookie.
+func startSignIn(t *testing.T, r *gin.Engine, g *fakeGoogle) (string, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/google", nil))
+	location, _ := url.Parse(w.Header().Get("Location"))
+	query := location.Query()
+	if w.Code != http.StatusFound || query.Get("code_challenge_method") != "S256" || query.Get("nonce") == "" || query.Get("state") == "" {
+		t.Fatalf("expected a PKCE redirect with state and nonce, got %d %s", w.Code, location)
+	}
+	g.challenge, g.nonce = query.Get("code_challenge"), query.Get("nonce")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("expected one HttpOnly SameSite=Lax state cookie, got %+v", cookies)
+	}
+	return query.Get("state"), cookies[0]
+}
+
+func callback(r *gin.Engine, query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?"+query, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGoogleSignInChecksStatePKCEAndIDToken(t *testing.T) {
+	g := newFakeGoogle(t)
+	r := newAuthRouter(t, g, "")
+
+	state, cookie := startSignIn(t, r, g)
+	w := callback(r, "code=good-code&state="+url.QueryEscape(state), cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected sign-in, got %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if claims, err := auth.ParseToken(resp.Token, "test-secret"); err != nil || claims.Email != "oauth@test.com" {
+		t.Fatalf("expected a JWT for the ID token's email, got %+v err=%v", claims, err)
+	}
+	if cleared := w.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Fatalf("expected the state cookie cleared, got %+v", cleared)
+	}
+
+	cases := []struct {
+		name   string
+		query  func(state string) string
+		cookie bool
+		claims func(jwt.MapClaims)
+		status int
+	}{
+		{"forged state", func(string) string { return "code=good-code&state=attacker" }, true, nil, http.StatusBadRequest},
+		{"missing cookie", func(s string) string { return "code=good-code&state=" + url.QueryEscape(s) }, false, nil, http.StatusBadRequest},
+		{"injected code", func(s string) string { return "code=stolen&state=" + url.QueryEscape(s) }, true, nil, http.StatusBadRequest},
+		{"wrong audience", func(s string) string { return "code=good-code&state=" + url.QueryEscape(s) }, true,
+			func(c jwt.MapClaims) { c["aud"] = "another-client" }, http.StatusUnauthorized},
+		{"wrong issuer", func(s string) string { return "code=good-code&state=" + url.QueryEscape(s) }, true,
+			func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, http.StatusUnauthorized},
+		{"replayed nonce", func(s string) string { return "code=good-code&state=" + url.QueryEscape(s) }, true,
+			func(c jwt.MapClaims) { c["nonce"] = "old-nonce" }, http.StatusUnauthorized},
+		{"unverified email", func(s string) string { return "code=good-code&state=" + url.QueryEscape(s) }, true,
+			func(c jwt.MapClaims) { c["email_verified"] = false }, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		g.claims = func(jwt.MapClaims) {}
+		if tc.claims != nil {
+			g.claims = tc.claims
+		}
+		state, cookie := startSignIn(t, r, g)
+		if !tc.cookie {
+			cookie = nil
+		}
+		if w := callback(r, tc.query(state), cookie); w.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGoogleSignInRedirectsToFrontend(t *testing.T) {
+	g := newFakeGoogle(t)
+	r := newAuthRouter(t, g, "https://app.example.com/auth/done")
+
+	state, cookie := startSignIn(t, r, g)
+	w := callback(r, "code=good-code&state="+url.QueryEscape(state), cookie)
+	location, _ := url.Parse(w.Header().Get("Location"))
+	fragment, _ := url.ParseQuery(location.Fragment)
+	if w.Code != http.StatusFound || location.Host != "app.example.com" || location.RawQuery != "" || fragment.Get("token") == "" {
+		t.Fatalf("expected the JWT in the redirect fragment, got %d %s", w.Code, location)
+	}
+	if fragment.Has("refresh_token") {
+		t.Fatalf("expected the refresh token kept out of the fragment, got %s", location)
+	}
+	refresh := refreshCookie(w)
+	if refresh == nil || !refresh.HttpOnly || refresh.Path != "/auth" || refresh.SameSite != http.SameSiteStrictMode || refresh.MaxAge <= 0 {
+		t.Fatalf("expected the refresh token in an HttpOnly cookie, got %+v", w.Result().Cookies())
+	}
+
+	// The frontend refreshes with the cookie alone; the rotated token goes back into the cookie.
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(refresh)
+	rotated := httptest.NewRecorder()
+	r.ServeHTTP(rotated, req)
+	var body map[string]interface{}
+	_ = json.Unmarshal(rotated.Body.Bytes(), &body)
+	next := refreshCookie(rotated)
+	if rotated.Code != http.StatusOK || body["token"] == nil || body["refresh_token"] != nil || next == nil || next.Value == refresh.Value {
+		t.Fatalf("expected a cookie refresh, got %d %s %+v", rotated.Code, rotated.Body.String(), next)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.AddCookie(next)
+	out := httptest.NewRecorder()
+	r.ServeHTTP(out, req)
+	if cleared := refreshCookie(out); out.Code != http.StatusOK || cleared == nil || cleared.MaxAge >= 0 {
+		t.Fatalf("expected logout to clear the refresh cookie, got %d %s", out.Code, out.Body.String())
+	}
+
+	// The state cookie is spent, so replaying the callback fails back to the frontend.
+	w = callback(r, "code=good-code&state="+url.QueryEscape(state), nil)
+	location, _ = url.Parse(w.Header().Get("Location"))
+	if fragment, _ := url.ParseQuery(location.Fragment); w.Code != http.StatusFound || fragment.Get("error") != "invalid oauth state" {
+		t.Fatalf("expected an error redirect, got %d %s", w.Code, location)
+	}
+}
+
+func refreshCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "refresh_token" {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+)
+
+func TestHoldReducesAvailableAndCapturesPartially(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+
+	buyer := seedUserWithWallet(db, "hold-buyer@test.com", 10_000)
+	seller := seedUserWithWallet(db, "hold-seller@test.com", 0)
+
+	hold, err := svc.PlaceHold(&buyer, "", 8_000, time.Hour, "order 17")
+	if err != nil {
+		t.Fatalf("place hold: %v", err)
+	}
+	if _, err := svc.Transfer(&buyer, seller.Wallet.Number, 3_000, services.TransferOptions{}); err == nil {
+		t.Fatalf("expected transfer beyond available balance to fail")
+	}
+
+	transferID, err := svc.CaptureHold(&buyer, hold.ID, seller.Wallet.Number, 6_000, services.TransferOptions{Narration: "order 17"})
+	if err != nil {
+		t.Fatalf("capture hold: %v", err)
+	}
+	if transferID == "" {
+		t.Fatalf("expected capture to return a transfer ID")
+	}
+
+	wallet, err := svc.Balance(buyer.ID, "")
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if wallet.Balance != 4_000 || wallet.HeldBalance != 0 || wallet.Available() != 4_000 {
+		t.Fatalf("unexpected buyer wallet after capture: balance %d held %d", wallet.Balance, wallet.HeldBalance)
+	}
+	var captured models.Hold
+	_ = db.First(&captured, "id = ?", hold.ID).Error
+	if captured.Status != models.HoldCaptured || captured.CapturedAmount != 6_000 {
+		t.Fatalf("unexpected hold after capture: %+v", captured)
+	}
+	if err := svc.ReleaseHold(&buyer, hold.ID); err == nil {
+		t.Fatalf("expected releasing a captured hold to fail")
+	}
+}
+
+func TestExpiredHoldFreesFunds(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+
+	user := seedUserWithWallet(db, "hold-expiry@test.com", 5_000)
+	hold, err := svc.PlaceHold(&user, "", 5_000, time.Hour, "")
+	if err != nil {
+		t.Fatalf("place hold: %v", err)
+	}
+	if err := db.Model(&models.Hold{}).Where("id = ?", hold.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("backdate hold: %v", err)
+	}
+	if err := svc.ExpireHolds(); err != nil {
+		t.Fatalf("expire holds: %v", err)
+	}
+	wallet, _ := svc.Balance(user.ID, "")
+	if wallet.HeldBalance != 0 || wallet.Available() != 5_000 {
+		t.Fatalf("expected hold released on expiry, held %d", wallet.HeldBalance)
+	}
+	var expired models.Hold
+	_ = db.First(&expired, "id = ?", hold.ID).Error
+	if expired.Status != models.HoldExpired {
+		t.Fatalf("expected expired status, got %s", expired.Status)
+	}
+}
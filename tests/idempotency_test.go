@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTransferIdempotencyKeyReplaysResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	const secret = "test-secret"
+
+	sender := seedUserWithWallet(db, "idem-sender@test.com", 10_000)
+	receiver := seedUserWithWallet(db, "idem-receiver@test.com", 0)
+	token := signIn(t, db, &sender, secret).AccessToken
+
+	walletHandler := handlers.NewWalletHandler(services.NewWalletService(db, nil))
+	r := gin.New()
+	r.POST("/wallet/transfer",
+		middleware.AuthMiddleware(db, secret),
+		middleware.Idempotency(services.NewIdempotencyService(db)),
+		walletHandler.Transfer,
+	)
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallet/transfer", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"wallet_number":"` + receiver.Wallet.Number + `","amount":3000}`
+	first := send("retry-1", body)
+	if first.Code != http.StatusOK {
+		t.Fatalf("first transfer: %d %s", first.Code, first.Body.String())
+	}
+	second := send("retry-1", body)
+	if second.Code != http.StatusOK || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected replayed response, got %d %v", second.Code, second.Header())
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Fatalf("replayed body %q differs from original %q", second.Body.String(), first.Body.String())
+	}
+
+	var wallet models.Wallet
+	_ = db.First(&wallet, "id = ?", sender.Wallet.ID).Error
+	if wallet.Balance != 7_000 {
+		t.Fatalf("expected a single debit leaving 7000, got %d", wallet.Balance)
+	}
+
+	conflict := send("retry-1", `{"wallet_number":"`+receiver.Wallet.Number+`","amount":1000}`)
+	if conflict.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for reused key, got %d", conflict.Code)
+	}
+}
+
+func TestIdempotencyKeyReleasedWhenHandlerPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	const secret = "test-secret"
+	user := seedUserWithWallet(db, "idem-panic@test.com", 0)
+	token := signIn(t, db, &user, secret).AccessToken
+
+	calls := 0
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	r.POST("/wallet/transfer",
+		middleware.AuthMiddleware(db, secret),
+		middleware.Idempotency(services.NewIdempotencyService(db)),
+		func(c *gin.Context) {
+			if calls++; calls == 1 {
+				panic("boom")
+			}
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		},
+	)
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/wallet/transfer", strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(middleware.IdempotencyHeader, "panic-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := send(); code != http.StatusInternalServerError {
+		t.Fatalf("expected the panic recovered as 500, got %d", code)
+	}
+	if code := send(); code != http.StatusOK || calls != 2 {
+		t.Fatalf("expected the retry to run the handler again, got %d after %d calls", code, calls)
+	}
+}
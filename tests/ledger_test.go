@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+)
+
+func TestDepositAndTransferPostBalancedEntries(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	svc := services.NewWalletService(db, paystack.Service)
+	ledger := services.NewLedgerService(db)
+
+	sender := seedUserWithWallet(db, "ledger-sender@test.com", 0)
+	receiver := seedUserWithWallet(db, "ledger-receiver@test.com", 0)
+
+	ref := paystack.paidCheckout(t, svc, &sender, 8_000)
+	if err := svc.ApplyDepositWebhook(context.Background(), ref, "success", []byte(`{}`)); err != nil {
+		t.Fatalf("apply deposit: %v", err)
+	}
+	if _, err := svc.Transfer(&sender, receiver.Wallet.Number, 3_000, services.TransferOptions{}); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		walletID string
+		want     int64
+	}{
+		{sender.Wallet.ID, 5_000},
+		{receiver.Wallet.ID, 3_000},
+	} {
+		var wallet models.Wallet
+		_ = db.First(&wallet, "id = ?", tc.walletID).Error
+		if wallet.Balance != tc.want {
+			t.Fatalf("expected wallet balance %d, got %d", tc.want, wallet.Balance)
+		}
+		account, err := ledger.WalletAccount(db, tc.walletID)
+		if err != nil {
+			t.Fatalf("wallet account: %v", err)
+		}
+		fromLines, err := ledger.AccountBalanceFromLines(account.ID)
+		if err != nil {
+			t.Fatalf("ledger balance: %v", err)
+		}
+		if fromLines != wallet.Balance {
+			t.Fatalf("ledger balance %d does not match wallet balance %d", fromLines, wallet.Balance)
+		}
+	}
+
+	debits, credits, err := ledger.TrialBalance()
+	if err != nil {
+		t.Fatalf("trial balance: %v", err)
+	}
+	if debits != credits {
+		t.Fatalf("ledger out of balance: debits %d, credits %d", debits, credits)
+	}
+}
+
+func TestLedgerRejectsUnbalancedEntry(t *testing.T) {
+	db := newTestDB(t)
+	ledger := services.NewLedgerService(db)
+	fees, err := ledger.SystemAccount(db, models.SystemAccountFees)
+	if err != nil {
+		t.Fatalf("fees account: %v", err)
+	}
+	clearing, err := ledger.SystemAccount(db, models.SystemAccountPaystackClearing)
+	if err != nil {
+		t.Fatalf("clearing account: %v", err)
+	}
+	if _, err := ledger.Post(db, "UNBALANCED-"+util.MustUUID(), "bad",
+		services.Debit(clearing.ID, 100),
+		services.Credit(fees.ID, 90),
+	); err == nil {
+		t.Fatalf("expected unbalanced entry to be rejected")
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPaystackEmulatorDepositAndWithdrawalEndToEnd(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+
+	var api http.Handler
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { api.ServeHTTP(w, r) }))
+	t.Cleanup(app.Close)
+	var emuHandler http.Handler
+	emuServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { emuHandler.ServeHTTP(w, r) }))
+	t.Cleanup(emuServer.Close)
+	emuHandler = paystackemu.New(paystackemu.Config{
+		SecretKey:       "sk_test_emulator",
+		WebhookURL:      app.URL + "/wallet/paystack/webhook",
+		PublicURL:       emuServer.URL,
+		TransferOutcome: paystackemu.TransferOutcomeManual,
+	}).Handler()
+
+	paystack := services.NewPaystackService("sk_test_emulator", emuServer.URL)
+	wallets := services.NewWalletService(db, paystack)
+	inbox := services.NewWebhookService(db, wallets)
+	router := gin.New()
+	router.POST("/wallet/paystack/webhook", handlers.NewWebhookHandler(inbox).Receive(paystack))
+	api = router
+	user := seedUserWithWallet(db, "emulator-e2e@test.com", 0)
+
+	// Approve a checkout: the emulator sends a signed charge.success the worker applies.
+	deposit, authURL, err := wallets.InitiateDeposit(context.Background(), &user, 7_500, "", services.DepositOptions{})
+	if err != nil {
+		t.Fatalf("initiate deposit: %v", err)
+	}
+	page := fetch(t, http.MethodGet, authURL)
+	if !strings.Contains(page, "NGN 75.00") || !strings.Contains(page, "Approve payment") {
+		t.Fatalf("unexpected checkout page: %s", page)
+	}
+	if page := fetch(t, http.MethodPost, authURL+"/approve"); !strings.Contains(page, "Payment success.") {
+		t.Fatalf("expected approval page, got: %s", page)
+	}
+	if err := inbox.ProcessDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if wallet, _ := wallets.Balance(user.ID, ""); wallet.Balance != 7_500 {
+		t.Fatalf("expected deposit %s credited, got balance %d", deposit.Reference, wallet.Balance)
+	}
+
+	// Decline a checkout: no webhook, but verification reports the failure.
+	declined, declineURL, err := wallets.InitiateDeposit(context.Background(), &user, 1_000, "", services.DepositOptions{})
+	if err != nil {
+		t.Fatalf("initiate deposit: %v", err)
+	}
+	fetch(t, http.MethodPost, declineURL+"/decline")
+	refreshed, err := wallets.RefreshDeposit(context.Background(), declined.Reference)
+	if err != nil || refreshed.Status != models.TransactionFailed {
+		t.Fatalf("expected declined deposit to fail, got %+v err=%v", refreshed, err)
+	}
+
+	// Withdraw and settle the transfer through the emulator's control endpoint.
+	recipient, err := wallets.AddRecipient(context.Background(), &user, "058", "0123456789", "NGN")
+	if err != nil || recipient.AccountName != "EMULATED ACCOUNT 6789" {
+		t.Fatalf("add recipient: %+v err=%v", recipient, err)
+	}
+	withdrawal, err := wallets.InitiateWithdrawal(context.Background(), &user, recipient.ID, 2_000, "rent")
+	if err != nil {
+		t.Fatalf("withdraw: %v", err)
+	}
+	fetch(t, http.MethodPost, emuServer.URL+"/emulator/transfers/"+strings.ToLower(withdrawal.Reference)+"/success")
+	if err := inbox.ProcessDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	wallet, _ := wallets.Balance(user.ID, "")
+	settled, _ := wallets.DepositStatus(withdrawal.Reference)
+	if wallet.Balance != 5_500 || wallet.HeldBalance != 0 || settled.Status != models.TransactionSuccess {
+		t.Fatalf("expected withdrawal paid out, got balance %d held %d status %s", wallet.Balance, wallet.HeldBalance, settled.Status)
+	}
+}
+
+func fetch(t *testing.T, method, url string) string {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: status %d: %s", method, url, resp.StatusCode, body)
+	}
+	return string(body)
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+)
+
+func TestReconciliationReportsDrift(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	wallets := services.NewWalletService(db, paystack.Service)
+	recon := services.NewReconciliationService(db)
+
+	funder := seedUserWithWallet(db, "recon-funder@test.com", 0)
+	clean := seedUserWithWallet(db, "recon-clean@test.com", 0)
+	drifted := seedUserWithWallet(db, "recon-drifted@test.com", 0)
+
+	// Fund through the ledger so the funder's history explains its balance.
+	ref := paystack.paidCheckout(t, wallets, &funder, 10_000)
+	if err := wallets.ApplyDepositWebhook(context.Background(), ref, "success", []byte(`{}`)); err != nil {
+		t.Fatalf("apply deposit: %v", err)
+	}
+	if _, err := wallets.Transfer(&funder, clean.Wallet.Number, 4_000, services.TransferOptions{}); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	// Simulate a manual DB edit.
+	if err := db.Model(&models.Wallet{}).Where("id = ?", drifted.Wallet.ID).Update("balance", 999).Error; err != nil {
+		t.Fatalf("edit balance: %v", err)
+	}
+
+	report, err := recon.Run(services.ReconciliationTriggerManual)
+	if err != nil {
+		t.Fatalf("reconciliation failed: %v", err)
+	}
+	found := map[string]services.BalanceMismatch{}
+	for _, m := range report.Mismatches {
+		found[m.WalletID] = m
+	}
+	for _, id := range []string{funder.Wallet.ID, clean.Wallet.ID} {
+		if m, ok := found[id]; ok {
+			t.Fatalf("wallet %s unexpectedly drifted: %+v", id, m)
+		}
+	}
+	m, ok := found[drifted.Wallet.ID]
+	if !ok {
+		t.Fatalf("expected drifted wallet in report")
+	}
+	if m.Drift != 999 || m.ComputedBalance != 0 {
+		t.Fatalf("unexpected mismatch: %+v", m)
+	}
+
+	stored, err := recon.Report(report.RunID)
+	if err != nil {
+		t.Fatalf("load report: %v", err)
+	}
+	if len(stored.Mismatches) != len(report.Mismatches) {
+		t.Fatalf("stored report has %d mismatches, want %d", len(stored.Mismatches), len(report.Mismatches))
+	}
+}
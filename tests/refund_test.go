@@ -0,0 +1,231 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+)
+
+func TestDepositRefundLifecycle(t *testing.T) {
+	f := newCardFixture(t, "refund-lifecycle@test.com")
+	ctx := context.Background()
+	var deposit models.Transaction
+	if err := f.db.First(&deposit, "wallet_id = ? AND type = ?", f.user.Wallet.ID, models.TransactionTypeDeposit).Error; err != nil {
+		t.Fatalf("load deposit: %v", err)
+	}
+
+	partial, err := f.wallets.RefundDeposit(ctx, services.RefundRequest{
+		DepositReference: deposit.Reference, Amount: 2_000, Operator: "ops@test.com", Reason: "customer request",
+	})
+	if err != nil || partial.Status != models.TransactionPending || partial.ProviderReference == "" {
+		t.Fatalf("refund: %+v err=%v", partial, err)
+	}
+	wallet, _ := f.wallets.Balance(f.user.ID, "")
+	if wallet.Balance != 5_000 || wallet.Available() != 3_000 {
+		t.Fatalf("expected the refund held, got balance %d available %d", wallet.Balance, wallet.Available())
+	}
+	if _, err := f.wallets.RefundDeposit(ctx, services.RefundRequest{
+		DepositReference: deposit.Reference, Amount: 3_500, Reason: "too much",
+	}); !errors.Is(err, services.ErrRefundExceedsDeposit) {
+		t.Fatalf("expected pending refunds to count against the deposit, got %v", err)
+	}
+
+	if err := f.emu.SettleRefund(partial.ProviderReference, "refund.processed"); err != nil {
+		t.Fatalf("settle refund: %v", err)
+	}
+	f.process(t)
+	if got := f.balance(); got != 3_000 {
+		t.Fatalf("expected processed refund to debit the wallet, got balance %d", got)
+	}
+
+	// Refunding the rest defaults to the unrefunded amount; a failure releases it.
+	rest, err := f.wallets.RefundDeposit(ctx, services.RefundRequest{DepositReference: deposit.Reference, Reason: "rest"})
+	if err != nil || rest.Amount != 3_000 {
+		t.Fatalf("refund rest: %+v err=%v", rest, err)
+	}
+	if err := f.emu.SettleRefund(rest.ProviderReference, "refund.failed"); err != nil {
+		t.Fatalf("fail refund: %v", err)
+	}
+	f.process(t)
+	wallet, _ = f.wallets.Balance(f.user.ID, "")
+	if wallet.Balance != 3_000 || wallet.Available() != 3_000 {
+		t.Fatalf("expected failed refund released, got balance %d available %d", wallet.Balance, wallet.Available())
+	}
+
+	refunds, err := f.wallets.Refunds(deposit.Reference)
+	if err != nil || len(refunds) != 2 ||
+		refunds[0].Status != models.TransactionSuccess || refunds[1].Status != models.TransactionFailed ||
+		refunds[0].OriginalReference != deposit.Reference {
+		t.Fatalf("unexpected refunds %+v err=%v", refunds, err)
+	}
+
+	report, err := services.NewReconciliationService(f.db).Run(services.ReconciliationTriggerManual)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	for _, m := range report.Mismatches {
+		if m.WalletID == f.user.Wallet.ID {
+			t.Fatalf("refunds left the wallet out of balance: %+v", m)
+		}
+	}
+}
+
+func TestRefundRejectsUnsettledDeposit(t *testing.T) {
+	db := newTestDB(t)
+	user := seedUserWithWallet(db, "refund-pending@test.com", 0)
+	reference := seedPendingDeposit(t, db, user.Wallet.ID, 1_000)
+	wallets := services.NewWalletService(db, services.NewPaystackService("sk_test", "http://127.0.0.1:0"))
+	if _, err := wallets.RefundDeposit(context.Background(), services.RefundRequest{
+		DepositReference: reference, Reason: "not paid",
+	}); err == nil {
+		t.Fatal("expected a pending deposit not to be refundable")
+	}
+	if _, err := services.NewWalletService(db, nil).RefundDeposit(context.Background(), services.RefundRequest{
+		DepositReference: reference, Reason: "no paystack",
+	}); err == nil || err.Error() != "paystack is not configured" {
+		t.Fatalf("expected refunds to need paystack, got %v", err)
+	}
+}
+
+func TestRefundWebhookWithoutStoredIDMatchesAmount(t *testing.T) {
+	f := newCardFixture(t, "refund-match@test.com")
+	ctx := context.Background()
+	var deposit models.Transaction
+	if err := f.db.First(&deposit, "wallet_id = ? AND type = ?", f.user.Wallet.ID, models.TransactionTypeDeposit).Error; err != nil {
+		t.Fatalf("load deposit: %v", err)
+	}
+	refund := func(amount int64) models.Transaction {
+		record, err := f.wallets.RefundDeposit(ctx, services.RefundRequest{DepositReference: deposit.Reference, Amount: amount, Reason: "split"})
+		if err != nil {
+			t.Fatalf("refund %d: %v", amount, err)
+		}
+		// As if the webhook beat the refund id being stored.
+		f.db.Model(&models.Transaction{}).Where("id = ?", record.ID).Update("provider_reference", "")
+		return *record
+	}
+	processed := func(amount int64) error {
+		return f.wallets.ApplyRefundWebhook(&services.WebhookNotification{
+			Type: services.PaystackRefundProcessed, Reference: deposit.Reference, RefundID: "999", Amount: amount, Currency: "NGN",
+		}, nil)
+	}
+	status := func(id string) models.TransactionStatus {
+		var record models.Transaction
+		_ = f.db.First(&record, "id = ?", id).Error
+		return record.Status
+	}
+
+	first, second := refund(1_000), refund(1_500)
+	if err := processed(1_500); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if status(second.ID) != models.TransactionSuccess || status(first.ID) != models.TransactionPending {
+		t.Fatalf("expected only the 1500 refund settled, got %s/%s", status(first.ID), status(second.ID))
+	}
+
+	third := refund(1_000)
+	if err := processed(1_000); !errors.Is(err, services.ErrRefundAmbiguous) {
+		t.Fatalf("expected two equal pending refunds to be ambiguous, got %v", err)
+	}
+	if status(first.ID) != models.TransactionPending || status(third.ID) != models.TransactionPending {
+		t.Fatalf("expected neither refund settled by an ambiguous event")
+	}
+}
+
+func TestVerifyRefundsSettlesRefundsWithoutWebhooks(t *testing.T) {
+	f := newCardFixture(t, "refund-verify@test.com")
+	ctx := context.Background()
+	var deposit models.Transaction
+	if err := f.db.First(&deposit, "wallet_id = ? AND type = ?", f.user.Wallet.ID, models.TransactionTypeDeposit).Error; err != nil {
+		t.Fatalf("load deposit: %v", err)
+	}
+	refund := func(amount int64) *models.Transaction {
+		record, err := f.wallets.RefundDeposit(ctx, services.RefundRequest{DepositReference: deposit.Reference, Amount: amount, Reason: "verify"})
+		if err != nil {
+			t.Fatalf("refund: %v", err)
+		}
+		return record
+	}
+
+	// Paystack creates the refund but the response is lost.
+	var lostID string
+	emulator := f.emu.Handler()
+	f.emu.setIntercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodPost || r.URL.Path != "/refund" {
+			return false
+		}
+		created := httptest.NewRecorder()
+		emulator.ServeHTTP(created, r)
+		var body struct {
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		_ = json.Unmarshal(created.Body.Bytes(), &body)
+		lostID = fmt.Sprint(body.Data.ID)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+		return true
+	})
+	lost := refund(1_000)
+	// The request never reaches Paystack.
+	f.emu.setIntercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodPost || r.URL.Path != "/refund" {
+			return false
+		}
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+		return true
+	})
+	dropped := refund(1_500)
+	f.emu.setIntercept(nil)
+	queued := refund(2_000)
+	if lost.ProviderReference != "" || dropped.ProviderReference != "" || lost.Status != models.TransactionPending {
+		t.Fatalf("expected both refunds pending without a Paystack id, got %+v %+v", lost, dropped)
+	}
+	// Processed at Paystack, but the webhook is never applied.
+	if err := f.emu.SettleRefund(lostID, "refund.processed"); err != nil {
+		t.Fatalf("settle refund: %v", err)
+	}
+
+	if n, err := f.wallets.VerifyRefunds(ctx, time.Now(), time.Hour); err != nil || n != 0 {
+		t.Fatalf("expected recent refunds left alone, got %d err=%v", n, err)
+	}
+	now := time.Now().Add(2 * time.Hour)
+	// The shared test database may hold other tests' pending refunds too.
+	if n, err := f.wallets.VerifyRefunds(ctx, now, time.Hour); err != nil || n < 2 {
+		t.Fatalf("expected at least two refunds settled, got %d err=%v", n, err)
+	}
+	want := map[string]models.TransactionStatus{
+		lost.Reference:    models.TransactionSuccess,
+		dropped.Reference: models.TransactionFailed,
+		queued.Reference:  models.TransactionPending,
+	}
+	for ref, status := range want {
+		var record models.Transaction
+		f.db.First(&record, "reference = ?", ref)
+		if record.Status != status {
+			t.Fatalf("refund %s: expected %s, got %s", ref, status, record.Status)
+		}
+		if ref == lost.Reference && record.ProviderReference != lostID {
+			t.Fatalf("expected the matched Paystack id stored, got %q", record.ProviderReference)
+		}
+		if ref == queued.Reference && !record.UpdatedAt.Equal(now) {
+			t.Fatalf("expected the still pending refund moved to the back, got updated_at %s", record.UpdatedAt)
+		}
+	}
+	wallet, _ := f.wallets.Balance(f.user.ID, "")
+	if wallet.Balance != 4_000 || wallet.HeldBalance != 2_000 {
+		t.Fatalf("expected the processed refund debited and only the queued one held, got balance %d held %d", wallet.Balance, wallet.HeldBalance)
+	}
+	// The late webhook finds the refund already settled.
+	f.process(t)
+	if got := f.balance(); got != 4_000 {
+		t.Fatalf("expected the late webhook ignored, got balance %d", got)
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+)
+
+func TestReverseTransferRestoresBalances(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+
+	sender := seedUserWithWallet(db, "rev-sender@test.com", 10_000)
+	receiver := seedUserWithWallet(db, "rev-receiver@test.com", 0)
+
+	transferID, err := svc.Transfer(&sender, receiver.Wallet.Number, 4_000, services.TransferOptions{})
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	reversalID, err := svc.ReverseTransfer(services.ReversalRequest{TransferID: transferID, Operator: "ops@test.com", Reason: "mis-sent"})
+	if err != nil {
+		t.Fatalf("reverse failed: %v", err)
+	}
+
+	var s, r models.Wallet
+	_ = db.First(&s, "id = ?", sender.Wallet.ID).Error
+	_ = db.First(&r, "id = ?", receiver.Wallet.ID).Error
+	if s.Balance != 10_000 || r.Balance != 0 {
+		t.Fatalf("expected balances restored to 10000/0, got %d/%d", s.Balance, r.Balance)
+	}
+
+	var originals []models.Transaction
+	_ = db.Where("transfer_id = ?", transferID).Find(&originals).Error
+	for _, leg := range originals {
+		if leg.Status != models.TransactionReversed {
+			t.Fatalf("expected original leg %s reversed, got %s", leg.Reference, leg.Status)
+		}
+	}
+	var compensating []models.Transaction
+	_ = db.Where("transfer_id = ?", reversalID).Find(&compensating).Error
+	if len(compensating) != 2 {
+		t.Fatalf("expected 2 reversal legs, got %d", len(compensating))
+	}
+	for _, leg := range compensating {
+		if leg.OriginalReference == "" || leg.Type != models.TransactionTypeReversal {
+			t.Fatalf("reversal leg not linked to original: %+v", leg)
+		}
+	}
+
+	if _, err := svc.ReverseTransfer(services.ReversalRequest{TransferID: transferID, Reason: "again"}); err == nil {
+		t.Fatalf("expected second reversal to be refused")
+	}
+}
+
+func TestReverseTransferRequiresForceWhenRecipientSpent(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+
+	sender := seedUserWithWallet(db, "rev-force-sender@test.com", 5_000)
+	receiver := seedUserWithWallet(db, "rev-force-receiver@test.com", 0)
+	other := seedUserWithWallet(db, "rev-force-other@test.com", 0)
+
+	transferID, err := svc.Transfer(&sender, receiver.Wallet.Number, 5_000, services.TransferOptions{})
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if _, err := svc.Transfer(&receiver, other.Wallet.Number, 3_000, services.TransferOptions{}); err != nil {
+		t.Fatalf("onward transfer failed: %v", err)
+	}
+
+	req := services.ReversalRequest{TransferID: transferID, Operator: "ops@test.com", Reason: "fraud"}
+	if _, err := svc.ReverseTransfer(req); !errors.Is(err, services.ErrRecipientInsufficientFunds) {
+		t.Fatalf("expected ErrRecipientInsufficientFunds, got %v", err)
+	}
+	req.Force = true
+	if _, err := svc.ReverseTransfer(req); err != nil {
+		t.Fatalf("forced reversal failed: %v", err)
+	}
+	var r models.Wallet
+	_ = db.First(&r, "id = ?", receiver.Wallet.ID).Error
+	if r.Balance != -3_000 {
+		t.Fatalf("expected recipient overdrawn to -3000, got %d", r.Balance)
+	}
+}
+
+func TestReverseTransferLeavesHeldFundsAlone(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+
+	sender := seedUserWithWallet(db, "rev-held-sender@test.com", 5_000)
+	receiver := seedUserWithWallet(db, "rev-held-receiver@test.com", 0)
+
+	transferID, err := svc.Transfer(&sender, receiver.Wallet.Number, 5_000, services.TransferOptions{})
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if _, err := svc.PlaceHold(&receiver, "", 2_000, time.Hour, "pending payout"); err != nil {
+		t.Fatalf("place hold: %v", err)
+	}
+	req := services.ReversalRequest{TransferID: transferID, Operator: "ops@test.com", Reason: "fraud"}
+	if _, err := svc.ReverseTransfer(req); !errors.Is(err, services.ErrRecipientInsufficientFunds) {
+		t.Fatalf("expected held funds to block the reversal, got %v", err)
+	}
+}
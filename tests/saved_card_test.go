@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type cardFixture struct {
+	db       *gorm.DB
+	emu      *emulatedPaystack
+	paystack *services.PaystackService
+	wallets  *services.WalletService
+	inbox    *services.WebhookService
+	user     models.User
+}
+
+// newCardFixture runs the service against the Paystack emulator and saves a card
+// for a fresh user through an approved checkout. Refunds stay pending until
+// settled through f.emu.
+func newCardFixture(t *testing.T, email string) *cardFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+
+	var api http.Handler
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { api.ServeHTTP(w, r) }))
+	t.Cleanup(app.Close)
+	emu := newEmulatedPaystack(t, paystackemu.Config{
+		WebhookURL:      app.URL + "/wallet/paystack/webhook",
+		TransferOutcome: paystackemu.TransferOutcomeManual,
+	})
+
+	f := &cardFixture{db: db, emu: emu, paystack: emu.Service}
+	f.wallets = services.NewWalletService(db, f.paystack)
+	f.inbox = services.NewWebhookService(db, f.wallets)
+	router := gin.New()
+	router.POST("/wallet/paystack/webhook", handlers.NewWebhookHandler(f.inbox).Receive(f.paystack))
+	api = router
+	f.user = seedUserWithWallet(db, email, 0)
+
+	_, authURL, err := f.wallets.InitiateDeposit(context.Background(), &f.user, 5_000, "", services.DepositOptions{})
+	if err != nil {
+		t.Fatalf("initiate deposit: %v", err)
+	}
+	fetch(t, http.MethodPost, authURL+"/approve")
+	f.process(t)
+	return f
+}
+
+func (f *cardFixture) process(t *testing.T) {
+	t.Helper()
+	if err := f.inbox.ProcessDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+}
+
+func (f *cardFixture) balance() int64 {
+	wallet, _ := f.wallets.Balance(f.user.ID, "")
+	return wallet.Balance
+}
+
+func TestSavedCardChargeAndRemoval(t *testing.T) {
+	f := newCardFixture(t, "cards-charge@test.com")
+	cards, err := f.wallets.Cards(f.user.ID)
+	if err != nil || len(cards) != 1 || cards[0].Last4 != "4081" || cards[0].AuthorizationCode == "" {
+		t.Fatalf("expected the checkout card saved, got %+v err=%v", cards, err)
+	}
+	card := cards[0]
+
+	charged, err := f.wallets.ChargeCard(context.Background(), &f.user, card.ID, 3_000, "")
+	if err != nil || charged.Status != models.TransactionSuccess {
+		t.Fatalf("charge card: %+v err=%v", charged, err)
+	}
+	f.process(t) // the charge.success webhook for the same reference is a no-op
+	if got := f.balance(); got != 8_000 {
+		t.Fatalf("expected balance 8000, got %d", got)
+	}
+	if cards, _ := f.wallets.Cards(f.user.ID); len(cards) != 1 {
+		t.Fatalf("expected the same card not to be saved twice, got %d", len(cards))
+	}
+
+	// Without Paystack the authorization cannot be deactivated, so the card stays.
+	if err := services.NewWalletService(f.db, nil).RemoveCard(context.Background(), f.user.ID, card.ID); err == nil {
+		t.Fatal("expected removal to fail without Paystack")
+	}
+	if cards, _ := f.wallets.Cards(f.user.ID); len(cards) != 1 {
+		t.Fatalf("expected the card kept, got %d cards", len(cards))
+	}
+
+	if err := f.wallets.RemoveCard(context.Background(), f.user.ID, card.ID); err != nil {
+		t.Fatalf("remove card: %v", err)
+	}
+	if _, err := f.wallets.ChargeCard(context.Background(), &f.user, card.ID, 1_000, ""); !errors.Is(err, services.ErrCardNotFound) {
+		t.Fatalf("expected removed card to be unusable, got %v", err)
+	}
+	// The authorization was deactivated with Paystack too.
+	if _, err := f.paystack.ChargeAuthorization(context.Background(), card.AuthorizationCode, card.Email, 1_000, "NGN", "DEP-after-removal"); err == nil {
+		t.Fatal("expected Paystack to refuse a deactivated authorization")
+	}
+}
+
+func TestAutoTopUpChargesCardBelowThreshold(t *testing.T) {
+	f := newCardFixture(t, "autotopup-charge@test.com")
+	cards, _ := f.wallets.Cards(f.user.ID)
+	topUps := services.NewAutoTopUpService(f.db, f.wallets)
+	if _, err := topUps.Set(&f.user, services.AutoTopUpRequest{CardID: cards[0].ID, Threshold: 4_000, Amount: 2_500}); err != nil {
+		t.Fatalf("set auto top-up: %v", err)
+	}
+
+	if err := topUps.RunDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := f.balance(); got != 5_000 {
+		t.Fatalf("expected no charge above the threshold, got balance %d", got)
+	}
+
+	spender := seedUserWithWallet(f.db, "autotopup-payee@test.com", 0)
+	if _, err := f.wallets.Transfer(&f.user, spender.Wallet.Number, 2_000, services.TransferOptions{}); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	now := time.Now()
+	if err := topUps.RunDue(context.Background(), now); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	rule, _ := topUps.Get(f.user.ID, "")
+	if got := f.balance(); got != 5_500 || rule.LastReference == "" || rule.Failures != 0 {
+		t.Fatalf("expected one top-up of 2500, got balance %d rule %+v", got, rule)
+	}
+
+	// Still below the threshold, but the card was just charged.
+	if _, err := f.wallets.Transfer(&f.user, spender.Wallet.Number, 2_000, services.TransferOptions{}); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	for _, at := range []time.Time{now.Add(time.Minute), now.Add(30 * time.Minute)} {
+		if err := topUps.RunDue(context.Background(), at); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+	if got := f.balance(); got != 3_500 {
+		t.Fatalf("expected no charge during the cooldown, got balance %d", got)
+	}
+	if err := topUps.RunDue(context.Background(), now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := f.balance(); got != 6_000 {
+		t.Fatalf("expected a second top-up after the cooldown, got balance %d", got)
+	}
+}
+
+func TestAutoTopUpPausesAfterRepeatedFailures(t *testing.T) {
+	f := newCardFixture(t, "autotopup-fail@test.com")
+	cards, _ := f.wallets.Cards(f.user.ID)
+	topUps := services.NewAutoTopUpService(f.db, f.wallets)
+	if _, err := topUps.Set(&f.user, services.AutoTopUpRequest{CardID: cards[0].ID, Threshold: 10_000, Amount: 2_500}); err != nil {
+		t.Fatalf("set auto top-up: %v", err)
+	}
+	// The customer cancels the card with their bank, so every charge is refused.
+	if err := f.paystack.DeactivateAuthorization(context.Background(), cards[0].AuthorizationCode); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+
+	now := time.Now()
+	for i := 0; i < services.MaxAutoTopUpFailures+1; i++ {
+		if err := topUps.RunDue(context.Background(), now.Add(time.Duration(i)*2*time.Hour)); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+	rule, _ := topUps.Get(f.user.ID, "")
+	if rule.Status != models.AutoTopUpPaused || rule.Failures != services.MaxAutoTopUpFailures {
+		t.Fatalf("expected rule paused after %d failures, got %+v", services.MaxAutoTopUpFailures, rule)
+	}
+	if got := f.balance(); got != 5_000 {
+		t.Fatalf("expected no credit from refused charges, got balance %d", got)
+	}
+}
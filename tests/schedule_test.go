@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+)
+
+func TestStandingOrderRunsAndRecordsFailures(t *testing.T) {
+	db := newTestDB(t)
+	wallets := services.NewWalletService(db, nil)
+	schedules := services.NewScheduleService(db, wallets)
+
+	payer := seedUserWithWallet(db, "so-payer@test.com", 7_000)
+	landlord := seedUserWithWallet(db, "so-landlord@test.com", 0)
+
+	start := time.Now().Add(time.Second)
+	order, err := schedules.Create(&payer, services.ScheduleRequest{
+		DestWalletNumber: landlord.Wallet.Number,
+		Amount:           5_000,
+		Narration:        "rent",
+		Frequency:        models.FrequencyMonthly,
+		StartAt:          start,
+		MaxRuns:          2,
+	})
+	if err != nil {
+		t.Fatalf("create standing order: %v", err)
+	}
+
+	// First occurrence succeeds; running again at the same instant must not pay twice.
+	for i := 0; i < 2; i++ {
+		if err := schedules.RunDue(start.Add(time.Minute)); err != nil {
+			t.Fatalf("run due: %v", err)
+		}
+	}
+	var l models.Wallet
+	_ = db.First(&l, "id = ?", landlord.Wallet.ID).Error
+	if l.Balance != 5_000 {
+		t.Fatalf("expected one rent payment of 5000, got %d", l.Balance)
+	}
+
+	// Second occurrence a month later fails for lack of funds and completes the order.
+	if err := schedules.RunDue(start.AddDate(0, 1, 1)); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+	runs, err := schedules.Runs(payer.ID, order.ID)
+	if err != nil {
+		t.Fatalf("runs: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	statuses := map[models.TransactionStatus]int{}
+	for _, r := range runs {
+		statuses[r.Status]++
+		if r.Status == models.TransactionFailed && r.Error != "insufficient balance" {
+			t.Fatalf("expected insufficient balance failure, got %q", r.Error)
+		}
+	}
+	if statuses[models.TransactionSuccess] != 1 || statuses[models.TransactionFailed] != 1 {
+		t.Fatalf("expected one success and one failure, got %v", statuses)
+	}
+	var stored models.StandingOrder
+	_ = db.First(&stored, "id = ?", order.ID).Error
+	if stored.Status != models.StandingOrderCompleted || stored.RunCount != 2 {
+		t.Fatalf("expected completed order after max runs, got %s with %d runs", stored.Status, stored.RunCount)
+	}
+	if err := schedules.Cancel(payer.ID, order.ID); err == nil {
+		t.Fatalf("expected cancelling a completed order to fail")
+	}
+}
+
+func TestStandingOrderDebitsItsOwnCurrencyWallet(t *testing.T) {
+	db := newTestDB(t)
+	wallets := services.NewWalletService(db, nil)
+	schedules := services.NewScheduleService(db, wallets)
+	bulk := services.NewBulkTransferService(db, wallets)
+
+	payer := seedUserWithWallet(db, "so-fx-payer@test.com", 9_000)
+	payee := seedUserWithWallet(db, "so-fx-payee@test.com", 0)
+	payerGHS, err := wallets.OpenWallet(&payer, "GHS")
+	if err != nil {
+		t.Fatalf("open GHS wallet: %v", err)
+	}
+	payeeGHS, err := wallets.OpenWallet(&payee, "GHS")
+	if err != nil {
+		t.Fatalf("open GHS wallet: %v", err)
+	}
+	db.Model(&models.Wallet{}).Where("id = ?", payerGHS.ID).Update("balance", 3_000)
+
+	if _, err := schedules.Create(&payer, services.ScheduleRequest{
+		DestWalletNumber: payee.Wallet.Number, Amount: 1_000, Frequency: models.FrequencyOnce,
+		StartAt: time.Now().Add(time.Second), Currency: "GHS",
+	}); !errors.Is(err, services.ErrCurrencyMismatch) {
+		t.Fatalf("expected a GHS order to an NGN wallet to be refused, got %v", err)
+	}
+	start := time.Now().Add(time.Second)
+	order, err := schedules.Create(&payer, services.ScheduleRequest{
+		DestWalletNumber: payeeGHS.Number, Amount: 1_000, Frequency: models.FrequencyOnce, StartAt: start, Currency: "ghs",
+	})
+	if err != nil || order.WalletID != payerGHS.ID || order.Currency != "GHS" {
+		t.Fatalf("expected an order on the GHS wallet, got %+v err=%v", order, err)
+	}
+	if err := schedules.RunDue(start.Add(time.Minute)); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+	batch, err := bulk.Create(&payer, models.BatchAllOrNothing, "GHS", []services.BulkTransferItem{
+		{WalletNumber: payeeGHS.Number, Amount: 500},
+	})
+	if err != nil || batch.Status != models.BatchCompleted {
+		t.Fatalf("expected a GHS batch to complete, got %+v err=%v", batch, err)
+	}
+	hold, err := wallets.PlaceHold(&payer, "GHS", 1_000, time.Hour, "")
+	if err != nil {
+		t.Fatalf("place GHS hold: %v", err)
+	}
+	if _, err := wallets.CaptureHold(&payer, hold.ID, payeeGHS.Number, 0, services.TransferOptions{}); err != nil {
+		t.Fatalf("capture GHS hold: %v", err)
+	}
+
+	var ngn, ghs models.Wallet
+	_ = db.First(&ngn, "id = ?", payer.Wallet.ID).Error
+	_ = db.First(&ghs, "id = ?", payerGHS.ID).Error
+	if ngn.Balance != 9_000 || ghs.Balance != 500 {
+		t.Fatalf("expected only the GHS wallet debited, got NGN %d GHS %d", ngn.Balance, ghs.Balance)
+	}
+}
+
+func TestStandingOrderSkipsOccurrencesMissedDuringDowntime(t *testing.T) {
+	db := newTestDB(t)
+	wallets := services.NewWalletService(db, nil)
+	schedules := services.NewScheduleService(db, wallets)
+	payer := seedUserWithWallet(db, "so-downtime-payer@test.com", 100_000)
+	payee := seedUserWithWallet(db, "so-downtime-payee@test.com", 0)
+
+	start := time.Now().Add(time.Second)
+	order, err := schedules.Create(&payer, services.ScheduleRequest{
+		DestWalletNumber: payee.Wallet.Number,
+		Amount:           1_000,
+		Frequency:        models.FrequencyDaily,
+		StartAt:          start,
+	})
+	if err != nil {
+		t.Fatalf("create standing order: %v", err)
+	}
+
+	// The executor comes back after five days: one payment, not five.
+	back := start.AddDate(0, 0, 4).Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := schedules.RunDue(back.Add(time.Duration(i) * time.Minute)); err != nil {
+			t.Fatalf("run due: %v", err)
+		}
+	}
+	var w models.Wallet
+	_ = db.First(&w, "id = ?", payee.Wallet.ID).Error
+	if w.Balance != 1_000 {
+		t.Fatalf("expected a single catch-up payment, got %d", w.Balance)
+	}
+	var stored models.StandingOrder
+	_ = db.First(&stored, "id = ?", order.ID).Error
+	if stored.RunCount != 1 || stored.SkippedRuns != 4 || !stored.NextRunAt.Equal(start.AddDate(0, 0, 5)) {
+		t.Fatalf("expected the schedule moved past now, got runs %d skipped %d next %s", stored.RunCount, stored.SkippedRuns, stored.NextRunAt)
+	}
+
+	if err := schedules.RunDue(start.AddDate(0, 0, 5).Add(time.Minute)); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+	_ = db.First(&w, "id = ?", payee.Wallet.ID).Error
+	if w.Balance != 2_000 {
+		t.Fatalf("expected the next occurrence paid on schedule, got %d", w.Balance)
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CyberwizD/Wallet-Service/internal/config"
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// signIn opens a session for user as a Google sign-in would.
+func signIn(t *testing.T, db *gorm.DB, user *models.User, secret string) *services.SessionTokens {
+	t.Helper()
+	tokens, err := services.NewSessionService(db, secret).Start(user, "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	return tokens
+}
+
+func newSessionRouter(db *gorm.DB, secret string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handlers.NewAuthHandler(config.Config{JWTSecret: secret}, services.NewUserService(db), services.NewSessionService(db, secret))
+	r := gin.New()
+	r.POST("/auth/refresh", h.Refresh)
+	r.POST("/auth/logout", h.Logout)
+	protected := r.Group("/", middleware.AuthMiddleware(db, secret))
+	protected.GET("/auth/sessions", h.Sessions)
+	protected.DELETE("/auth/sessions/:id", h.RevokeSession)
+	protected.GET("/wallet/balance", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	return r
+}
+
+func sessionRequest(r *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRefreshRotatesTokenAndDetectsReuse(t *testing.T) {
+	db := newTestDB(t)
+	const secret = "test-secret"
+	user := seedUserWithWallet(db, "refresh@test.com", 0)
+	first := signIn(t, db, &user, secret)
+	r := newSessionRouter(db, secret)
+
+	w := sessionRequest(r, http.MethodPost, "/auth/refresh", "", `{"refresh_token":"`+first.RefreshToken+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected refresh, got %d %s", w.Code, w.Body.String())
+	}
+	var rotated struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		SessionID    string `json:"session_id"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &rotated)
+	if rotated.Token == "" || rotated.RefreshToken == "" || rotated.RefreshToken == first.RefreshToken || rotated.SessionID != first.SessionID {
+		t.Fatalf("expected a rotated refresh token on the same session, got %+v", rotated)
+	}
+	if w := sessionRequest(r, http.MethodGet, "/wallet/balance", rotated.Token, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the new access token to work, got %d", w.Code)
+	}
+
+	// Replaying the rotated token later looks like theft: the whole session is revoked.
+	db.Model(&models.Session{}).Where("id = ?", first.SessionID).Update("last_used_at", time.Now().Add(-time.Minute))
+	if w := sessionRequest(r, http.MethodPost, "/auth/refresh", "", `{"refresh_token":"`+first.RefreshToken+`"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the reused token rejected, got %d %s", w.Code, w.Body.String())
+	}
+	if w := sessionRequest(r, http.MethodPost, "/auth/refresh", "", `{"refresh_token":"`+rotated.RefreshToken+`"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the current refresh token revoked with the session, got %d", w.Code)
+	}
+	if w := sessionRequest(r, http.MethodGet, "/wallet/balance", rotated.Token, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the access token of a revoked session rejected, got %d", w.Code)
+	}
+	var session models.Session
+	db.First(&session, "id = ?", first.SessionID)
+	if session.RevokedAt == nil || session.RevokeReason != "refresh token reused" {
+		t.Fatalf("expected the session revoked for reuse, got %+v", session)
+	}
+}
+
+func TestRefreshToleratesConcurrentRotation(t *testing.T) {
+	db := newTestDB(t)
+	const secret = "test-secret"
+	user := seedUserWithWallet(db, "refresh-race@test.com", 0)
+	first := signIn(t, db, &user, secret)
+	sessions := services.NewSessionService(db, secret)
+
+	// Two tabs refresh with the same token at once; the slower one is neither taken
+	// for theft nor allowed to rotate the token the faster one just received.
+	agent := strings.Repeat("é", 200)
+	rotated, err := sessions.Refresh(first.RefreshToken, agent, "")
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, err := sessions.Refresh(first.RefreshToken, agent, ""); !errors.Is(err, services.ErrRefreshTokenRotated) {
+		t.Fatalf("expected the concurrent refresh told to retry, got %v", err)
+	}
+	if _, err := sessions.Refresh(rotated.RefreshToken, "", ""); err != nil {
+		t.Fatalf("expected the token from the first refresh still valid, got %v", err)
+	}
+	var session models.Session
+	db.First(&session, "id = ?", first.SessionID)
+	if session.RevokedAt != nil || len(session.UserAgent) > 255 || !utf8.ValidString(session.UserAgent) {
+		t.Fatalf("expected an active session with a truncated user agent, got %+v", session)
+	}
+}
+
+func TestLogoutRevokesOneOrAllSessions(t *testing.T) {
+	db := newTestDB(t)
+	const secret = "test-secret"
+	user := seedUserWithWallet(db, "logout@test.com", 0)
+	phone, laptop, tablet := signIn(t, db, &user, secret), signIn(t, db, &user, secret), signIn(t, db, &user, secret)
+	r := newSessionRouter(db, secret)
+
+	w := sessionRequest(r, http.MethodPost, "/auth/logout", "", `{"refresh_token":"`+phone.RefreshToken+`"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":1`) {
+		t.Fatalf("expected one session logged out, got %d %s", w.Code, w.Body.String())
+	}
+	if w := sessionRequest(r, http.MethodGet, "/wallet/balance", phone.AccessToken, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the logged out access token rejected, got %d", w.Code)
+	}
+	if w := sessionRequest(r, http.MethodGet, "/wallet/balance", laptop.AccessToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected other sessions untouched, got %d", w.Code)
+	}
+
+	w = sessionRequest(r, http.MethodPost, "/auth/logout", "", `{"refresh_token":"`+laptop.RefreshToken+`","all":true}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":2`) {
+		t.Fatalf("expected the remaining two sessions logged out, got %d %s", w.Code, w.Body.String())
+	}
+	if w := sessionRequest(r, http.MethodGet, "/wallet/balance", tablet.AccessToken, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected every session logged out, got %d", w.Code)
+	}
+	if w := sessionRequest(r, http.MethodPost, "/auth/logout", "", `{"refresh_token":"rt_unknown"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an unknown refresh token rejected, got %d", w.Code)
+	}
+}
+
+func TestListAndRevokeSessions(t *testing.T) {
+	db := newTestDB(t)
+	const secret = "test-secret"
+	user := seedUserWithWallet(db, "sessions@test.com", 0)
+	other := seedUserWithWallet(db, "other-sessions@test.com", 0)
+	current, stale := signIn(t, db, &user, secret), signIn(t, db, &user, secret)
+	foreign := signIn(t, db, &other, secret)
+	r := newSessionRouter(db, secret)
+
+	w := sessionRequest(r, http.MethodGet, "/auth/sessions", current.AccessToken, "")
+	var listed []struct {
+		ID      string `json:"id"`
+		Current bool   `json:"current"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &listed)
+	if w.Code != http.StatusOK || len(listed) != 2 {
+		t.Fatalf("expected the user's two sessions, got %d %s", w.Code, w.Body.String())
+	}
+	for _, s := range listed {
+		if s.Current != (s.ID == current.SessionID) {
+			t.Fatalf("expected only the calling session marked current, got %+v", listed)
+		}
+	}
+
+	if w := sessionRequest(r, http.MethodDelete, "/auth/sessions/"+foreign.SessionID, current.AccessToken, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected another user's session to be invisible, got %d", w.Code)
+	}
+	if w := sessionRequest(r, http.MethodDelete, "/auth/sessions/"+stale.SessionID, current.AccessToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the session revoked, got %d %s", w.Code, w.Body.String())
+	}
+	if w := sessionRequest(r, http.MethodGet, "/wallet/balance", stale.AccessToken, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked session's access token rejected, got %d", w.Code)
+	}
+	if _, err := services.NewSessionService(db, secret).Refresh(stale.RefreshToken, "", ""); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Fatalf("expected the revoked session's refresh token rejected, got %v", err)
+	}
+}
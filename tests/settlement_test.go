@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// discrepancies indexes a report's discrepancies by reference.
+func discrepancies(report *services.SettlementReport) map[string]services.SettlementDiscrepancy {
+	res := map[string]services.SettlementDiscrepancy{}
+	for _, d := range report.Discrepancies {
+		res[d.Reference] = d
+	}
+	return res
+}
+
+func TestSettlementReconciliationAgainstPaystack(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	f := newCardFixture(t, "settlement-api@test.com")
+	ctx := context.Background()
+
+	// Paid at Paystack, but the webhook was never processed.
+	missing, authURL, err := f.wallets.InitiateDeposit(ctx, &f.user, 2_000, "", services.DepositOptions{})
+	if err != nil {
+		t.Fatalf("initiate deposit: %v", err)
+	}
+	fetch(t, http.MethodPost, authURL+"/approve")
+	// Charged at Paystack under a reference we never recorded.
+	cards, _ := f.wallets.Cards(f.user.ID)
+	orphan := "ORPHAN-" + util.MustUUID()
+	if _, err := f.paystack.ChargeAuthorization(ctx, cards[0].AuthorizationCode, cards[0].Email, 700, "NGN", orphan); err != nil {
+		t.Fatalf("orphan charge: %v", err)
+	}
+	// Credited here with no Paystack charge behind it.
+	unconfirmed := models.Transaction{
+		ID: util.MustUUID(), Reference: "DEP-" + util.MustUUID(), Type: models.TransactionTypeDeposit,
+		Status: models.TransactionSuccess, Amount: 900, Currency: "NGN", WalletID: f.user.Wallet.ID,
+		Direction: models.EntryCredit, Provider: services.ProviderPaystack, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	if err := f.db.Create(&unconfirmed).Error; err != nil {
+		t.Fatalf("seed credited deposit: %v", err)
+	}
+	settlementID, err := f.emu.Settle()
+	if err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+
+	settlements := services.NewSettlementService(f.db, f.wallets)
+	req := services.SettlementRequest{From: start, To: time.Now().Add(time.Minute), Trigger: services.ReconciliationTriggerManual}
+	report, err := settlements.RunFromPaystack(ctx, req)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	found := discrepancies(report)
+	if d := found[missing.Reference]; d.Kind != services.DiscrepancyMissingCredit || d.Fixed || d.SettlementID == "" {
+		t.Fatalf("expected an unfixed, settled missing credit, got %+v", d)
+	}
+	if d := found[orphan]; d.Kind != services.DiscrepancyOrphanCharge || d.ChargeAmount != 700 {
+		t.Fatalf("expected an orphan charge, got %+v", d)
+	}
+	if d := found[unconfirmed.Reference]; d.Kind != services.DiscrepancyUnconfirmedCredit {
+		t.Fatalf("expected an unconfirmed credit, got %+v", d)
+	}
+	var first models.Transaction
+	f.db.First(&first, "wallet_id = ? AND type = ? AND status = ? AND amount = ?",
+		f.user.Wallet.ID, models.TransactionTypeDeposit, models.TransactionSuccess, 5_000)
+	if _, ok := found[first.Reference]; ok {
+		t.Fatalf("expected the credited checkout deposit to reconcile, got %+v", found[first.Reference])
+	}
+	if len(report.Settlements) == 0 || report.Settlements[len(report.Settlements)-1].ID == "" {
+		t.Fatalf("expected settlement %d in the report, got %+v", settlementID, report.Settlements)
+	}
+
+	req.Fix = true
+	report, err = settlements.RunFromPaystack(ctx, req)
+	if err != nil {
+		t.Fatalf("run with fix: %v", err)
+	}
+	if d := discrepancies(report)[missing.Reference]; !d.Fixed || d.FixError != "" {
+		t.Fatalf("expected the missing credit fixed, got %+v", d)
+	}
+	if got := f.balance(); got != 7_000 {
+		t.Fatalf("expected the missing credit applied, got balance %d", got)
+	}
+	if stored, err := settlements.Report(report.RunID); err != nil || stored.Fixed != report.Fixed {
+		t.Fatalf("expected the report stored, got %+v err=%v", stored, err)
+	}
+}
+
+func TestSettlementReconciliationFromCSVExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	user := seedUserWithWallet(db, "settlement-csv@test.com", 0)
+	wallets := services.NewWalletService(db, services.NewPaystackService("sk_test", "http://127.0.0.1:0"))
+	pending := seedPendingDeposit(t, db, user.Wallet.ID, 5_000)
+	short := seedPendingDeposit(t, db, user.Wallet.ID, 600_000)
+
+	r := gin.New()
+	r.POST("/admin/settlements/reconcile",
+		handlers.NewAdminHandler(nil, services.NewSettlementService(db, wallets), wallets, nil).ReconcileSettlements)
+	body := "Reference,Amount,Currency,Status,Customer Email\n" +
+		pending + ",50.00,NGN,success,settlement-csv@test.com\n" +
+		short + ",\"5,999.99\",NGN,success,settlement-csv@test.com\n" +
+		"DEP-abandoned,10.00,NGN,abandoned,someone@test.com\n"
+	from := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	to := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodPost, "/admin/settlements/reconcile?from="+from+"&to="+to, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	var report services.SettlementReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	found := discrepancies(&report)
+	if report.Source != services.SettlementSourceCSV || report.ChargesChecked != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	// Export amounts are in naira: 50.00 is the 5000 kobo recorded.
+	if d := found[pending]; d.Kind != services.DiscrepancyMissingCredit || d.ChargeAmount != 5_000 {
+		t.Fatalf("expected a missing credit, got %+v", d)
+	}
+	if d := found[short]; d.Kind != services.DiscrepancyAmountMismatch || d.ChargeAmount != 599_999 {
+		t.Fatalf("expected an amount mismatch, got %+v", d)
+	}
+	if _, ok := found["DEP-abandoned"]; ok {
+		t.Fatal("expected unpaid charges to be ignored")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/settlements/reconcile?from="+from+"&to="+to,
+		strings.NewReader("Reference,Status\nDEP-x,success\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an export without amounts, got %d", w.Code)
+	}
+	// An export over 10 MiB is refused before it is parsed, as a body or an upload.
+	huge := "Reference,Amount,Status\n" + strings.Repeat("DEP-x,1.00,success\n", 600_000)
+	req = httptest.NewRequest(http.MethodPost, "/admin/settlements/reconcile?from="+from+"&to="+to, strings.NewReader(huge))
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an oversized export, got %d %s", w.Code, w.Body.String())
+	}
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	_ = form.WriteField("from", from)
+	_ = form.WriteField("to", to)
+	part, _ := form.CreateFormFile("file", "export.csv")
+	_, _ = part.Write([]byte(huge))
+	_ = form.Close()
+	req = httptest.NewRequest(http.MethodPost, "/admin/settlements/reconcile", &upload)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an oversized upload, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -1,9 +1,15 @@
 package tests
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -16,8 +22,86 @@ func newTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("failed to open sqlite: %v", err)
 	}
-	if err := db.AutoMigrate(&models.User{}, &models.Wallet{}, &models.APIKey{}, &models.Transaction{}); err != nil {
+	if err := db.AutoMigrate(
+		&models.User{}, &models.Wallet{}, &models.APIKey{}, &models.Transaction{},
+		&models.LedgerAccount{}, &models.JournalEntry{}, &models.LedgerLine{},
+		&models.ReconciliationRun{}, &models.SettlementRun{}, &models.IdempotencyKey{}, &models.Hold{},
+		&models.StandingOrder{}, &models.StandingOrderRun{},
+		&models.TransferBatch{}, &models.TransferBatchItem{},
+		&models.BankRecipient{}, &models.WebhookEvent{},
+		&models.SavedCard{}, &models.AutoTopUp{}, &models.Session{},
+	); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
 	return db
 }
+
+// emulatedPaystack serves the Paystack emulator to the service under test.
+type emulatedPaystack struct {
+	*paystackemu.Emulator
+	Service *services.PaystackService
+	URL     string
+
+	mu sync.Mutex
+	// intercept, when set, sees every request first and answers it instead of the
+	// emulator by returning true, e.g. to simulate an outage.
+	intercept func(w http.ResponseWriter, r *http.Request) bool
+}
+
+// newEmulatedPaystack starts an emulator with cfg, defaulting the secret key and
+// the public URL to the test server's.
+func newEmulatedPaystack(t *testing.T, cfg paystackemu.Config) *emulatedPaystack {
+	t.Helper()
+	if cfg.SecretKey == "" {
+		cfg.SecretKey = "sk_test_emulator"
+	}
+	p := &emulatedPaystack{}
+	var handler http.Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		intercept := p.intercept
+		p.mu.Unlock()
+		if intercept != nil && intercept(w, r) {
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = srv.URL
+	}
+	p.Emulator = paystackemu.New(cfg)
+	handler = p.Emulator.Handler()
+	p.URL = srv.URL
+	p.Service = services.NewPaystackService(cfg.SecretKey, srv.URL)
+	return p
+}
+
+// setIntercept replaces the request intercept; nil lets every request through.
+func (p *emulatedPaystack) setIntercept(intercept func(w http.ResponseWriter, r *http.Request) bool) {
+	p.mu.Lock()
+	p.intercept = intercept
+	p.mu.Unlock()
+}
+
+// checkout opens a deposit checkout for amount on the user's wallet in currency
+// and returns its reference; the payment stays open until approved or declined.
+func (p *emulatedPaystack) checkout(t *testing.T, wallets *services.WalletService, user *models.User, amount int64, currency string) string {
+	t.Helper()
+	deposit, _, err := wallets.InitiateDeposit(context.Background(), user, amount, currency, services.DepositOptions{})
+	if err != nil {
+		t.Fatalf("initiate deposit: %v", err)
+	}
+	return deposit.Reference
+}
+
+// paidCheckout opens a checkout and approves it. Without a webhook URL the
+// charge.success event is not delivered; tests apply it themselves.
+func (p *emulatedPaystack) paidCheckout(t *testing.T, wallets *services.WalletService, user *models.User, amount int64) string {
+	t.Helper()
+	reference := p.checkout(t, wallets, user, amount, "")
+	if err := p.Approve(reference); err != nil {
+		t.Fatalf("approve %s: %v", reference, err)
+	}
+	return reference
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+	"github.com/CyberwizD/Wallet-Service/internal/util"
+)
+
+func TestTransactionsCursorPagination(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+	user := seedUserWithWallet(db, "history@test.com", 0)
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		tx := models.Transaction{
+			ID:        util.MustUUID(),
+			Reference: "HIST-" + util.MustUUID(),
+			Type:      models.TransactionTypeDeposit,
+			Status:    models.TransactionSuccess,
+			Amount:    int64(1_000 * (i + 1)),
+			WalletID:  user.Wallet.ID,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+			UpdatedAt: base,
+		}
+		if err := db.Create(&tx).Error; err != nil {
+			t.Fatalf("seed tx: %v", err)
+		}
+	}
+
+	var seen []int64
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		page, err := svc.Transactions(user.ID, services.TransactionFilter{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("page %d: %v", pages, err)
+		}
+		for _, tx := range page.Items {
+			seen = append(seen, tx.Amount)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	want := []int64{5_000, 4_000, 3_000, 2_000, 1_000}
+	if len(seen) != len(want) {
+		t.Fatalf("expected %d rows across pages, got %v", len(want), seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("expected newest-first order %v, got %v", want, seen)
+		}
+	}
+
+	minAmount := int64(2_500)
+	filtered, err := svc.Transactions(user.ID, services.TransactionFilter{MinAmount: &minAmount, ReferencePrefix: "HIST-"})
+	if err != nil {
+		t.Fatalf("filtered: %v", err)
+	}
+	if len(filtered.Items) != 3 || filtered.NextCursor != "" {
+		t.Fatalf("expected 3 rows and no cursor, got %d rows cursor %q", len(filtered.Items), filtered.NextCursor)
+	}
+
+	if _, err := svc.Transactions(user.ID, services.TransactionFilter{Cursor: "not-a-cursor"}); err != services.ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
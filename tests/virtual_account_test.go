@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVirtualAccountBankTransferCreditsWallet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+
+	var api http.Handler
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { api.ServeHTTP(w, r) }))
+	t.Cleanup(app.Close)
+	emu := newEmulatedPaystack(t, paystackemu.Config{WebhookURL: app.URL + "/wallet/paystack/webhook"})
+	paystack := emu.Service
+	wallets := services.NewWalletService(db, paystack)
+	inbox := services.NewWebhookService(db, wallets)
+	router := gin.New()
+	router.POST("/wallet/paystack/webhook", handlers.NewWebhookHandler(inbox).Receive(paystack))
+	api = router
+	user := seedUserWithWallet(db, "dva-transfer@test.com", 0)
+
+	wallet, created, err := wallets.AssignVirtualAccount(context.Background(), &user, "")
+	if err != nil || !created || wallet.VirtualAccountNumber == "" || wallet.PaystackCustomerCode == "" {
+		t.Fatalf("assign virtual account: %+v created=%v err=%v", wallet, created, err)
+	}
+	again, created, err := wallets.AssignVirtualAccount(context.Background(), &user, "")
+	if err != nil || created || again.VirtualAccountNumber != wallet.VirtualAccountNumber {
+		t.Fatalf("expected the existing account back, got %+v created=%v err=%v", again, created, err)
+	}
+
+	reference, err := emu.CreditVirtualAccount(wallet.VirtualAccountNumber, 12_000)
+	if err != nil {
+		t.Fatalf("bank transfer: %v", err)
+	}
+	if err := inbox.ProcessDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	deposit, err := wallets.DepositStatus(reference)
+	if err != nil || deposit.Status != models.TransactionSuccess || deposit.WalletID != wallet.ID {
+		t.Fatalf("expected credited deposit under %s, got %+v err=%v", reference, deposit, err)
+	}
+
+	// A redelivery with a fresh event id must not credit twice.
+	if err := wallets.ApplyVirtualAccountDeposit(context.Background(), reference, wallet.VirtualAccountNumber, nil); err != nil {
+		t.Fatalf("reapply: %v", err)
+	}
+	if balance, _ := wallets.Balance(user.ID, ""); balance.Balance != 12_000 {
+		t.Fatalf("expected balance 12000, got %d", balance.Balance)
+	}
+}
+
+func TestVirtualAccountDepositRejectsUnknownAccount(t *testing.T) {
+	db := newTestDB(t)
+	wallets := services.NewWalletService(db, newEmulatedPaystack(t, paystackemu.Config{}).Service)
+	if err := wallets.ApplyVirtualAccountDeposit(context.Background(), "T-unknown", "0000000000", nil); err == nil {
+		t.Fatal("expected an error for a transfer into an unknown account")
+	}
+}
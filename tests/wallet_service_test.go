@@ -2,6 +2,7 @@ package tests
 
 import (
 	"testing"
+	"time"
 
 	"github.com/CyberwizD/Wallet-Service/internal/models"
 	"github.com/CyberwizD/Wallet-Service/internal/services"
@@ -24,6 +25,25 @@ func seedUserWithWallet(db *gorm.DB, email string, balance int64) models.User {
 	return user
 }
 
+func seedPendingDeposit(t *testing.T, db *gorm.DB, walletID string, amount int64) string {
+	t.Helper()
+	deposit := models.Transaction{
+		ID:        util.MustUUID(),
+		Reference: "DEP-" + util.MustUUID(),
+		Type:      models.TransactionTypeDeposit,
+		Status:    models.TransactionPending,
+		Amount:    amount,
+		WalletID:  walletID,
+		Direction: models.EntryCredit,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.Create(&deposit).Error; err != nil {
+		t.Fatalf("seed deposit: %v", err)
+	}
+	return deposit.Reference
+}
+
 func TestTransferSuccess(t *testing.T) {
 	db := newTestDB(t)
 	svc := services.NewWalletService(db, nil)
@@ -31,7 +51,7 @@ func TestTransferSuccess(t *testing.T) {
 	sender := seedUserWithWallet(db, "sender@test.com", 10_000)
 	receiver := seedUserWithWallet(db, "receiver@test.com", 2_000)
 
-	if err := svc.Transfer(&sender, receiver.Wallet.Number, 5_000); err != nil {
+	if _, err := svc.Transfer(&sender, receiver.Wallet.Number, 5_000, services.TransferOptions{}); err != nil {
 		t.Fatalf("transfer failed: %v", err)
 	}
 
@@ -54,7 +74,42 @@ func TestTransferInsufficientBalance(t *testing.T) {
 	sender := seedUserWithWallet(db, "sender2@test.com", 1_000)
 	receiver := seedUserWithWallet(db, "receiver2@test.com", 0)
 
-	if err := svc.Transfer(&sender, receiver.Wallet.Number, 5_000); err == nil {
+	if _, err := svc.Transfer(&sender, receiver.Wallet.Number, 5_000, services.TransferOptions{}); err == nil {
 		t.Fatalf("expected insufficient balance error")
 	}
 }
+
+func TestTransferLegsShareTransferID(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+
+	sender := seedUserWithWallet(db, "legs-sender@test.com", 10_000)
+	receiver := seedUserWithWallet(db, "legs-receiver@test.com", 0)
+	outsider := seedUserWithWallet(db, "legs-outsider@test.com", 0)
+
+	transferID, err := svc.Transfer(&sender, receiver.Wallet.Number, 2_500, services.TransferOptions{
+		Narration:       "March rent",
+		ClientReference: "inv-42",
+	})
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	legs, err := svc.TransferLegs(receiver.ID, transferID)
+	if err != nil {
+		t.Fatalf("transfer legs: %v", err)
+	}
+	if len(legs) != 2 {
+		t.Fatalf("expected 2 legs, got %d", len(legs))
+	}
+	if legs[0].Direction != models.EntryDebit || legs[0].WalletID != sender.Wallet.ID {
+		t.Fatalf("expected sender debit leg first, got %+v", legs[0])
+	}
+	for _, leg := range legs {
+		if leg.TransferID != transferID || leg.Narration != "March rent" || leg.ClientReference != "inv-42" {
+			t.Fatalf("leg missing transfer details: %+v", leg)
+		}
+	}
+	if _, err := svc.TransferLegs(outsider.ID, transferID); err == nil {
+		t.Fatalf("expected outsider to be refused")
+	}
+}
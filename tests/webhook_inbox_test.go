@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func chargeSuccessBody(id int, reference string) []byte {
+	return []byte(fmt.Sprintf(`{"event":"charge.success","data":{"id":%d,"status":"success","reference":%q}}`, id, reference))
+}
+
+func TestWebhookInboxDeduplicatesAndCreditsOnce(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	wallets := services.NewWalletService(db, paystack.Service)
+	inbox := services.NewWebhookService(db, wallets)
+	user := seedUserWithWallet(db, "inbox-dedup@test.com", 0)
+	ref := paystack.paidCheckout(t, wallets, &user, 4_000)
+	body := chargeSuccessBody(910001, ref)
+
+	first, dup, err := inbox.Record(services.ProviderPaystack, map[string][]string{"X-Paystack-Signature": {"sig"}}, body, true)
+	if err != nil || dup {
+		t.Fatalf("record: dup=%v err=%v", dup, err)
+	}
+	second, dup, err := inbox.Record(services.ProviderPaystack, nil, body, true)
+	if err != nil || !dup || second.ID != first.ID {
+		t.Fatalf("expected redelivery to be deduplicated, dup=%v err=%v", dup, err)
+	}
+	if wallet, _ := wallets.Balance(user.ID, ""); wallet.Balance != 0 {
+		t.Fatalf("expected nothing credited before the worker runs, got %d", wallet.Balance)
+	}
+
+	if err := inbox.ProcessDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	stored, _ := inbox.Get(first.ID)
+	wallet, _ := wallets.Balance(user.ID, "")
+	if stored.Status != models.WebhookProcessed || stored.Attempts != 1 || wallet.Balance != 4_000 {
+		t.Fatalf("expected processed event and credit, got %s/%d balance %d", stored.Status, stored.Attempts, wallet.Balance)
+	}
+}
+
+func TestWebhookInboxRetriesThenReplays(t *testing.T) {
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	wallets := services.NewWalletService(db, paystack.Service)
+	inbox := services.NewWebhookService(db, wallets)
+	user := seedUserWithWallet(db, "inbox-retry@test.com", 0)
+
+	// The deposit row does not exist yet, as during an outage or a race with InitiateDeposit.
+	ref := "DEP-inbox-retry"
+	if _, err := paystack.Service.InitializeTransaction(context.Background(), 2_000, "NGN", user.Email, ref, services.CheckoutOptions{}); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if err := paystack.Approve(ref); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	event, _, err := inbox.Record(services.ProviderPaystack, nil, chargeSuccessBody(910002, ref), true)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	now := time.Now()
+	for i := 0; i < services.MaxWebhookAttempts; i++ {
+		now = now.Add(2 * time.Hour)
+		if err := inbox.ProcessDue(context.Background(), now); err != nil {
+			t.Fatalf("process: %v", err)
+		}
+	}
+	stored, _ := inbox.Get(event.ID)
+	if stored.Status != models.WebhookFailed || stored.Attempts != services.MaxWebhookAttempts || stored.LastError == "" {
+		t.Fatalf("expected failed after %d attempts, got %s/%d (%q)", services.MaxWebhookAttempts, stored.Status, stored.Attempts, stored.LastError)
+	}
+
+	seeded := seedPendingDeposit(t, db, user.Wallet.ID, 2_000)
+	db.Model(&models.Transaction{}).Where("reference = ?", seeded).Update("reference", ref)
+	replayed, err := inbox.Replay(context.Background(), event.ID)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	wallet, _ := wallets.Balance(user.ID, "")
+	if replayed.Status != models.WebhookProcessed || wallet.Balance != 2_000 {
+		t.Fatalf("expected replay to credit the deposit, got %s balance %d", replayed.Status, wallet.Balance)
+	}
+}
+
+func TestWebhookInboxStoresRejectedSignatures(t *testing.T) {
+	db := newTestDB(t)
+	inbox := services.NewWebhookService(db, services.NewWalletService(db, newEmulatedPaystack(t, paystackemu.Config{}).Service))
+	body := chargeSuccessBody(910003, "DEP-forged")
+
+	forged, _, err := inbox.Record(services.ProviderPaystack, nil, body, false)
+	if err != nil {
+		t.Fatalf("record forged: %v", err)
+	}
+	var stored models.WebhookEvent
+	_ = db.First(&stored, "id = ?", forged.ID).Error
+	if stored.Status != models.WebhookRejected || len(stored.Body) != 0 || len(stored.Headers) != 0 || stored.Reference != "" {
+		t.Fatalf("expected a rejected record without the forged payload, got %+v", stored)
+	}
+	if _, err := inbox.Replay(context.Background(), forged.ID); err == nil {
+		t.Fatalf("expected rejected event replay to be refused")
+	}
+	// A forged copy must not block the genuine event.
+	if _, dup, err := inbox.Record(services.ProviderPaystack, nil, body, true); err != nil || dup {
+		t.Fatalf("expected genuine event to be stored, dup=%v err=%v", dup, err)
+	}
+	if err := inbox.PurgeRejected(time.Now().Add(8 * 24 * time.Hour)); err != nil {
+		t.Fatalf("purge rejected: %v", err)
+	}
+	if err := db.First(&stored, "id = ?", forged.ID).Error; err == nil {
+		t.Fatalf("expected old rejected deliveries to be purged")
+	}
+}
+
+func TestWebhookEndpointRefusesOversizedBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{}).Service
+	inbox := services.NewWebhookService(db, services.NewWalletService(db, paystack))
+	r := gin.New()
+	r.POST("/wallet/paystack/webhook", handlers.NewWebhookHandler(inbox).Receive(paystack))
+
+	before := time.Now()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/wallet/paystack/webhook", strings.NewReader(strings.Repeat("x", 2<<20))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+	var stored int64
+	db.Model(&models.WebhookEvent{}).Where("created_at >= ?", before).Count(&stored)
+	if stored != 0 {
+		t.Fatalf("expected nothing stored, got %d events", stored)
+	}
+}
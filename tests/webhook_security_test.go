@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignatureAcceptsRotatedSecretsUntilWindowEnds(t *testing.T) {
+	paystack := services.NewPaystackService("sk_test_api", "")
+	body := []byte(`{"event":"charge.success"}`)
+
+	if !paystack.VerifySignature(body, sign("sk_test_api", body)) {
+		t.Fatalf("expected the API key to verify when no webhook secrets are configured")
+	}
+
+	paystack.SetWebhookSecrets([]services.WebhookSecret{
+		{Key: "whsec_current"},
+		{Key: "whsec_previous", NotAfter: time.Now().Add(time.Hour)},
+		{Key: "whsec_expired", NotAfter: time.Now().Add(-time.Minute)},
+		{Key: ""},
+	})
+	cases := map[string]bool{
+		"whsec_current":  true,
+		"whsec_previous": true,
+		"whsec_expired":  false,
+		"sk_test_api":    false,
+		"":               false,
+	}
+	for secret, want := range cases {
+		if got := paystack.VerifySignature(body, sign(secret, body)); got != want {
+			t.Errorf("secret %q: expected %v, got %v", secret, want, got)
+		}
+	}
+	if paystack.VerifySignature(body, "not-hex") {
+		t.Errorf("expected a malformed signature to be rejected")
+	}
+}
+
+func TestWebhookSourceAllowlist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatalf("trusted proxies: %v", err)
+	}
+	r.POST("/hook", middleware.WebhookSourceAllowlist("paystack", []string{"52.31.139.75", "10.0.0.0/24", "2001:db8::1"}),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		remote, forwarded string
+		want              int
+	}{
+		{"52.31.139.75:443", "", http.StatusOK},
+		{"10.0.0.42:1234", "", http.StatusOK},
+		{"[2001:db8::1]:443", "", http.StatusOK},
+		{"10.0.1.1:1234", "", http.StatusForbidden},
+		// X-Forwarded-For is ignored when no proxy is trusted.
+		{"203.0.113.9:1234", "52.31.139.75", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/hook", nil)
+		req.RemoteAddr = tc.remote
+		if tc.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s (xff %q): expected %d, got %d", tc.remote, tc.forwarded, tc.want, w.Code)
+		}
+	}
+
+	open := gin.New()
+	open.POST("/hook", middleware.WebhookSourceAllowlist("paystack", nil), func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodPost, "/hook", nil)
+	req.RemoteAddr = "198.51.100.7:80"
+	w := httptest.NewRecorder()
+	open.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected an empty allowlist to allow every source, got %d", w.Code)
+	}
+}
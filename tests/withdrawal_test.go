@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CyberwizD/Wallet-Service/internal/handlers"
+	"github.com/CyberwizD/Wallet-Service/internal/middleware"
+	"github.com/CyberwizD/Wallet-Service/internal/models"
+	"github.com/CyberwizD/Wallet-Service/internal/paystackemu"
+	"github.com/CyberwizD/Wallet-Service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWithdrawalHoldsThenSettlesOnSuccess(t *testing.T) {
+	paystack := newEmulatedPaystack(t, paystackemu.Config{TransferOutcome: paystackemu.TransferOutcomeManual})
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, paystack.Service)
+	user := seedUserWithWallet(db, "wdr-success@test.com", 10_000)
+	ctx := context.Background()
+
+	recipient, err := svc.AddRecipient(ctx, &user, "058", "0123456789", "")
+	if err != nil {
+		t.Fatalf("add recipient: %v", err)
+	}
+	if recipient.AccountName != "EMULATED ACCOUNT 6789" || recipient.Currency != models.DefaultCurrency {
+		t.Fatalf("unexpected recipient: %+v", recipient)
+	}
+	record, err := svc.InitiateWithdrawal(ctx, &user, recipient.ID, 4_000, "rent")
+	if err != nil {
+		t.Fatalf("initiate withdrawal: %v", err)
+	}
+	sent := strings.ToLower(record.Reference)
+	if status, err := paystack.Service.VerifyTransfer(ctx, sent); err != nil || status != "pending" {
+		t.Fatalf("expected lowercase reference sent to paystack, got status %q err=%v", status, err)
+	}
+	wallet, _ := svc.Balance(user.ID, "")
+	if wallet.Balance != 10_000 || wallet.Available() != 6_000 {
+		t.Fatalf("expected funds held while pending, got balance %d available %d", wallet.Balance, wallet.Available())
+	}
+	if err := svc.ExpireHolds(); err != nil {
+		t.Fatalf("expire holds: %v", err)
+	}
+
+	for i := 0; i < 2; i++ { // replayed webhook must not debit twice
+		if err := svc.ApplyWithdrawalWebhook(sent, services.PaystackTransferSuccess, []byte(`{}`)); err != nil {
+			t.Fatalf("apply success webhook: %v", err)
+		}
+	}
+	wallet, _ = svc.Balance(user.ID, "")
+	if wallet.Balance != 6_000 || wallet.HeldBalance != 0 {
+		t.Fatalf("expected payout debited and hold cleared, got balance %d held %d", wallet.Balance, wallet.HeldBalance)
+	}
+
+	if err := svc.ApplyWithdrawalWebhook(sent, services.PaystackTransferReversed, []byte(`{}`)); err != nil {
+		t.Fatalf("apply reversed webhook: %v", err)
+	}
+	wallet, _ = svc.Balance(user.ID, "")
+	var stored models.Transaction
+	_ = db.First(&stored, "reference = ?", record.Reference).Error
+	if wallet.Balance != 10_000 || stored.Status != models.TransactionReversed {
+		t.Fatalf("expected reversal to refund the wallet, got balance %d status %s", wallet.Balance, stored.Status)
+	}
+}
+
+func TestWithdrawalReleasesHoldWhenPaystackRejects(t *testing.T) {
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	paystack.setIntercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodPost || r.URL.Path != "/transfer" {
+			return false
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status":false,"message":"insufficient paystack balance"}`))
+		return true
+	})
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, paystack.Service)
+	user := seedUserWithWallet(db, "wdr-rejected@test.com", 5_000)
+
+	recipient, err := svc.AddRecipient(context.Background(), &user, "058", "0987654321", "NGN")
+	if err != nil {
+		t.Fatalf("add recipient: %v", err)
+	}
+	if _, err := svc.InitiateWithdrawal(context.Background(), &user, recipient.ID, 3_000, ""); err == nil {
+		t.Fatalf("expected paystack rejection to surface")
+	}
+	wallet, _ := svc.Balance(user.ID, "")
+	if wallet.Balance != 5_000 || wallet.HeldBalance != 0 {
+		t.Fatalf("expected hold released, got balance %d held %d", wallet.Balance, wallet.HeldBalance)
+	}
+	var failed int64
+	db.Model(&models.Transaction{}).
+		Where("wallet_id = ? AND type = ? AND status = ?", wallet.ID, models.TransactionTypeWithdrawal, models.TransactionFailed).
+		Count(&failed)
+	if failed != 1 {
+		t.Fatalf("expected one failed withdrawal, got %d", failed)
+	}
+}
+
+func TestVerifyWithdrawalsSettlesMissedTransferWebhooks(t *testing.T) {
+	db := newTestDB(t)
+	// No webhook URL: Paystack's transfer events never reach the service.
+	emu := newEmulatedPaystack(t, paystackemu.Config{TransferOutcome: paystackemu.TransferOutcomeManual})
+	paystack := emu.Service
+	paystack.SetRetryPolicy(services.RetryPolicy{MaxAttempts: 1})
+	svc := services.NewWalletService(db, paystack)
+	user := seedUserWithWallet(db, "wdr-verify@test.com", 10_000)
+	ctx := context.Background()
+
+	recipient, err := svc.AddRecipient(ctx, &user, "058", "0444555666", "")
+	if err != nil {
+		t.Fatalf("add recipient: %v", err)
+	}
+	withdraw := func(amount int64) string {
+		record, err := svc.InitiateWithdrawal(ctx, &user, recipient.ID, amount, "")
+		if err != nil {
+			t.Fatalf("initiate withdrawal: %v", err)
+		}
+		return record.Reference
+	}
+	paid, declined, queued := withdraw(1_000), withdraw(2_000), withdraw(3_000)
+	emu.setIntercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/transfer" {
+			return false
+		}
+		http.Error(w, "bad gateway", http.StatusBadGateway) // lost before Paystack queued it
+		return true
+	})
+	lost := withdraw(4_000)
+	if err := emu.SettleTransfer(strings.ToLower(paid), "transfer.success"); err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+	if err := emu.SettleTransfer(strings.ToLower(declined), "transfer.failed"); err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+
+	// Too recent to check yet.
+	if n, err := svc.VerifyWithdrawals(ctx, time.Now(), 30*time.Minute); err != nil || n != 0 {
+		t.Fatalf("expected recent withdrawals left alone, got %d err=%v", n, err)
+	}
+	now := time.Now().Add(time.Hour)
+	if n, err := svc.VerifyWithdrawals(ctx, now, 30*time.Minute); err != nil || n != 3 {
+		t.Fatalf("expected three withdrawals settled, got %d err=%v", n, err)
+	}
+	want := map[string]models.TransactionStatus{
+		paid:     models.TransactionSuccess,
+		declined: models.TransactionFailed,
+		queued:   models.TransactionPending,
+		lost:     models.TransactionFailed,
+	}
+	for ref, status := range want {
+		var record models.Transaction
+		db.First(&record, "reference = ?", ref)
+		if record.Status != status {
+			t.Fatalf("withdrawal %s: expected %s, got %s", ref, status, record.Status)
+		}
+		if ref == queued && !record.UpdatedAt.Equal(now) {
+			t.Fatalf("expected the still pending withdrawal moved to the back, got updated_at %s", record.UpdatedAt)
+		}
+	}
+	wallet, _ := svc.Balance(user.ID, "")
+	if wallet.Balance != 9_000 || wallet.HeldBalance != 3_000 {
+		t.Fatalf("expected the payout debited and only the queued one held, got balance %d held %d", wallet.Balance, wallet.HeldBalance)
+	}
+}
+
+func TestWithdrawalRoutesRequireWithdrawPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	db := newTestDB(t)
+	user := seedUserWithWallet(db, "wdr-perms@test.com", 5_000)
+	keys := services.NewAPIKeyService(db)
+	_, readOnly, err := keys.CreateKey(&user, "reader", []string{"read"}, "1D")
+	if err != nil {
+		t.Fatalf("create read key: %v", err)
+	}
+	_, withdrawer, err := keys.CreateKey(&user, "payouts", []string{"withdraw"}, "1D")
+	if err != nil {
+		t.Fatalf("create withdraw key: %v", err)
+	}
+	r := gin.New()
+	r.POST("/wallet/recipients", middleware.AuthMiddleware(db, "test-secret"), middleware.RequirePermission("withdraw"),
+		handlers.NewWalletHandler(services.NewWalletService(db, paystack.Service)).AddRecipient)
+
+	addRecipient := func(header, value, account string) int {
+		req := httptest.NewRequest(http.MethodPost, "/wallet/recipients", strings.NewReader(`{"bank_code":"058","account_number":"`+account+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := addRecipient("x-api-key", readOnly, "0222333444"); code != http.StatusForbidden {
+		t.Fatalf("expected a read-only key to be refused, got %d", code)
+	}
+	if code := addRecipient("x-api-key", withdrawer, "0222333444"); code != http.StatusCreated {
+		t.Fatalf("expected a withdraw key to add a recipient, got %d", code)
+	}
+	if code := addRecipient("Authorization", "Bearer "+signIn(t, db, &user, "test-secret").AccessToken, "0333444555"); code != http.StatusCreated {
+		t.Fatalf("expected a user token to add a recipient, got %d", code)
+	}
+}
+
+func TestWithdrawalsNeedPaystack(t *testing.T) {
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, nil)
+	user := seedUserWithWallet(db, "wdr-no-paystack@test.com", 5_000)
+	if _, err := svc.AddRecipient(context.Background(), &user, "058", "0555666777", ""); err == nil {
+		t.Fatal("expected adding a recipient to fail without paystack")
+	}
+	if _, err := svc.InitiateWithdrawal(context.Background(), &user, "any", 1_000, ""); err == nil {
+		t.Fatal("expected a withdrawal to fail without paystack")
+	}
+	if wallet, _ := svc.Balance(user.ID, ""); wallet.HeldBalance != 0 {
+		t.Fatalf("expected nothing held, got %d", wallet.HeldBalance)
+	}
+}
+
+func TestWithdrawalFailsWhenPaystackWantsOTP(t *testing.T) {
+	paystack := newEmulatedPaystack(t, paystackemu.Config{})
+	paystack.setIntercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodPost || r.URL.Path != "/transfer" {
+			return false
+		}
+		_, _ = w.Write([]byte(`{"status":true,"message":"Transfer requires OTP to continue","data":{"transfer_code":"TRF_otp","status":"otp"}}`))
+		return true
+	})
+	db := newTestDB(t)
+	svc := services.NewWalletService(db, paystack.Service)
+	user := seedUserWithWallet(db, "wdr-otp@test.com", 5_000)
+
+	recipient, err := svc.AddRecipient(context.Background(), &user, "058", "0666777888", "")
+	if err != nil {
+		t.Fatalf("add recipient: %v", err)
+	}
+	if _, err := svc.InitiateWithdrawal(context.Background(), &user, recipient.ID, 2_000, ""); !errors.Is(err, services.ErrTransferOTPRequired) {
+		t.Fatalf("expected the OTP requirement surfaced, got %v", err)
+	}
+	wallet, _ := svc.Balance(user.ID, "")
+	var failed int64
+	db.Model(&models.Transaction{}).
+		Where("wallet_id = ? AND type = ? AND status = ?", wallet.ID, models.TransactionTypeWithdrawal, models.TransactionFailed).
+		Count(&failed)
+	if wallet.Balance != 5_000 || wallet.HeldBalance != 0 || failed != 1 {
+		t.Fatalf("expected the withdrawal failed and its hold released, got balance %d held %d failed %d", wallet.Balance, wallet.HeldBalance, failed)
+	}
+}